@@ -3,17 +3,27 @@ module github.com/ornew/tekton-integration
 go 1.16
 
 require (
+	github.com/Shopify/sarama v1.29.1
 	github.com/bradleyfalzon/ghinstallation v1.1.1
 	github.com/go-logr/logr v0.4.0
 	github.com/google/go-github/v37 v37.0.0
+	github.com/nats-io/jwt/v2 v2.0.2
+	github.com/nats-io/nats-server/v2 v2.2.6
+	github.com/nats-io/nats.go v1.11.0
+	github.com/nats-io/nkeys v0.3.0
 	github.com/onsi/ginkgo v1.16.4
 	github.com/onsi/gomega v1.13.0
+	github.com/prometheus/client_golang v1.11.0
+	github.com/prometheus/client_model v0.2.0
 	github.com/stretchr/testify v1.7.0
 	github.com/tektoncd/pipeline v0.26.0
+	github.com/xdg-go/scram v1.0.2
+	golang.org/x/time v0.0.0-20210611083556-38a9dc6acbc6
 	k8s.io/api v0.21.2
 	k8s.io/apimachinery v0.21.2
 	k8s.io/client-go v0.21.2
 	k8s.io/utils v0.0.0-20210527160623-6fdb442a123b
 	knative.dev/pkg v0.0.0-20210510175900-4564797bf3b7
 	sigs.k8s.io/controller-runtime v0.9.2
+	sigs.k8s.io/yaml v1.2.0
 )
@@ -0,0 +1,285 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"knative.dev/pkg/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/go-logr/logr"
+	pipelinesv1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+
+	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
+)
+
+const (
+	alertmanagerDefaultAlertName      = "TektonPipelineRunFailed"
+	alertmanagerDefaultResolveTimeout = 24 * time.Hour
+)
+
+var alertmanagerLabelNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Alertmanager posts the alerts of the failed pipelines to Prometheus Alertmanager,
+// and resolves them when the pipelines succeed.
+type Alertmanager struct {
+	URL string
+	// Authorization is the value of the Authorization header, or empty.
+	Authorization SecretString
+	// Transport verifies Alertmanager with the custom CAs, or nil to use the default transport.
+	Transport      http.RoundTripper
+	AlertName      string
+	Labels         map[string]string
+	CopyLabels     map[string]string
+	ResolveTimeout time.Duration
+	Outcomes       *pipelineOutcomes
+
+	now func() time.Time
+}
+
+var _ Provider = (*Alertmanager)(nil)
+
+func NewAlertmanager(ctx context.Context, p *v1alpha1.Provider, k client.Client) (*Alertmanager, *ProviderError) {
+	s := p.Spec.Alertmanager
+	if s == nil {
+		return nil, NewInvalidProviderSpecError("missing value .alertmanager")
+	}
+	if len(s.URL) < 1 {
+		return nil, NewInvalidProviderSpecError("missing value .alertmanager.url")
+	}
+	for name := range s.Labels {
+		if !alertmanagerLabelNamePattern.MatchString(name) {
+			return nil, NewInvalidProviderSpecError(fmt.Sprintf("invalid label name in .labels: %s", name))
+		}
+	}
+	for _, name := range s.CopyLabels {
+		if !alertmanagerLabelNamePattern.MatchString(name) {
+			return nil, NewInvalidProviderSpecError(fmt.Sprintf("invalid label name in .copyLabels: %s", name))
+		}
+	}
+	a := &Alertmanager{
+		URL:            strings.TrimSuffix(s.URL, "/"),
+		AlertName:      alertmanagerDefaultAlertName,
+		Labels:         s.Labels,
+		CopyLabels:     s.CopyLabels,
+		ResolveTimeout: alertmanagerDefaultResolveTimeout,
+		Outcomes:       newPipelineOutcomes(p, k),
+		now:            time.Now,
+	}
+	if s.AlertName != nil && len(*s.AlertName) > 0 {
+		a.AlertName = *s.AlertName
+	}
+	if s.ResolveTimeout != nil && s.ResolveTimeout.Duration > 0 {
+		a.ResolveTimeout = s.ResolveTimeout.Duration
+	}
+	if s.AuthType != nil && len(*s.AuthType) > 0 {
+		if s.SecretRef == nil {
+			return nil, NewInvalidProviderSpecError("missing value .alertmanager.secretRef")
+		}
+		auth, perr := getAlertmanagerAuthorization(ctx, k, p.Namespace, *s.AuthType, s.SecretRef.Name)
+		if perr != nil {
+			return nil, perr
+		}
+		a.Authorization = NewSecretString(auth)
+	}
+	if s.CA != nil {
+		data, perr := getConfigMapValue(ctx, k, p.Namespace, s.CA, "ca.crt")
+		if perr != nil {
+			return nil, perr
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM([]byte(data)) {
+			return nil, NewFailedValidationError(fmt.Sprintf("no certificates found in ConfigMap %s", s.CA.Name))
+		}
+		tr := http.DefaultTransport.(*http.Transport).Clone()
+		tr.TLSClientConfig = &tls.Config{RootCAs: pool}
+		a.Transport = tr
+	}
+	return a, nil
+}
+
+// getAlertmanagerAuthorization returns the Authorization header of the credentials in the secret.
+func getAlertmanagerAuthorization(ctx context.Context, k client.Client, namespace, authType, name string) (string, *ProviderError) {
+	switch authType {
+	case "Basic":
+		data, perr := getSecretData(ctx, k, namespace, name, "username", "password")
+		if perr != nil {
+			return "", perr
+		}
+		cred := fmt.Sprintf("%s:%s", data["username"], data["password"])
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(cred)), nil
+	case "Bearer":
+		data, perr := getSecretData(ctx, k, namespace, name, "token")
+		if perr != nil {
+			return "", perr
+		}
+		return "Bearer " + string(data["token"]), nil
+	}
+	return "", NewInvalidProviderSpecError(fmt.Sprintf("unknown auth type: %s", authType))
+}
+
+func (a *Alertmanager) Notify(ctx context.Context, pr *pipelinesv1beta1.PipelineRun) *ProviderError {
+	log := logr.FromContext(ctx).WithName("providers.alertmanager").
+		WithValues("providerType", "Alertmanager", "pipelineRun", pr.Name)
+	cond := pr.Status.GetCondition(apis.ConditionSucceeded)
+	if cond == nil {
+		log.V(1).Info("PipelineRun has not condition, ignored")
+		return NewSkippedError("PipelineRun has not condition")
+	}
+	if cond.Status == corev1.ConditionUnknown {
+		log.V(2).Info("this run is not finished yet, skipped")
+		return NewSkippedError("this run is not finished yet")
+	}
+	return notifyPipelineAlert(ctx, log, a, a.Outcomes, pr)
+}
+
+// triggerAlert posts the alert which ends after the resolve timeout,
+// since Alertmanager resolves the alerts not sent again in its resolve_timeout.
+func (a *Alertmanager) triggerAlert(ctx context.Context, pr *pipelinesv1beta1.PipelineRun, pipeline string) *ProviderError {
+	alert, perr := a.newAlert(pr)
+	if perr != nil {
+		return perr
+	}
+	_, perr = a.do(ctx, http.MethodPost, "/api/v2/alerts", []alertmanagerAlert{*alert})
+	return perr
+}
+
+// resolveAlert posts the firing alerts of the pipeline again with endsAt.
+// The alerts are looked up by the labels of the pipeline, since their labels
+// depend on the failed runs, e.g. the reason.
+func (a *Alertmanager) resolveAlert(ctx context.Context, pr *pipelinesv1beta1.PipelineRun, pipeline string) *ProviderError {
+	id, perr := getContextID(pr)
+	if perr != nil {
+		return perr
+	}
+	query := url.Values{}
+	for _, m := range []struct{ name, value string }{
+		{"alertname", a.AlertName},
+		{"namespace", pr.Namespace},
+		{"pipeline", id},
+	} {
+		query.Add("filter", fmt.Sprintf("%s=%s", m.name, strconv.Quote(m.value)))
+	}
+	b, perr := a.do(ctx, http.MethodGet, "/api/v2/alerts?"+query.Encode(), nil)
+	if perr != nil {
+		return perr
+	}
+	var alerts []alertmanagerAlert
+	if err := json.Unmarshal(b, &alerts); err != nil {
+		return NewRuntimeError(fmt.Sprintf("failed to unmarshal Alertmanager alerts: %v", err))
+	}
+	if len(alerts) < 1 {
+		return nil
+	}
+	endsAt := a.now().UTC()
+	resolved := make([]alertmanagerAlert, 0, len(alerts))
+	for _, alert := range alerts {
+		resolved = append(resolved, alertmanagerAlert{
+			Labels:       alert.Labels,
+			Annotations:  alert.Annotations,
+			StartsAt:     alert.StartsAt,
+			EndsAt:       &endsAt,
+			GeneratorURL: alert.GeneratorURL,
+		})
+	}
+	_, perr = a.do(ctx, http.MethodPost, "/api/v2/alerts", resolved)
+	return perr
+}
+
+func (a *Alertmanager) do(ctx context.Context, method, path string, payload interface{}) ([]byte, *ProviderError) {
+	if a.Transport != nil {
+		ctx = withHTTPTransport(ctx, a.Transport)
+	}
+	b, err := doHTTP(ctx, method, a.URL+path, a.Authorization.GetNoRedactedString(), payload)
+	if err != nil {
+		return nil, newHTTPError("Alertmanager", err)
+	}
+	return b, nil
+}
+
+// alertmanagerAlert is the postable and the gettable alert of the API v2.
+type alertmanagerAlert struct {
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+	StartsAt     *time.Time        `json:"startsAt,omitempty"`
+	EndsAt       *time.Time        `json:"endsAt,omitempty"`
+	GeneratorURL string            `json:"generatorURL,omitempty"`
+}
+
+// newAlert returns the alert of the failed run. The labels identify the pipeline and the reason,
+// and the built-in labels take precedence over the static and the copied labels.
+func (a *Alertmanager) newAlert(pr *pipelinesv1beta1.PipelineRun) (*alertmanagerAlert, *ProviderError) {
+	id, perr := getContextID(pr)
+	if perr != nil {
+		return nil, perr
+	}
+	m := newChatMessage(pr)
+	labels := map[string]string{}
+	for name, value := range a.Labels {
+		labels[name] = value
+	}
+	for key, name := range a.CopyLabels {
+		if v, ok := pr.Labels[key]; ok {
+			labels[name] = v
+		}
+	}
+	labels["alertname"] = a.AlertName
+	labels["namespace"] = pr.Namespace
+	labels["pipeline"] = id
+	labels["reason"] = m.Reason
+
+	annotations := map[string]string{
+		"summary":     m.Summary(),
+		"description": m.Message,
+		"pipelinerun": pr.Name,
+	}
+	if details := formatFailureDetails(m.FailedTasks, "%s"); len(details) > 0 {
+		annotations["failed_tasks"] = details
+	}
+	if len(m.DashboardURL) > 0 {
+		annotations["dashboard"] = m.DashboardURL
+	}
+
+	startsAt := a.now().UTC()
+	if t := pr.Status.CompletionTime; t != nil {
+		startsAt = t.UTC()
+	}
+	endsAt := a.now().Add(a.ResolveTimeout).UTC()
+	return &alertmanagerAlert{
+		Labels:       labels,
+		Annotations:  annotations,
+		StartsAt:     &startsAt,
+		EndsAt:       &endsAt,
+		GeneratorURL: m.DashboardURL,
+	}, nil
+}
@@ -0,0 +1,394 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
+)
+
+func TestNewAlertmanager(t *testing.T) {
+	k := fakeclient.NewClientBuilder().
+		WithObjects(
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "basic", Namespace: "default"},
+				Data: map[string][]byte{
+					"username": []byte("tekton"),
+					"password": []byte("pass"),
+				},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "bearer", Namespace: "default"},
+				Data: map[string][]byte{
+					"token": []byte("t0k3n"),
+				},
+			},
+			&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "invalid-ca", Namespace: "default"},
+				Data: map[string]string{
+					"ca.crt": "not a certificate",
+				},
+			},
+		).
+		Build()
+	for _, c := range []struct {
+		name      string
+		spec      *v1alpha1.AlertmanagerSpec
+		wantAuth  string
+		wantAlert string
+		wantErr   *ProviderError
+	}{
+		{
+			name:      "Basic",
+			spec:      &v1alpha1.AlertmanagerSpec{URL: "http://alertmanager:9093/"},
+			wantAlert: "TektonPipelineRunFailed",
+		},
+		{
+			name: "BasicAuth",
+			spec: &v1alpha1.AlertmanagerSpec{
+				URL:       "http://alertmanager:9093",
+				AuthType:  pointer.String("Basic"),
+				SecretRef: &corev1.LocalObjectReference{Name: "basic"},
+				AlertName: pointer.String("PipelineFailed"),
+			},
+			wantAuth:  "Basic dGVrdG9uOnBhc3M=",
+			wantAlert: "PipelineFailed",
+		},
+		{
+			name: "BearerAuth",
+			spec: &v1alpha1.AlertmanagerSpec{
+				URL:       "http://alertmanager:9093",
+				AuthType:  pointer.String("Bearer"),
+				SecretRef: &corev1.LocalObjectReference{Name: "bearer"},
+			},
+			wantAuth:  "Bearer t0k3n",
+			wantAlert: "TektonPipelineRunFailed",
+		},
+		{
+			name: "MissingKey",
+			spec: &v1alpha1.AlertmanagerSpec{
+				URL:       "http://alertmanager:9093",
+				AuthType:  pointer.String("Bearer"),
+				SecretRef: &corev1.LocalObjectReference{Name: "basic"},
+			},
+			wantErr: NewNotFoundPrivateKeyError(""),
+		},
+		{
+			name: "MissingSecretRef",
+			spec: &v1alpha1.AlertmanagerSpec{
+				URL:      "http://alertmanager:9093",
+				AuthType: pointer.String("Basic"),
+			},
+			wantErr: NewInvalidProviderSpecError(""),
+		},
+		{
+			name: "InvalidLabelName",
+			spec: &v1alpha1.AlertmanagerSpec{
+				URL:        "http://alertmanager:9093",
+				CopyLabels: map[string]string{"tekton.dev/pipeline": "tekton.dev/pipeline"},
+			},
+			wantErr: NewInvalidProviderSpecError(""),
+		},
+		{
+			name: "InvalidCA",
+			spec: &v1alpha1.AlertmanagerSpec{
+				URL: "https://alertmanager:9093",
+				CA: &v1alpha1.LocalConfigMapKeyReference{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "invalid-ca"},
+				},
+			},
+			wantErr: NewFailedValidationError(""),
+		},
+		{
+			name: "CANotFound",
+			spec: &v1alpha1.AlertmanagerSpec{
+				URL: "https://alertmanager:9093",
+				CA: &v1alpha1.LocalConfigMapKeyReference{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "not-exists-ca"},
+				},
+			},
+			wantErr: NewFailedValidationError(""),
+		},
+		{
+			name:    "AlertmanagerSpecNotFound",
+			wantErr: NewInvalidProviderSpecError(""),
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			p := &v1alpha1.Provider{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "provider",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.ProviderSpec{
+					Type:         "Alertmanager",
+					Alertmanager: c.spec,
+				},
+			}
+			a, err := NewAlertmanager(ctx, p, k)
+			if c.wantErr != nil {
+				if assert.NotNil(t, err) {
+					assert.Equal(t, c.wantErr.Code, err.Code)
+				}
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, "http://alertmanager:9093", a.URL)
+			assert.Equal(t, c.wantAuth, a.Authorization.GetNoRedactedString())
+			assert.Equal(t, c.wantAlert, a.AlertName)
+			assert.Equal(t, 24*time.Hour, a.ResolveTimeout)
+		})
+	}
+}
+
+func TestAlertmanagerNotify(t *testing.T) {
+	now := time.Date(2021, 7, 1, 0, 10, 0, 0, time.UTC)
+	earlier := metav1.NewTime(now.Add(-time.Hour))
+	firing := `[{
+		"labels": {"alertname": "TektonPipelineRunFailed", "namespace": "default", "pipeline": "sample", "reason": "Failed", "team": "platform"},
+		"annotations": {"summary": "Failed: previous-run.default"},
+		"startsAt": "2021-06-30T23:00:00Z",
+		"endsAt": "2021-07-01T23:00:00Z",
+		"generatorURL": "",
+		"fingerprint": "0123456789abcdef",
+		"status": {"state": "active", "silencedBy": [], "inhibitedBy": []},
+		"receivers": [{"name": "default"}],
+		"updatedAt": "2021-06-30T23:00:00Z"
+	}]`
+	for _, c := range []struct {
+		name        string
+		status      corev1.ConditionStatus
+		reason      string
+		last        *v1alpha1.PipelineOutcome
+		firing      string
+		respCode    int
+		wantQuery   string
+		want        string
+		wantStatus  metav1.ConditionStatus
+		wantErr     *ProviderError
+		wantSkipped bool
+	}{
+		{
+			name:     "Trigger",
+			status:   corev1.ConditionFalse,
+			reason:   "Failed",
+			respCode: http.StatusOK,
+			want: `[{
+				"labels": {
+					"alertname": "TektonPipelineRunFailed",
+					"namespace": "default",
+					"pipeline": "sample",
+					"reason": "Failed",
+					"severity": "warning",
+					"tekton_pipeline": "sample"
+				},
+				"annotations": {
+					"summary": "Failed: sample-run.default",
+					"description": "Tasks Completed: 1 (Failed: 1, Cancelled 0), Skipped: 0",
+					"pipelinerun": "sample-run",
+					"failed_tasks": "build (sample-run-build) Failed: Tasks Completed: 1 (Failed: 1, Cancelled 0), Skipped: 0",
+					"dashboard": "https://dashboard.example.com/#/namespaces/default/pipelineruns/sample-run"
+				},
+				"startsAt": "2021-07-01T00:01:00Z",
+				"endsAt": "2021-07-02T00:10:00Z",
+				"generatorURL": "https://dashboard.example.com/#/namespaces/default/pipelineruns/sample-run"
+			}]`,
+			wantStatus: metav1.ConditionFalse,
+		},
+		{
+			name:      "Resolve",
+			status:    corev1.ConditionTrue,
+			reason:    "Succeeded",
+			last:      &v1alpha1.PipelineOutcome{Status: metav1.ConditionFalse, PipelineRun: "previous-run", StartTime: &earlier},
+			firing:    firing,
+			respCode:  http.StatusOK,
+			wantQuery: `filter=alertname%3D%22TektonPipelineRunFailed%22&filter=namespace%3D%22default%22&filter=pipeline%3D%22sample%22`,
+			want: `[{
+				"labels": {"alertname": "TektonPipelineRunFailed", "namespace": "default", "pipeline": "sample", "reason": "Failed", "team": "platform"},
+				"annotations": {"summary": "Failed: previous-run.default"},
+				"startsAt": "2021-06-30T23:00:00Z",
+				"endsAt": "2021-07-01T00:10:00Z"
+			}]`,
+			wantStatus: metav1.ConditionTrue,
+		},
+		{
+			name:       "AlreadyResolved",
+			status:     corev1.ConditionTrue,
+			reason:     "Succeeded",
+			last:       &v1alpha1.PipelineOutcome{Status: metav1.ConditionFalse, PipelineRun: "previous-run", StartTime: &earlier},
+			firing:     `[]`,
+			respCode:   http.StatusOK,
+			wantQuery:  `filter=alertname%3D%22TektonPipelineRunFailed%22&filter=namespace%3D%22default%22&filter=pipeline%3D%22sample%22`,
+			wantStatus: metav1.ConditionTrue,
+		},
+		{
+			name:       "SucceededAgain",
+			status:     corev1.ConditionTrue,
+			reason:     "Succeeded",
+			last:       &v1alpha1.PipelineOutcome{Status: metav1.ConditionTrue, PipelineRun: "previous-run", StartTime: &earlier},
+			wantStatus: metav1.ConditionTrue,
+		},
+		{
+			name:        "Cancelled",
+			wantSkipped: true,
+			status:      corev1.ConditionFalse,
+			reason:      "Cancelled",
+		},
+		{
+			name:        "Running",
+			wantSkipped: true,
+			status:      corev1.ConditionUnknown,
+			reason:      "Running",
+		},
+		{
+			name:     "Unauthorized",
+			status:   corev1.ConditionFalse,
+			reason:   "Failed",
+			respCode: http.StatusUnauthorized,
+			wantErr:  NewRuntimeError(""),
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			var queries, bodies []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/api/v2/alerts", r.URL.Path)
+				assert.Equal(t, "Bearer t0k3n", r.Header.Get("Authorization"))
+				if r.Method == http.MethodGet {
+					queries = append(queries, r.URL.RawQuery)
+					w.Write([]byte(c.firing))
+					return
+				}
+				var body json.RawMessage
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("failed to decode request: %v", err)
+				}
+				bodies = append(bodies, string(body))
+				w.WriteHeader(c.respCode)
+			}))
+			defer srv.Close()
+
+			p := &v1alpha1.Provider{
+				ObjectMeta: metav1.ObjectMeta{Name: "provider", Namespace: "default"},
+			}
+			if c.last != nil {
+				last := *c.last
+				last.Pipeline = "default/sample"
+				p.Status.Pipelines = []v1alpha1.PipelineOutcome{last}
+			}
+			k := newOutcomesTestClient(t, p).Build()
+			assert.Nil(t, k.Get(ctx, types.NamespacedName{Namespace: "default", Name: "provider"}, p))
+			a := &Alertmanager{
+				URL:            srv.URL,
+				Authorization:  NewSecretString("Bearer t0k3n"),
+				AlertName:      "TektonPipelineRunFailed",
+				Labels:         map[string]string{"severity": "warning", "pipeline": "overridden"},
+				CopyLabels:     map[string]string{"tekton.dev/pipeline": "tekton_pipeline", "not-exists": "not_exists"},
+				ResolveTimeout: 24 * time.Hour,
+				Outcomes:       newPipelineOutcomes(p, k),
+				now:            func() time.Time { return now },
+			}
+			pr := newTemplateSamplePipelineRun()
+			pr.Status.Conditions[0].Status = c.status
+			pr.Status.Conditions[0].Reason = c.reason
+			err := a.Notify(ctx, pr)
+			if c.wantErr != nil {
+				if assert.NotNil(t, err) {
+					assert.Equal(t, c.wantErr.Code, err.Code)
+					assert.NotContains(t, err.Message, "t0k3n")
+				}
+				return
+			}
+			if c.wantSkipped {
+				assert.True(t, err.IsSkipped())
+			} else {
+				assert.Nil(t, err)
+			}
+			if len(c.wantQuery) > 0 {
+				assert.Equal(t, []string{c.wantQuery}, queries)
+			} else {
+				assert.Empty(t, queries)
+			}
+			if len(c.want) < 1 {
+				assert.Empty(t, bodies)
+			} else if assert.Len(t, bodies, 1) {
+				assert.JSONEq(t, c.want, bodies[0])
+			}
+
+			var got v1alpha1.Provider
+			assert.Nil(t, k.Get(ctx, types.NamespacedName{Namespace: "default", Name: "provider"}, &got))
+			if len(c.wantStatus) < 1 {
+				assert.Empty(t, got.Status.Pipelines)
+				return
+			}
+			if assert.Len(t, got.Status.Pipelines, 1) {
+				assert.Equal(t, c.wantStatus, got.Status.Pipelines[0].Status)
+			}
+		})
+	}
+}
+
+func TestAlertmanagerCA(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+
+	p := &v1alpha1.Provider{
+		ObjectMeta: metav1.ObjectMeta{Name: "provider", Namespace: "default"},
+		Spec: v1alpha1.ProviderSpec{
+			Type: "Alertmanager",
+			Alertmanager: &v1alpha1.AlertmanagerSpec{
+				URL: srv.URL,
+				CA: &v1alpha1.LocalConfigMapKeyReference{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "ca"},
+				},
+			},
+		},
+	}
+	k := newOutcomesTestClient(t, p).
+		WithObjects(&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "ca", Namespace: "default"},
+			Data:       map[string]string{"ca.crt": string(ca)},
+		}).
+		Build()
+	a, perr := NewAlertmanager(ctx, p, k)
+	if !assert.Nil(t, perr) {
+		return
+	}
+	assert.Nil(t, a.Notify(ctx, newTemplateSamplePipelineRun()))
+
+	// the server is not trusted without the CA.
+	a.Transport = nil
+	assert.NotNil(t, a.triggerAlert(ctx, newTemplateSamplePipelineRun(), "default/sample"))
+}
@@ -0,0 +1,198 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"knative.dev/pkg/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/go-logr/logr"
+	pipelinesv1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+
+	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
+)
+
+const (
+	azureDevOpsDefaultBaseURL = "https://dev.azure.com"
+	azureDevOpsDefaultGenre   = "tekton"
+)
+
+type AzureDevOps struct {
+	BaseURL      string
+	Organization string
+	AccessToken  SecretString
+	Genre        string
+}
+
+var _ Provider = (*AzureDevOps)(nil)
+
+func NewAzureDevOps(ctx context.Context, p *v1alpha1.Provider, k client.Client) (*AzureDevOps, *ProviderError) {
+	s := p.Spec.AzureDevOps
+	if s == nil {
+		return nil, NewInvalidProviderSpecError("missing value .azureDevOps")
+	}
+	if s.AccessToken.SecretRef == nil {
+		return nil, NewInvalidProviderSpecError("missing valid values in .accessToken")
+	}
+	token, perr := getSecretValue(ctx, k, p.Namespace, s.AccessToken.SecretRef, "access-token")
+	if perr != nil {
+		return nil, perr
+	}
+	a := &AzureDevOps{
+		BaseURL:     azureDevOpsDefaultBaseURL,
+		AccessToken: NewSecretString(string(token)),
+		Genre:       azureDevOpsDefaultGenre,
+	}
+	if s.BaseURL != nil {
+		a.BaseURL = strings.TrimSuffix(*s.BaseURL, "/")
+	}
+	if s.Organization != nil {
+		a.Organization = *s.Organization
+	}
+	if s.Genre != nil {
+		a.Genre = *s.Genre
+	}
+	return a, nil
+}
+
+type azureDevOpsStatusContext struct {
+	Name  string `json:"name"`
+	Genre string `json:"genre"`
+}
+
+type azureDevOpsStatusRequest struct {
+	State       string                   `json:"state"`
+	Description string                   `json:"description,omitempty"`
+	TargetURL   string                   `json:"targetUrl,omitempty"`
+	Context     azureDevOpsStatusContext `json:"context"`
+}
+
+// azureDevOpsRef identifies a commit and an optional pull request on Azure Repos.
+type azureDevOpsRef struct {
+	Organization  string
+	Project       string
+	Repository    string
+	Revision      string
+	PullRequestID string
+}
+
+func (a *AzureDevOps) Notify(ctx context.Context, pr *pipelinesv1beta1.PipelineRun) *ProviderError {
+	log := logr.FromContext(ctx).WithName("providers.azuredevops").
+		WithValues("providerType", "AzureDevOps", "pipelineRun", pr.Name)
+	name, perr := getContextID(pr)
+	if perr != nil {
+		return perr
+	}
+	ref, perr := a.getRef(pr)
+	if perr != nil {
+		return perr
+	}
+	cond := pr.Status.GetCondition(apis.ConditionSucceeded)
+	if cond == nil {
+		log.V(1).Info("PipelineRun has not condition, ignored")
+		return NewSkippedError("PipelineRun has not condition")
+	}
+	status := &azureDevOpsStatusRequest{
+		State:       toAzureDevOpsStatus(cond.Status),
+		Description: cond.Reason,
+		TargetURL:   getDashboardTargetURL(pr),
+		Context: azureDevOpsStatusContext{
+			Name:  name,
+			Genre: a.Genre,
+		},
+	}
+	repoURL := fmt.Sprintf("%s/%s/%s/_apis/git/repositories/%s", a.BaseURL,
+		url.PathEscape(ref.Organization), url.PathEscape(ref.Project), url.PathEscape(ref.Repository))
+	u := fmt.Sprintf("%s/commits/%s/statuses?api-version=6.0", repoURL, url.PathEscape(ref.Revision))
+	if perr := a.post(ctx, u, status); perr != nil {
+		return perr
+	}
+	log.V(2).Info("set commit status", "status", status)
+	if len(ref.PullRequestID) < 1 {
+		return nil
+	}
+	u = fmt.Sprintf("%s/pullRequests/%s/statuses?api-version=6.0-preview.1", repoURL, url.PathEscape(ref.PullRequestID))
+	if perr := a.post(ctx, u, status); perr != nil {
+		return perr
+	}
+	log.V(2).Info("set pull request status", "pullRequest", ref.PullRequestID)
+	return nil
+}
+
+// getRef reads the values from the annotations integrations.tekton.ornew.io/azure-devops-<name>,
+// or the parameters azure-devops-<name> of the run.
+func (a *AzureDevOps) getRef(pr *pipelinesv1beta1.PipelineRun) (*azureDevOpsRef, *ProviderError) {
+	get := func(name string) string {
+		if v := pr.Annotations["integrations.tekton.ornew.io/azure-devops-"+name]; len(v) > 0 {
+			return v
+		}
+		return getParam(pr, "azure-devops-"+name)
+	}
+	ref := &azureDevOpsRef{
+		Organization:  get("organization"),
+		Project:       get("project"),
+		Repository:    get("repo"),
+		Revision:      get("sha"),
+		PullRequestID: get("pull-request-id"),
+	}
+	if len(ref.Organization) < 1 {
+		ref.Organization = a.Organization
+	}
+	if len(ref.Project) < 1 {
+		ref.Project = pr.Annotations[annotationRepositoryOwner]
+	}
+	if len(ref.Repository) < 1 {
+		ref.Repository = pr.Annotations[annotationRepositoryRepo]
+	}
+	if len(ref.Revision) < 1 {
+		ref.Revision = pr.Annotations[annotationRepositorySHA]
+	}
+	if len(ref.Organization) < 1 || len(ref.Project) < 1 || len(ref.Repository) < 1 || len(ref.Revision) < 1 {
+		return nil, NewFailedValidationError(fmt.Sprintf("required values: organization=%s project=%s repo=%s sha=%s",
+			ref.Organization, ref.Project, ref.Repository, ref.Revision))
+	}
+	return ref, nil
+}
+
+func (a *AzureDevOps) post(ctx context.Context, url string, payload interface{}) *ProviderError {
+	if _, err := postHTTP(ctx, url, basicAuthorization("", a.AccessToken.GetNoRedactedString()), payload); err != nil {
+		return newHTTPError("Azure DevOps", err)
+	}
+	return nil
+}
+
+const (
+	AzureDevOpsStatusPending   = "pending"
+	AzureDevOpsStatusSucceeded = "succeeded"
+	AzureDevOpsStatusFailed    = "failed"
+)
+
+func toAzureDevOpsStatus(status corev1.ConditionStatus) string {
+	switch status {
+	case corev1.ConditionUnknown:
+		return AzureDevOpsStatusPending
+	case corev1.ConditionTrue:
+		return AzureDevOpsStatusSucceeded
+	}
+	return AzureDevOpsStatusFailed
+}
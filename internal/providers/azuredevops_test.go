@@ -0,0 +1,233 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	knativeapis "knative.dev/pkg/apis"
+	knativeapisduckv1beta1 "knative.dev/pkg/apis/duck/v1beta1"
+
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	pipelinesv1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+
+	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
+)
+
+func TestNewAzureDevOps(t *testing.T) {
+	k := fakeclient.NewClientBuilder().
+		WithObjects(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "secret",
+				Namespace: "default",
+			},
+			Data: map[string][]byte{
+				"access-token": []byte("pat"),
+			},
+		}).
+		Build()
+	accessToken := v1alpha1.AccessTokenSource{
+		SecretRef: &v1alpha1.LocalSecretKeyReference{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "secret"},
+		},
+	}
+	for _, c := range []struct {
+		name    string
+		spec    *v1alpha1.AzureDevOpsSpec
+		want    *AzureDevOps
+		wantErr *ProviderError
+	}{
+		{
+			name: "Defaults",
+			spec: &v1alpha1.AzureDevOpsSpec{
+				AccessToken: accessToken,
+			},
+			want: &AzureDevOps{
+				BaseURL:     "https://dev.azure.com",
+				AccessToken: NewSecretString("pat"),
+				Genre:       "tekton",
+			},
+		},
+		{
+			name: "Server",
+			spec: &v1alpha1.AzureDevOpsSpec{
+				BaseURL:      pointer.String("https://server/tfs/"),
+				Organization: pointer.String("DefaultCollection"),
+				AccessToken:  accessToken,
+				Genre:        pointer.String("ci"),
+			},
+			want: &AzureDevOps{
+				BaseURL:      "https://server/tfs",
+				Organization: "DefaultCollection",
+				AccessToken:  NewSecretString("pat"),
+				Genre:        "ci",
+			},
+		},
+		{
+			name:    "AzureDevOpsSpecNotFound",
+			wantErr: NewInvalidProviderSpecError(""),
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			p := &v1alpha1.Provider{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "provider",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.ProviderSpec{
+					Type:        "AzureDevOps",
+					AzureDevOps: c.spec,
+				},
+			}
+			a, err := NewAzureDevOps(ctx, p, k)
+			if c.wantErr != nil {
+				if assert.NotNil(t, err) {
+					assert.Equal(t, c.wantErr.Code, err.Code)
+				}
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, c.want, a)
+		})
+	}
+}
+
+func TestAzureDevOpsNotify(t *testing.T) {
+	const commitPath = "/org/project/_apis/git/repositories/repo/commits/abc/statuses"
+	const pullRequestPath = "/org/project/_apis/git/repositories/repo/pullRequests/12/statuses"
+	for _, c := range []struct {
+		name         string
+		organization string
+		annotations  map[string]string
+		params       []pipelinesv1beta1.Param
+		wantPaths    []string
+		wantErr      *ProviderError
+	}{
+		{
+			name: "Annotations",
+			annotations: map[string]string{
+				"integrations.tekton.ornew.io/azure-devops-organization": "org",
+				"integrations.tekton.ornew.io/azure-devops-project":      "project",
+				"integrations.tekton.ornew.io/azure-devops-repo":         "repo",
+				"integrations.tekton.ornew.io/azure-devops-sha":          "abc",
+			},
+			wantPaths: []string{commitPath},
+		},
+		{
+			name:         "ParamsWithPullRequest",
+			organization: "org",
+			params: []pipelinesv1beta1.Param{
+				{Name: "azure-devops-project", Value: *pipelinesv1beta1.NewArrayOrString("project")},
+				{Name: "azure-devops-repo", Value: *pipelinesv1beta1.NewArrayOrString("repo")},
+				{Name: "azure-devops-sha", Value: *pipelinesv1beta1.NewArrayOrString("abc")},
+				{Name: "azure-devops-pull-request-id", Value: *pipelinesv1beta1.NewArrayOrString("12")},
+			},
+			wantPaths: []string{commitPath, pullRequestPath},
+		},
+		{
+			name:         "GenericAnnotations",
+			organization: "org",
+			annotations: map[string]string{
+				"integrations.tekton.ornew.io/owner": "project",
+				"integrations.tekton.ornew.io/repo":  "repo",
+				"integrations.tekton.ornew.io/sha":   "abc",
+			},
+			wantPaths: []string{commitPath},
+		},
+		{
+			name: "MissingOrganization",
+			annotations: map[string]string{
+				"integrations.tekton.ornew.io/owner": "project",
+				"integrations.tekton.ornew.io/repo":  "repo",
+				"integrations.tekton.ornew.io/sha":   "abc",
+			},
+			wantErr: NewFailedValidationError(""),
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			var paths []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				paths = append(paths, r.URL.Path)
+				user, pass, _ := r.BasicAuth()
+				assert.Equal(t, "", user)
+				assert.Equal(t, "pat", pass)
+				var body azureDevOpsStatusRequest
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("failed to decode request: %v", err)
+				}
+				assert.Equal(t, azureDevOpsStatusRequest{
+					State:       "failed",
+					Description: "Failed",
+					Context: azureDevOpsStatusContext{
+						Name:  "build",
+						Genre: "tekton",
+					},
+				}, body)
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte(`{}`))
+			}))
+			defer srv.Close()
+			pr := &pipelinesv1beta1.PipelineRun{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "foo",
+					Namespace:   "bar",
+					Annotations: c.annotations,
+				},
+				Spec: pipelinesv1beta1.PipelineRunSpec{
+					PipelineRef: &pipelinesv1beta1.PipelineRef{Name: "build"},
+					Params:      c.params,
+				},
+				Status: pipelinesv1beta1.PipelineRunStatus{
+					Status: knativeapisduckv1beta1.Status{
+						Conditions: []knativeapis.Condition{
+							{
+								Type:   knativeapis.ConditionSucceeded,
+								Status: corev1.ConditionFalse,
+								Reason: "Failed",
+							},
+						},
+					},
+				},
+			}
+			a := &AzureDevOps{
+				BaseURL:      srv.URL,
+				Organization: c.organization,
+				AccessToken:  NewSecretString("pat"),
+				Genre:        "tekton",
+			}
+			err := a.Notify(ctx, pr)
+			if c.wantErr != nil {
+				if assert.NotNil(t, err) {
+					assert.Equal(t, c.wantErr.Code, err.Code)
+				}
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, c.wantPaths, paths)
+		})
+	}
+}
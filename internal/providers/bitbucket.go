@@ -0,0 +1,238 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"knative.dev/pkg/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/go-logr/logr"
+	pipelinesv1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+
+	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
+)
+
+const (
+	bitbucketCloudAPIURL   = "https://api.bitbucket.org/2.0"
+	bitbucketCloudTokenURL = "https://bitbucket.org/site/oauth2/access_token"
+
+	BitbucketAuthTypeAppPassword   = "AppPassword"
+	BitbucketAuthTypeOAuthConsumer = "OAuthConsumer"
+	BitbucketAuthTypeAccessToken   = "AccessToken"
+
+	// bitbucketMaxKeyLength is the max length of the key of the build status on Bitbucket Cloud.
+	bitbucketMaxKeyLength = 40
+)
+
+type Bitbucket struct {
+	// APIURL is the Bitbucket Cloud API or the base URL of Bitbucket Data Center.
+	APIURL string
+	// TokenURL is the OAuth 2.0 token endpoint of Bitbucket Cloud.
+	TokenURL   string
+	DataCenter bool
+	AuthType   string
+	// Username is the username for AppPassword or the client ID for OAuthConsumer.
+	Username string
+	// Password is the app password, the client secret or the access token.
+	Password SecretString
+}
+
+var _ Provider = (*Bitbucket)(nil)
+
+func NewBitbucket(ctx context.Context, p *v1alpha1.Provider, k client.Client) (*Bitbucket, *ProviderError) {
+	s := p.Spec.Bitbucket
+	if s == nil {
+		return nil, NewInvalidProviderSpecError("missing value .bitbucket")
+	}
+	a := &Bitbucket{
+		APIURL:   bitbucketCloudAPIURL,
+		TokenURL: bitbucketCloudTokenURL,
+		AuthType: s.AuthType,
+	}
+	if s.BaseURL != nil {
+		a.APIURL = strings.TrimSuffix(*s.BaseURL, "/")
+		a.TokenURL = ""
+		a.DataCenter = true
+	}
+	var userKey, passwordKey string
+	switch s.AuthType {
+	case BitbucketAuthTypeAppPassword:
+		userKey, passwordKey = "username", "app-password"
+	case BitbucketAuthTypeOAuthConsumer:
+		if a.DataCenter {
+			return nil, NewInvalidProviderSpecError("OAuthConsumer is not supported on Bitbucket Data Center")
+		}
+		userKey, passwordKey = "client-id", "client-secret"
+	case BitbucketAuthTypeAccessToken:
+		passwordKey = "access-token"
+	default:
+		return nil, NewInvalidProviderSpecError(fmt.Sprintf("unknown auth type: %v", s.AuthType))
+	}
+	required := []string{passwordKey}
+	if len(userKey) > 0 {
+		required = append(required, userKey)
+	}
+	data, perr := getSecretData(ctx, k, p.Namespace, s.SecretRef.Name, required...)
+	if perr != nil {
+		return nil, perr
+	}
+	if len(userKey) > 0 {
+		a.Username = string(data[userKey])
+	}
+	a.Password = NewSecretString(string(data[passwordKey]))
+	return a, nil
+}
+
+type bitbucketBuildStatusRequest struct {
+	Key         string `json:"key"`
+	State       string `json:"state"`
+	Name        string `json:"name"`
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
+
+type bitbucketTokenResponse struct {
+	AccessToken string `json:"access_token"`
+}
+
+// getBitbucketStatusKey returns the context ID as the key of the build status.
+// The long ID is truncated and suffixed with its hash to fit in the max length, keeping the keys unique.
+// The length is counted in characters, so that the multi-byte characters are not split.
+func getBitbucketStatusKey(id string) string {
+	r := []rune(id)
+	if len(r) <= bitbucketMaxKeyLength {
+		return id
+	}
+	h := sha256.Sum256([]byte(id))
+	suffix := hex.EncodeToString(h[:])[:8]
+	return string(r[:bitbucketMaxKeyLength-len(suffix)-1]) + "-" + suffix
+}
+
+func (a *Bitbucket) Notify(ctx context.Context, pr *pipelinesv1beta1.PipelineRun) *ProviderError {
+	log := logr.FromContext(ctx).WithName("providers.bitbucket").
+		WithValues("providerType", "Bitbucket", "pipelineRun", pr.Name)
+	key, perr := getContextID(pr)
+	if perr != nil {
+		return perr
+	}
+	name, perr := getStatusContext(pr)
+	if perr != nil {
+		return perr
+	}
+	ref, perr := getRepositoryRef(pr, "bitbucket")
+	if perr != nil {
+		return perr
+	}
+	targetURL := getDashboardTargetURL(pr)
+	if len(targetURL) < 1 {
+		return NewFailedValidationError(fmt.Sprintf("Bitbucket requires the URL of the build, annotate %s", annotationTektonDashboardBaseURL))
+	}
+	cond := pr.Status.GetCondition(apis.ConditionSucceeded)
+	if cond == nil {
+		log.V(1).Info("PipelineRun has not condition, ignored")
+		return NewSkippedError("PipelineRun has not condition")
+	}
+	status := &bitbucketBuildStatusRequest{
+		Key:         getBitbucketStatusKey(key),
+		State:       toBitbucketBuildStatus(cond, a.DataCenter),
+		Name:        name,
+		URL:         targetURL,
+		Description: cond.Reason,
+	}
+	var u string
+	if a.DataCenter {
+		u = fmt.Sprintf("%s/rest/build-status/1.0/commits/%s", a.APIURL, url.PathEscape(ref.Revision))
+	} else {
+		u = fmt.Sprintf("%s/repositories/%s/%s/commit/%s/statuses/build", a.APIURL,
+			url.PathEscape(ref.Owner), url.PathEscape(ref.Repo), url.PathEscape(ref.Revision))
+	}
+	auth, perr := a.authorization(ctx)
+	if perr != nil {
+		return perr
+	}
+	if _, err := postHTTP(ctx, u, auth, status); err != nil {
+		return newHTTPError("Bitbucket", err)
+	}
+	log.V(2).Info("set build status", "status", status)
+	return nil
+}
+
+// authorization returns the value of the Authorization header,
+// requesting an access token with the client credentials grant for OAuthConsumer.
+func (a *Bitbucket) authorization(ctx context.Context) (string, *ProviderError) {
+	switch a.AuthType {
+	case BitbucketAuthTypeAppPassword:
+		return basicAuthorization(a.Username, a.Password.GetNoRedactedString()), nil
+	case BitbucketAuthTypeAccessToken:
+		return fmt.Sprintf("Bearer %s", a.Password.GetNoRedactedString()), nil
+	}
+	form := url.Values{"grant_type": {"client_credentials"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", NewRuntimeError(fmt.Sprintf("failed to create Bitbucket token request: %v", err))
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", basicAuthorization(a.Username, a.Password.GetNoRedactedString()))
+	b, err := sendHTTP(req)
+	if err != nil {
+		return "", newHTTPError("Bitbucket access token", err)
+	}
+	var token bitbucketTokenResponse
+	if err := json.Unmarshal(b, &token); err != nil {
+		return "", NewRuntimeError(fmt.Sprintf("failed to unmarshal Bitbucket token response: %v", err))
+	}
+	return fmt.Sprintf("Bearer %s", token.AccessToken), nil
+}
+
+func basicAuthorization(username, password string) string {
+	cred := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+	return fmt.Sprintf("Basic %s", cred)
+}
+
+const (
+	BitbucketBuildStatusInProgress = "INPROGRESS"
+	BitbucketBuildStatusSuccessful = "SUCCESSFUL"
+	BitbucketBuildStatusFailed     = "FAILED"
+	BitbucketBuildStatusStopped    = "STOPPED"
+)
+
+// toBitbucketBuildStatus maps the condition to the build state.
+// Bitbucket Data Center does not support STOPPED, so cancelled runs are FAILED.
+func toBitbucketBuildStatus(c *apis.Condition, dataCenter bool) string {
+	switch c.Status {
+	case corev1.ConditionUnknown:
+		return BitbucketBuildStatusInProgress
+	case corev1.ConditionTrue:
+		return BitbucketBuildStatusSuccessful
+	case corev1.ConditionFalse:
+		if isCancelled(c) && !dataCenter {
+			return BitbucketBuildStatusStopped
+		}
+	}
+	return BitbucketBuildStatusFailed
+}
@@ -0,0 +1,374 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	knativeapis "knative.dev/pkg/apis"
+	knativeapisduckv1beta1 "knative.dev/pkg/apis/duck/v1beta1"
+
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	pipelinesv1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+
+	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
+)
+
+func TestNewBitbucket(t *testing.T) {
+	k := fakeclient.NewClientBuilder().
+		WithObjects(
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "app-password",
+					Namespace: "default",
+				},
+				Data: map[string][]byte{
+					"username":     []byte("user"),
+					"app-password": []byte("password"),
+				},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "access-token",
+					Namespace: "default",
+				},
+				Data: map[string][]byte{
+					"access-token": []byte("token"),
+				},
+			}).
+		Build()
+	for _, c := range []struct {
+		name    string
+		spec    *v1alpha1.BitbucketSpec
+		want    *Bitbucket
+		wantErr *ProviderError
+	}{
+		{
+			name: "CloudAppPassword",
+			spec: &v1alpha1.BitbucketSpec{
+				AuthType:  "AppPassword",
+				SecretRef: corev1.LocalObjectReference{Name: "app-password"},
+			},
+			want: &Bitbucket{
+				APIURL:   "https://api.bitbucket.org/2.0",
+				TokenURL: "https://bitbucket.org/site/oauth2/access_token",
+				AuthType: "AppPassword",
+				Username: "user",
+				Password: NewSecretString("password"),
+			},
+		},
+		{
+			name: "DataCenterAccessToken",
+			spec: &v1alpha1.BitbucketSpec{
+				BaseURL:   pointer.String("https://bitbucket.example.com/"),
+				AuthType:  "AccessToken",
+				SecretRef: corev1.LocalObjectReference{Name: "access-token"},
+			},
+			want: &Bitbucket{
+				APIURL:     "https://bitbucket.example.com",
+				DataCenter: true,
+				AuthType:   "AccessToken",
+				Password:   NewSecretString("token"),
+			},
+		},
+		{
+			name: "DataCenterOAuthConsumer",
+			spec: &v1alpha1.BitbucketSpec{
+				BaseURL:   pointer.String("https://bitbucket.example.com"),
+				AuthType:  "OAuthConsumer",
+				SecretRef: corev1.LocalObjectReference{Name: "app-password"},
+			},
+			wantErr: NewInvalidProviderSpecError(""),
+		},
+		{
+			name: "SecretKeyNotFound",
+			spec: &v1alpha1.BitbucketSpec{
+				AuthType:  "AppPassword",
+				SecretRef: corev1.LocalObjectReference{Name: "access-token"},
+			},
+			wantErr: NewNotFoundPrivateKeyError(""),
+		},
+		{
+			name:    "BitbucketSpecNotFound",
+			wantErr: NewInvalidProviderSpecError(""),
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			p := &v1alpha1.Provider{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "provider",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.ProviderSpec{
+					Type:      "Bitbucket",
+					Bitbucket: c.spec,
+				},
+			}
+			a, err := NewBitbucket(ctx, p, k)
+			if c.wantErr != nil {
+				if assert.NotNil(t, err) {
+					assert.Equal(t, c.wantErr.Code, err.Code)
+				}
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, c.want, a)
+		})
+	}
+}
+
+type bitbucketRequest struct {
+	Path string
+	Auth string
+	Body bitbucketBuildStatusRequest
+}
+
+func newFakeBitbucket(t *testing.T, requests *[]bitbucketRequest) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/site/oauth2/access_token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("failed to parse form: %v", err)
+		}
+		id, secret, _ := r.BasicAuth()
+		if r.Form.Get("grant_type") != "client_credentials" || id != "client" || secret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"access_token":"oauth-token","token_type":"bearer"}`))
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		var body bitbucketBuildStatusRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		*requests = append(*requests, bitbucketRequest{
+			Path: r.URL.Path,
+			Auth: r.Header.Get("Authorization"),
+			Body: body,
+		})
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{}`))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newBitbucketTestPipelineRun(status corev1.ConditionStatus, reason string, annotations map[string]string) *pipelinesv1beta1.PipelineRun {
+	a := map[string]string{
+		"integrations.tekton.ornew.io/tekton-dashboard-base-url": "http://dashboard.example.com",
+	}
+	for k, v := range annotations {
+		a[k] = v
+	}
+	return &pipelinesv1beta1.PipelineRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "foo",
+			Namespace:   "bar",
+			Annotations: a,
+		},
+		Spec: pipelinesv1beta1.PipelineRunSpec{
+			PipelineRef: &pipelinesv1beta1.PipelineRef{Name: "build"},
+		},
+		Status: pipelinesv1beta1.PipelineRunStatus{
+			Status: knativeapisduckv1beta1.Status{
+				Conditions: []knativeapis.Condition{
+					{
+						Type:   knativeapis.ConditionSucceeded,
+						Status: status,
+						Reason: reason,
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestBitbucketNotify(t *testing.T) {
+	bitbucketAnnotations := map[string]string{
+		"integrations.tekton.ornew.io/bitbucket-owner": "workspace",
+		"integrations.tekton.ornew.io/bitbucket-repo":  "repo",
+		"integrations.tekton.ornew.io/bitbucket-sha":   "abc",
+	}
+	genericAnnotations := map[string]string{
+		"integrations.tekton.ornew.io/owner": "workspace",
+		"integrations.tekton.ornew.io/repo":  "repo",
+		"integrations.tekton.ornew.io/sha":   "abc",
+	}
+	for _, c := range []struct {
+		name       string
+		dataCenter bool
+		authType   string
+		username   string
+		password   string
+		pr         *pipelinesv1beta1.PipelineRun
+		wantPath   string
+		wantAuth   string
+		wantState  string
+		wantErr    *ProviderError
+	}{
+		{
+			name:      "CloudAppPassword",
+			authType:  "AppPassword",
+			username:  "user",
+			password:  "password",
+			pr:        newBitbucketTestPipelineRun(corev1.ConditionUnknown, "Running", bitbucketAnnotations),
+			wantPath:  "/repositories/workspace/repo/commit/abc/statuses/build",
+			wantAuth:  "Basic dXNlcjpwYXNzd29yZA==",
+			wantState: "INPROGRESS",
+		},
+		{
+			name:      "CloudOAuthConsumer",
+			authType:  "OAuthConsumer",
+			username:  "client",
+			password:  "secret",
+			pr:        newBitbucketTestPipelineRun(corev1.ConditionFalse, "Cancelled", genericAnnotations),
+			wantPath:  "/repositories/workspace/repo/commit/abc/statuses/build",
+			wantAuth:  "Bearer oauth-token",
+			wantState: "STOPPED",
+		},
+		{
+			name:     "CloudOAuthConsumerUnauthorized",
+			authType: "OAuthConsumer",
+			username: "client",
+			password: "wrong",
+			pr:       newBitbucketTestPipelineRun(corev1.ConditionTrue, "Succeeded", genericAnnotations),
+			wantErr:  NewRuntimeError(""),
+		},
+		{
+			name:       "DataCenterAccessToken",
+			dataCenter: true,
+			authType:   "AccessToken",
+			password:   "token",
+			pr:         newBitbucketTestPipelineRun(corev1.ConditionTrue, "Succeeded", genericAnnotations),
+			wantPath:   "/rest/build-status/1.0/commits/abc",
+			wantAuth:   "Bearer token",
+			wantState:  "SUCCESSFUL",
+		},
+		{
+			name:       "DataCenterCancelled",
+			dataCenter: true,
+			authType:   "AccessToken",
+			password:   "token",
+			pr:         newBitbucketTestPipelineRun(corev1.ConditionFalse, "Cancelled", genericAnnotations),
+			wantPath:   "/rest/build-status/1.0/commits/abc",
+			wantAuth:   "Bearer token",
+			wantState:  "FAILED",
+		},
+		{
+			name:     "MissingDashboardURL",
+			authType: "AccessToken",
+			pr: newBitbucketTestPipelineRun(corev1.ConditionTrue, "Succeeded", map[string]string{
+				"integrations.tekton.ornew.io/owner":                     "workspace",
+				"integrations.tekton.ornew.io/repo":                      "repo",
+				"integrations.tekton.ornew.io/sha":                       "abc",
+				"integrations.tekton.ornew.io/tekton-dashboard-base-url": "",
+			}),
+			wantErr: NewFailedValidationError(""),
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			var requests []bitbucketRequest
+			srv := newFakeBitbucket(t, &requests)
+			a := &Bitbucket{
+				APIURL:     srv.URL,
+				TokenURL:   srv.URL + "/site/oauth2/access_token",
+				DataCenter: c.dataCenter,
+				AuthType:   c.authType,
+				Username:   c.username,
+				Password:   NewSecretString(c.password),
+			}
+			err := a.Notify(ctx, c.pr)
+			if c.wantErr != nil {
+				if assert.NotNil(t, err) {
+					assert.Equal(t, c.wantErr.Code, err.Code)
+				}
+				return
+			}
+			assert.Nil(t, err)
+			if assert.Len(t, requests, 1) {
+				assert.Equal(t, c.wantPath, requests[0].Path)
+				assert.Equal(t, c.wantAuth, requests[0].Auth)
+				assert.Equal(t, bitbucketBuildStatusRequest{
+					Key:         "build",
+					State:       c.wantState,
+					Name:        "tekton: build",
+					URL:         "http://dashboard.example.com/#/namespaces/bar/pipelineruns/foo",
+					Description: c.pr.Status.GetCondition(knativeapis.ConditionSucceeded).Reason,
+				}, requests[0].Body)
+			}
+		})
+	}
+}
+
+func TestGetBitbucketStatusKey(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		id   string
+		want string
+	}{
+		{
+			name: "Short",
+			id:   "build",
+			want: "build",
+		},
+		{
+			name: "MaxLength",
+			id:   strings.Repeat("a", 40),
+			want: strings.Repeat("a", 40),
+		},
+		{
+			name: "Long",
+			id:   "deploy-production-" + strings.Repeat("a", 40),
+			want: "deploy-production-aaaaaaaaaaaaa-be0eafff",
+		},
+		{
+			name: "MultiByteMaxLength",
+			id:   strings.Repeat("デ", 40),
+			want: strings.Repeat("デ", 40),
+		},
+		{
+			name: "MultiByteLong",
+			id:   "デプロイ-" + strings.Repeat("あ", 40),
+			want: "デプロイ-" + strings.Repeat("あ", 26) + "-934d8cd9",
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			key := getBitbucketStatusKey(c.id)
+			assert.Equal(t, c.want, key)
+			assert.True(t, utf8.ValidString(key))
+			assert.LessOrEqual(t, utf8.RuneCountInString(key), 40)
+		})
+	}
+	// the long IDs with the same prefix have the different keys.
+	assert.NotEqual(t,
+		getBitbucketStatusKey(strings.Repeat("a", 50)+"-build"),
+		getBitbucketStatusKey(strings.Repeat("a", 50)+"-test"))
+}
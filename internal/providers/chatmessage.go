@@ -0,0 +1,190 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"knative.dev/pkg/apis"
+
+	pipelinesv1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+)
+
+const (
+	chatColorGood    = "#2EB886"
+	chatColorWarning = "#DAA038"
+	chatColorDanger  = "#A30100"
+)
+
+// chatMessage is the content of the built-in message of the chat providers.
+// Each provider renders it in the format of the service, so that all of them show the same content.
+type chatMessage struct {
+	// Title is the name of the run, e.g. sample-run.default
+	Title        string
+	Status       corev1.ConditionStatus
+	Reason       string
+	Message      string
+	Color        string
+	Namespace    string
+	Pipeline     string
+	Duration     string
+	DashboardURL string
+	FailedTasks  []chatFailedTask
+}
+
+// chatFailedTask is a failed TaskRun of the run.
+type chatFailedTask struct {
+	PipelineTask string
+	TaskRun      string
+	Reason       string
+	Message      string
+}
+
+// chatFact is a labeled value shown in the table of the message.
+type chatFact struct {
+	Title string
+	Value string
+}
+
+// newChatMessage returns the content of the run, which must have the Succeeded condition.
+func newChatMessage(pr *pipelinesv1beta1.PipelineRun) *chatMessage {
+	cond := pr.Status.GetCondition(apis.ConditionSucceeded)
+	m := &chatMessage{
+		Title:        fmt.Sprintf("%s.%s", pr.Name, pr.Namespace),
+		Status:       cond.Status,
+		Reason:       cond.Reason,
+		Message:      cond.Message,
+		Color:        getChatColor(cond),
+		Namespace:    pr.Namespace,
+		Duration:     "running",
+		DashboardURL: getDashboardTargetURL(pr),
+		FailedTasks:  getChatFailedTasks(pr),
+	}
+	if pr.Spec.PipelineRef != nil {
+		m.Pipeline = pr.Spec.PipelineRef.Name
+	}
+	if pr.Status.StartTime != nil && pr.Status.CompletionTime != nil {
+		m.Duration = pr.Status.CompletionTime.Time.Sub(pr.Status.StartTime.Time).String()
+	}
+	return m
+}
+
+// Summary returns the one line summary of the run, e.g. Failed: sample-run.default
+func (m *chatMessage) Summary() string {
+	return fmt.Sprintf("%s: %s", m.Reason, m.Title)
+}
+
+// Facts returns the namespace, the pipeline, the duration and the reason of the run.
+func (m *chatMessage) Facts() []chatFact {
+	facts := []chatFact{
+		{Title: "Namespace", Value: m.Namespace},
+	}
+	if len(m.Pipeline) > 0 {
+		facts = append(facts, chatFact{Title: "Pipeline", Value: m.Pipeline})
+	}
+	return append(facts,
+		chatFact{Title: "Duration", Value: m.Duration},
+		chatFact{Title: "Reason", Value: m.Reason},
+	)
+}
+
+// ColorInt returns the color as an integer, e.g. 0xA30100
+func (m *chatMessage) ColorInt() int {
+	c, _ := strconv.ParseInt(strings.TrimPrefix(m.Color, "#"), 16, 32)
+	return int(c)
+}
+
+// formatFailureDetails returns the reasons of the failed tasks, one per line.
+// The names of the tasks are formatted by nameFormat to emphasize them, e.g. "*%s*" for Slack.
+func formatFailureDetails(tasks []chatFailedTask, nameFormat string) string {
+	b := strings.Builder{}
+	for _, t := range tasks {
+		fmt.Fprintf(&b, nameFormat+" (%s) %s: %s\n", t.PipelineTask, t.TaskRun, t.Reason, t.Message)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func getChatColor(c *apis.Condition) string {
+	switch c.Status {
+	case corev1.ConditionUnknown:
+		return chatColorWarning
+	case corev1.ConditionTrue:
+		return chatColorGood
+	}
+	return chatColorDanger
+}
+
+// getChatFailedTasks returns the failed TaskRuns of the run, sorted by the name of the TaskRun.
+func getChatFailedTasks(pr *pipelinesv1beta1.PipelineRun) []chatFailedTask {
+	names := make([]string, 0, len(pr.Status.TaskRuns))
+	for name := range pr.Status.TaskRuns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var tasks []chatFailedTask
+	for _, name := range names {
+		tr := pr.Status.TaskRuns[name]
+		if tr.Status == nil {
+			continue
+		}
+		c := tr.Status.GetCondition(apis.ConditionSucceeded)
+		if c == nil || c.Status != corev1.ConditionFalse {
+			continue
+		}
+		tasks = append(tasks, chatFailedTask{
+			PipelineTask: tr.PipelineTaskName,
+			TaskRun:      name,
+			Reason:       c.Reason,
+			Message:      c.Message,
+		})
+	}
+	return tasks
+}
+
+// chatAttachment is the Slack-compatible message attachment, which Mattermost and Rocket.Chat accept.
+type chatAttachment struct {
+	Fallback  string                `json:"fallback,omitempty"`
+	Color     string                `json:"color,omitempty"`
+	Title     string                `json:"title,omitempty"`
+	TitleLink string                `json:"title_link,omitempty"`
+	Text      string                `json:"text,omitempty"`
+	Fields    []chatAttachmentField `json:"fields,omitempty"`
+}
+
+type chatAttachmentField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+func newChatAttachment(m *chatMessage) chatAttachment {
+	a := chatAttachment{
+		Fallback:  m.Summary(),
+		Color:     m.Color,
+		Title:     m.Title,
+		TitleLink: m.DashboardURL,
+		Text:      fmt.Sprintf("%s: %s", m.Reason, m.Message),
+	}
+	for _, f := range m.Facts() {
+		a.Fields = append(a.Fields, chatAttachmentField{Title: f.Title, Value: f.Value, Short: true})
+	}
+	return a
+}
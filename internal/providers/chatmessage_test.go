@@ -0,0 +1,71 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestNewChatMessage(t *testing.T) {
+	pr := newTemplateSamplePipelineRun()
+	m := newChatMessage(pr)
+	assert.Equal(t, &chatMessage{
+		Title:        "sample-run.default",
+		Status:       corev1.ConditionFalse,
+		Reason:       "Failed",
+		Message:      "Tasks Completed: 1 (Failed: 1, Cancelled 0), Skipped: 0",
+		Color:        chatColorDanger,
+		Namespace:    "default",
+		Pipeline:     "sample",
+		Duration:     "1m0s",
+		DashboardURL: "https://dashboard.example.com/#/namespaces/default/pipelineruns/sample-run",
+		FailedTasks: []chatFailedTask{
+			{
+				PipelineTask: "build",
+				TaskRun:      "sample-run-build",
+				Reason:       "Failed",
+				Message:      "Tasks Completed: 1 (Failed: 1, Cancelled 0), Skipped: 0",
+			},
+		},
+	}, m)
+	assert.Equal(t, "Failed: sample-run.default", m.Summary())
+	assert.Equal(t, 0xA30100, m.ColorInt())
+	assert.Equal(t, []chatFact{
+		{Title: "Namespace", Value: "default"},
+		{Title: "Pipeline", Value: "sample"},
+		{Title: "Duration", Value: "1m0s"},
+		{Title: "Reason", Value: "Failed"},
+	}, m.Facts())
+	assert.Equal(t, "**build** (sample-run-build) Failed: Tasks Completed: 1 (Failed: 1, Cancelled 0), Skipped: 0", formatFailureDetails(m.FailedTasks, "**%s**"))
+
+	pr.Spec.PipelineRef = nil
+	pr.Status.CompletionTime = nil
+	pr.Status.Conditions[0].Status = corev1.ConditionUnknown
+	delete(pr.Annotations, annotationTektonDashboardBaseURL)
+	m = newChatMessage(pr)
+	assert.Equal(t, chatColorWarning, m.Color)
+	assert.Equal(t, "running", m.Duration)
+	assert.Empty(t, m.DashboardURL)
+	assert.Equal(t, []chatFact{
+		{Title: "Namespace", Value: "default"},
+		{Title: "Duration", Value: "running"},
+		{Title: "Reason", Value: "Failed"},
+	}, m.Facts())
+}
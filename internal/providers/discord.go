@@ -0,0 +1,176 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"context"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"knative.dev/pkg/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/go-logr/logr"
+	pipelinesv1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+
+	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
+)
+
+// The limits of the embeds.
+// See https://discord.com/developers/docs/resources/channel#embed-object-embed-limits
+const (
+	discordEmbedTitleLimit       = 256
+	discordEmbedDescriptionLimit = 4096
+	discordEmbedFieldNameLimit   = 256
+	discordEmbedFieldValueLimit  = 1024
+	discordEmbedTotalLimit       = 6000
+)
+
+// Discord posts the embeds to a Discord webhook.
+type Discord struct {
+	URL      SecretString
+	Username string
+}
+
+var _ Provider = (*Discord)(nil)
+
+func NewDiscord(ctx context.Context, p *v1alpha1.Provider, k client.Client) (*Discord, *ProviderError) {
+	s := p.Spec.Discord
+	if s == nil {
+		return nil, NewInvalidProviderSpecError("missing value .discord")
+	}
+	u, perr := getWebhookURL(ctx, k, p.Namespace, s.URL)
+	if perr != nil {
+		return nil, perr
+	}
+	a := &Discord{
+		URL: u,
+	}
+	if s.Username != nil {
+		a.Username = *s.Username
+	}
+	return a, nil
+}
+
+func (a *Discord) Notify(ctx context.Context, pr *pipelinesv1beta1.PipelineRun) *ProviderError {
+	log := logr.FromContext(ctx).WithName("providers.discord").
+		WithValues("providerType", "Discord", "pipelineRun", pr.Name)
+	cond := pr.Status.GetCondition(apis.ConditionSucceeded)
+	if cond == nil {
+		log.V(1).Info("PipelineRun has not condition, ignored")
+		return NewSkippedError("PipelineRun has not condition")
+	}
+	if cond.Status == corev1.ConditionUnknown {
+		log.V(2).Info("this run is not finished yet, skipped")
+		return NewSkippedError("this run is not finished yet")
+	}
+	payload := &discordWebhookRequest{
+		Username: a.Username,
+		Embeds:   []discordEmbed{newDiscordEmbedFromPipelineRun(pr)},
+		// the names of the runs and the tasks must not mention anyone.
+		AllowedMentions: &discordAllowedMentions{Parse: []string{}},
+	}
+	// the 429 responses are retried by the shared client with Retry-After.
+	if _, err := postHTTP(ctx, a.URL.GetNoRedactedString(), "", payload); err != nil {
+		return newWebhookError("Discord", err)
+	}
+	log.V(2).Info("post message")
+	return nil
+}
+
+type discordWebhookRequest struct {
+	Username        string                  `json:"username,omitempty"`
+	Embeds          []discordEmbed          `json:"embeds"`
+	AllowedMentions *discordAllowedMentions `json:"allowed_mentions,omitempty"`
+}
+
+type discordAllowedMentions struct {
+	Parse []string `json:"parse"`
+}
+
+type discordEmbed struct {
+	Title       string              `json:"title"`
+	Description string              `json:"description,omitempty"`
+	URL         string              `json:"url,omitempty"`
+	Color       int                 `json:"color"`
+	Fields      []discordEmbedField `json:"fields,omitempty"`
+}
+
+type discordEmbedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline,omitempty"`
+}
+
+func newDiscordEmbedFromPipelineRun(pr *pipelinesv1beta1.PipelineRun) discordEmbed {
+	m := newChatMessage(pr)
+	e := discordEmbed{
+		Title:       m.Summary(),
+		Description: m.Message,
+		URL:         m.DashboardURL,
+		Color:       m.ColorInt(),
+		Fields: []discordEmbedField{
+			{Name: "Namespace", Value: m.Namespace, Inline: true},
+			{Name: "Duration", Value: m.Duration, Inline: true},
+		},
+	}
+	if len(m.FailedTasks) > 0 {
+		names := make([]string, 0, len(m.FailedTasks))
+		for _, t := range m.FailedTasks {
+			names = append(names, t.PipelineTask)
+		}
+		e.Fields = append(e.Fields, discordEmbedField{Name: "Failed Tasks", Value: strings.Join(names, "\n")})
+	}
+	if len(e.URL) > 0 {
+		e.Fields = append(e.Fields, discordEmbedField{Name: "Dashboard", Value: e.URL})
+	}
+	fitDiscordEmbed(&e)
+	return e
+}
+
+// fitDiscordEmbed truncates the embed to the limits, since Discord rejects the embeds over them.
+// The description is shortened first if the total exceeds the limit.
+func fitDiscordEmbed(e *discordEmbed) {
+	e.Title = truncateRunes(e.Title, discordEmbedTitleLimit)
+	e.Description = truncateRunes(e.Description, discordEmbedDescriptionLimit)
+	total := runeLen(e.Title)
+	for i := range e.Fields {
+		f := &e.Fields[i]
+		f.Name = truncateRunes(f.Name, discordEmbedFieldNameLimit)
+		f.Value = truncateRunes(f.Value, discordEmbedFieldValueLimit)
+		total += runeLen(f.Name) + runeLen(f.Value)
+	}
+	if rest := discordEmbedTotalLimit - total; runeLen(e.Description) > rest {
+		e.Description = truncateRunes(e.Description, rest)
+	}
+}
+
+func runeLen(s string) int {
+	return len([]rune(s))
+}
+
+// truncateRunes shortens the string to at most n runes, ending with an ellipsis if truncated.
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	if n < 1 {
+		return ""
+	}
+	return string(r[:n-1]) + "…"
+}
@@ -0,0 +1,218 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
+)
+
+func TestNewDiscord(t *testing.T) {
+	k := fakeclient.NewClientBuilder().
+		WithObjects(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "secret",
+				Namespace: "default",
+			},
+			Data: map[string][]byte{
+				"url": []byte("https://discord.com/api/webhooks/1/xxxx"),
+			},
+		}).
+		Build()
+	for _, c := range []struct {
+		name         string
+		spec         *v1alpha1.DiscordSpec
+		wantUsername string
+		wantErr      *ProviderError
+	}{
+		{
+			name: "Basic",
+			spec: &v1alpha1.DiscordSpec{
+				URL: v1alpha1.WebhookURLSource{
+					SecretRef: &v1alpha1.LocalSecretKeyReference{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "secret"},
+					},
+				},
+				Username: pointer.String("Tekton"),
+			},
+			wantUsername: "Tekton",
+		},
+		{
+			name: "SecretNotFound",
+			spec: &v1alpha1.DiscordSpec{
+				URL: v1alpha1.WebhookURLSource{
+					SecretRef: &v1alpha1.LocalSecretKeyReference{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "not-exists-secret"},
+					},
+				},
+			},
+			wantErr: NewNotFoundPrivateKeyError(""),
+		},
+		{
+			name:    "DiscordSpecNotFound",
+			wantErr: NewInvalidProviderSpecError(""),
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			p := &v1alpha1.Provider{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "provider",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.ProviderSpec{
+					Type:    "Discord",
+					Discord: c.spec,
+				},
+			}
+			a, err := NewDiscord(ctx, p, k)
+			if c.wantErr != nil {
+				if assert.NotNil(t, err) {
+					assert.Equal(t, c.wantErr.Code, err.Code)
+				}
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, "https://discord.com/api/webhooks/1/xxxx", a.URL.GetNoRedactedString())
+			assert.Equal(t, c.wantUsername, a.Username)
+		})
+	}
+}
+
+func TestDiscordNotify(t *testing.T) {
+	for _, c := range []struct {
+		name        string
+		status      corev1.ConditionStatus
+		respCode    int
+		want        string
+		wantErr     *ProviderError
+		wantSkipped bool
+	}{
+		{
+			name:     "Failed",
+			status:   corev1.ConditionFalse,
+			respCode: http.StatusNoContent,
+			want: `{
+				"username": "Tekton",
+				"embeds": [{
+					"title": "Failed: sample-run.default",
+					"description": "Tasks Completed: 1 (Failed: 1, Cancelled 0), Skipped: 0",
+					"url": "https://dashboard.example.com/#/namespaces/default/pipelineruns/sample-run",
+					"color": 10682624,
+					"fields": [
+						{"name": "Namespace", "value": "default", "inline": true},
+						{"name": "Duration", "value": "1m0s", "inline": true},
+						{"name": "Failed Tasks", "value": "build"},
+						{"name": "Dashboard", "value": "https://dashboard.example.com/#/namespaces/default/pipelineruns/sample-run"}
+					]
+				}],
+				"allowed_mentions": {"parse": []}
+			}`,
+		},
+		{
+			name:        "Running",
+			wantSkipped: true,
+			status:      corev1.ConditionUnknown,
+		},
+		{
+			name:     "BadRequest",
+			status:   corev1.ConditionTrue,
+			respCode: http.StatusBadRequest,
+			wantErr:  NewRuntimeError(""),
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			var bodies []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/api/webhooks/1/xxxx", r.URL.Path)
+				var body json.RawMessage
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("failed to decode request: %v", err)
+				}
+				bodies = append(bodies, string(body))
+				w.WriteHeader(c.respCode)
+			}))
+			defer srv.Close()
+			pr := newTemplateSamplePipelineRun()
+			pr.Status.Conditions[0].Status = c.status
+			a := &Discord{
+				URL:      NewSecretString(srv.URL + "/api/webhooks/1/xxxx"),
+				Username: "Tekton",
+			}
+			err := a.Notify(ctx, pr)
+			if c.wantErr != nil {
+				if assert.NotNil(t, err) {
+					assert.Equal(t, c.wantErr.Code, err.Code)
+					assert.NotContains(t, err.Message, srv.URL)
+				}
+				return
+			}
+			if c.wantSkipped {
+				assert.True(t, err.IsSkipped())
+			} else {
+				assert.Nil(t, err)
+			}
+			if len(c.want) < 1 {
+				assert.Empty(t, bodies)
+				return
+			}
+			if assert.Len(t, bodies, 1) {
+				assert.JSONEq(t, c.want, bodies[0])
+			}
+		})
+	}
+}
+
+func TestFitDiscordEmbed(t *testing.T) {
+	e := discordEmbed{
+		Title:       strings.Repeat("t", 300),
+		Description: strings.Repeat("d", 5000),
+		Fields: []discordEmbedField{
+			{Name: "a", Value: strings.Repeat("v", 2000)},
+			{Name: "b", Value: strings.Repeat("あ", 100)},
+		},
+	}
+	fitDiscordEmbed(&e)
+	assert.Equal(t, discordEmbedTitleLimit, runeLen(e.Title))
+	assert.True(t, strings.HasSuffix(e.Title, "…"))
+	assert.Equal(t, discordEmbedFieldValueLimit, runeLen(e.Fields[0].Value))
+	assert.Equal(t, strings.Repeat("あ", 100), e.Fields[1].Value)
+	assert.Equal(t, discordEmbedDescriptionLimit, runeLen(e.Description))
+
+	// the description is shortened to fit in the total limit.
+	e.Fields = append(e.Fields, discordEmbedField{Name: "c", Value: strings.Repeat("v", 1024)})
+	fitDiscordEmbed(&e)
+	total := runeLen(e.Title) + runeLen(e.Description)
+	for _, f := range e.Fields {
+		total += runeLen(f.Name) + runeLen(f.Value)
+	}
+	assert.Equal(t, discordEmbedTotalLimit, total)
+	assert.True(t, strings.HasSuffix(e.Description, "…"))
+}
@@ -0,0 +1,437 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	htmltemplate "html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"knative.dev/pkg/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/go-logr/logr"
+	pipelinesv1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+
+	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
+)
+
+const (
+	EmailTLSStartTLS = "StartTLS"
+	EmailTLSImplicit = "TLS"
+	EmailTLSNone     = "None"
+
+	annotationEmailTo = "integrations.tekton.ornew.io/email-to"
+
+	emailTimeout = time.Minute
+)
+
+const emailDefaultSubjectTemplate = `{{ .Reason }}: {{ .Name }}.{{ .Namespace }}`
+
+const emailDefaultTextTemplate = `{{ .Name }}.{{ .Namespace }}
+{{ .Reason }}: {{ .Message }}
+
+Namespace: {{ .Namespace }}
+{{- if .Pipeline }}
+Pipeline: {{ .Pipeline }}
+{{- end }}
+Duration: {{ .Duration }}
+Reason: {{ .Reason }}
+{{- range .TaskRuns }}{{ if .Failed }}
+Failed: {{ .PipelineTaskName }} ({{ .Name }}) {{ .Reason }}: {{ .Message }}
+{{- end }}{{ end }}
+{{- if .DashboardURL }}
+
+{{ .DashboardURL }}
+{{- end }}
+`
+
+const emailDefaultHTMLTemplate = `<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif;">
+<h2 style="border-left: 6px solid {{ color . }}; padding-left: 8px;">
+{{- if .DashboardURL }}<a href="{{ .DashboardURL }}">{{ .Name }}.{{ .Namespace }}</a>{{ else }}{{ .Name }}.{{ .Namespace }}{{ end -}}
+</h2>
+<p><strong>{{ .Reason }}</strong>: {{ .Message }}</p>
+<table>
+<tr><th align="left">Namespace</th><td>{{ .Namespace }}</td></tr>
+{{- if .Pipeline }}
+<tr><th align="left">Pipeline</th><td>{{ .Pipeline }}</td></tr>
+{{- end }}
+<tr><th align="left">Duration</th><td>{{ .Duration }}</td></tr>
+<tr><th align="left">Reason</th><td>{{ .Reason }}</td></tr>
+</table>
+{{- range .TaskRuns }}{{ if .Failed }}
+<p><strong>{{ .PipelineTaskName }}</strong> ({{ .Name }}) {{ .Reason }}: {{ .Message }}</p>
+{{- end }}{{ end }}
+</body>
+</html>
+`
+
+// Email sends the emails with the plaintext and the HTML bodies through an SMTP relay.
+type Email struct {
+	Host           string
+	Port           int
+	TLSMode        string
+	Username       string
+	Password       SecretString
+	From           string
+	To             []string
+	ToParam        string
+	AllowedDomains []string
+	Subject        *template.Template
+	Text           *template.Template
+	HTML           *htmltemplate.Template
+
+	// TLSConfig is the base configuration of TLS, which is used to trust the private CAs in the tests.
+	TLSConfig *tls.Config
+	now       func() time.Time
+}
+
+var _ Provider = (*Email)(nil)
+var _ Validator = (*Email)(nil)
+
+func NewEmail(ctx context.Context, p *v1alpha1.Provider, k client.Client) (*Email, *ProviderError) {
+	s := p.Spec.Email
+	if s == nil {
+		return nil, NewInvalidProviderSpecError("missing value .email")
+	}
+	if len(s.Host) < 1 {
+		return nil, NewInvalidProviderSpecError("missing value .email.host")
+	}
+	from, err := mail.ParseAddress(s.From)
+	if err != nil {
+		return nil, NewInvalidProviderSpecError(fmt.Sprintf("invalid address in .email.from: %v", err))
+	}
+	for _, to := range s.To {
+		if _, err := mail.ParseAddress(to); err != nil {
+			return nil, NewInvalidProviderSpecError(fmt.Sprintf("invalid address in .email.to: %v", err))
+		}
+	}
+	a := &Email{
+		Host:           s.Host,
+		TLSMode:        EmailTLSStartTLS,
+		From:           from.String(),
+		To:             s.To,
+		AllowedDomains: s.AllowedDomains,
+		now:            time.Now,
+	}
+	if s.TLS != nil && len(*s.TLS) > 0 {
+		a.TLSMode = *s.TLS
+	}
+	switch a.TLSMode {
+	case EmailTLSStartTLS, EmailTLSNone:
+		a.Port = 587
+	case EmailTLSImplicit:
+		a.Port = 465
+	default:
+		return nil, NewInvalidProviderSpecError(fmt.Sprintf("unknown TLS mode: %v", a.TLSMode))
+	}
+	if s.Port != nil {
+		a.Port = int(*s.Port)
+	}
+	if s.ToParam != nil {
+		a.ToParam = *s.ToParam
+	}
+	if s.SecretRef != nil {
+		data, perr := getSecretData(ctx, k, p.Namespace, s.SecretRef.Name, "username", "password")
+		if perr != nil {
+			return nil, perr
+		}
+		a.Username = string(data["username"])
+		a.Password = NewSecretString(string(data["password"]))
+	}
+	var perr *ProviderError
+	if a.Subject, perr = parseEmailTemplate("subjectTemplate", s.SubjectTemplate, emailDefaultSubjectTemplate); perr != nil {
+		return nil, perr
+	}
+	if a.Text, perr = parseEmailTemplate("textTemplate", s.TextTemplate, emailDefaultTextTemplate); perr != nil {
+		return nil, perr
+	}
+	if a.HTML, perr = parseEmailHTMLTemplate(s.HTMLTemplate); perr != nil {
+		return nil, perr
+	}
+	return a, nil
+}
+
+func parseEmailTemplate(name, text, defaultText string) (*template.Template, *ProviderError) {
+	if len(text) < 1 {
+		text = defaultText
+	}
+	return parseTemplate(name, text)
+}
+
+// parseEmailHTMLTemplate parses the HTML template, which escapes the values of the run.
+func parseEmailHTMLTemplate(text string) (*htmltemplate.Template, *ProviderError) {
+	if len(text) < 1 {
+		text = emailDefaultHTMLTemplate
+	}
+	funcs := htmltemplate.FuncMap{}
+	for k, f := range templateFuncs {
+		funcs[k] = f
+	}
+	funcs["color"] = func(d *TemplateData) string {
+		return getChatColor(&apis.Condition{Status: corev1.ConditionStatus(d.Status)})
+	}
+	t, err := htmltemplate.New("htmlTemplate").Funcs(funcs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, NewInvalidProviderSpecError(fmt.Sprintf("failed to parse the template: %v", err))
+	}
+	return t, nil
+}
+
+func (a *Email) Notify(ctx context.Context, pr *pipelinesv1beta1.PipelineRun) *ProviderError {
+	log := logr.FromContext(ctx).WithName("providers.email").
+		WithValues("providerType", "Email", "pipelineRun", pr.Name)
+	cond := pr.Status.GetCondition(apis.ConditionSucceeded)
+	if cond == nil {
+		log.V(1).Info("PipelineRun has not condition, ignored")
+		return NewSkippedError("PipelineRun has not condition")
+	}
+	if cond.Status == corev1.ConditionUnknown {
+		log.V(2).Info("this run is not finished yet, skipped")
+		return NewSkippedError("this run is not finished yet")
+	}
+	to := a.getRecipients(ctx, pr)
+	if len(to) < 1 {
+		return NewFailedValidationError("no recipients of the email")
+	}
+	msg, perr := a.newMessage(pr, to)
+	if perr != nil {
+		return perr
+	}
+	if perr := a.send(ctx, to, msg); perr != nil {
+		return perr
+	}
+	log.V(2).Info("send email", "recipients", len(to))
+	return nil
+}
+
+// Validate checks the templates.
+func (a *Email) Validate(ctx context.Context) *ProviderError {
+	_, perr := a.newMessage(newTemplateSamplePipelineRun(), a.To)
+	return perr
+}
+
+// getRecipients returns the static recipients and the recipients read from the run.
+// The recipients from the run are ignored if they are invalid or not in the allowed domains,
+// so only the static recipients are used without the allowed domains.
+func (a *Email) getRecipients(ctx context.Context, pr *pipelinesv1beta1.PipelineRun) []string {
+	log := logr.FromContext(ctx).WithName("providers.email")
+	seen := map[string]bool{}
+	var to []string
+	add := func(addr string) {
+		key := strings.ToLower(addr)
+		if !seen[key] {
+			seen[key] = true
+			to = append(to, addr)
+		}
+	}
+	for _, v := range a.To {
+		addr, _ := mail.ParseAddress(v)
+		add(addr.Address)
+	}
+	values := []string{pr.Annotations[annotationEmailTo]}
+	if len(a.ToParam) > 0 {
+		values = append(values, getParam(pr, a.ToParam))
+	}
+	for _, v := range values {
+		if len(strings.TrimSpace(v)) < 1 {
+			continue
+		}
+		addrs, err := mail.ParseAddressList(v)
+		if err != nil {
+			log.Info("ignored the invalid recipients of the run", "error", err.Error())
+			continue
+		}
+		for _, addr := range addrs {
+			if !a.isAllowedDomain(addr.Address) {
+				log.Info("ignored the recipient not in the allowed domains", "recipient", addr.Address)
+				continue
+			}
+			add(addr.Address)
+		}
+	}
+	return to
+}
+
+func (a *Email) isAllowedDomain(addr string) bool {
+	domain := addr[strings.LastIndex(addr, "@")+1:]
+	for _, d := range a.AllowedDomains {
+		if strings.EqualFold(domain, d) {
+			return true
+		}
+	}
+	return false
+}
+
+// newMessage renders the multipart/alternative message with the plaintext and the HTML bodies.
+func (a *Email) newMessage(pr *pipelinesv1beta1.PipelineRun, to []string) ([]byte, *ProviderError) {
+	subject, perr := executeTemplate(a.Subject, pr)
+	if perr != nil {
+		return nil, perr
+	}
+	text, perr := executeTemplate(a.Text, pr)
+	if perr != nil {
+		return nil, perr
+	}
+	var html bytes.Buffer
+	if err := a.HTML.Execute(&html, newTemplateData(pr)); err != nil {
+		return nil, NewFailedValidationError(fmt.Sprintf("failed to render the template: %v", err))
+	}
+
+	var b bytes.Buffer
+	body := multipart.NewWriter(&b)
+	var header bytes.Buffer
+	fmt.Fprintf(&header, "From: %s\r\n", a.From)
+	fmt.Fprintf(&header, "To: %s\r\n", strings.Join(to, ", "))
+	// the line breaks in the subject would inject the headers.
+	fmt.Fprintf(&header, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", strings.Join(strings.Fields(string(subject)), " ")))
+	fmt.Fprintf(&header, "Date: %s\r\n", a.now().Format(time.RFC1123Z))
+	fmt.Fprintf(&header, "Message-ID: %s\r\n", newEmailMessageID(a.From))
+	fmt.Fprintf(&header, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&header, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", body.Boundary())
+
+	for _, part := range []struct {
+		contentType string
+		content     []byte
+	}{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", html.Bytes()},
+	} {
+		w, err := body.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, NewRuntimeError(fmt.Sprintf("failed to create the email: %v", err))
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write(part.content); err != nil {
+			return nil, NewRuntimeError(fmt.Sprintf("failed to create the email: %v", err))
+		}
+		if err := qp.Close(); err != nil {
+			return nil, NewRuntimeError(fmt.Sprintf("failed to create the email: %v", err))
+		}
+	}
+	if err := body.Close(); err != nil {
+		return nil, NewRuntimeError(fmt.Sprintf("failed to create the email: %v", err))
+	}
+	return append(header.Bytes(), b.Bytes()...), nil
+}
+
+func newEmailMessageID(from string) string {
+	domain := "localhost"
+	if i := strings.LastIndex(from, "@"); i >= 0 {
+		domain = strings.TrimSuffix(from[i+1:], ">")
+	}
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(b), domain)
+}
+
+func (a *Email) tlsConfig() *tls.Config {
+	c := &tls.Config{}
+	if a.TLSConfig != nil {
+		c = a.TLSConfig.Clone()
+	}
+	c.ServerName = a.Host
+	return c
+}
+
+// send sends the message to the recipients through the SMTP server.
+func (a *Email) send(ctx context.Context, to []string, msg []byte) *ProviderError {
+	addr := net.JoinHostPort(a.Host, strconv.Itoa(a.Port))
+	ctx, cancel := context.WithTimeout(ctx, emailTimeout)
+	defer cancel()
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return NewRuntimeError(fmt.Sprintf("failed to connect to the SMTP server: %v", err))
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	if a.TLSMode == EmailTLSImplicit {
+		conn = tls.Client(conn, a.tlsConfig())
+	}
+	c, err := smtp.NewClient(conn, a.Host)
+	if err != nil {
+		conn.Close()
+		return newSMTPError("connect", err)
+	}
+	defer c.Close()
+	if a.TLSMode == EmailTLSStartTLS {
+		// never fall back to plaintext, since the credentials and the contents would be exposed.
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return NewRuntimeError("the SMTP server does not support STARTTLS")
+		}
+		if err := c.StartTLS(a.tlsConfig()); err != nil {
+			return newSMTPError("STARTTLS", err)
+		}
+	}
+	if len(a.Username) > 0 {
+		// PlainAuth refuses to send the password without TLS except to localhost.
+		auth := smtp.PlainAuth("", a.Username, a.Password.GetNoRedactedString(), a.Host)
+		if err := c.Auth(auth); err != nil {
+			return newSMTPError("AUTH", err)
+		}
+	}
+	from, _ := mail.ParseAddress(a.From)
+	if err := c.Mail(from.Address); err != nil {
+		return newSMTPError("MAIL", err)
+	}
+	for _, rcpt := range to {
+		if err := c.Rcpt(rcpt); err != nil {
+			return newSMTPError("RCPT "+rcpt, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return newSMTPError("DATA", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return newSMTPError("DATA", err)
+	}
+	if err := w.Close(); err != nil {
+		return newSMTPError("DATA", err)
+	}
+	if err := c.Quit(); err != nil {
+		return newSMTPError("QUIT", err)
+	}
+	return nil
+}
+
+// newSMTPError converts the error of the SMTP command, which has the reply code of the server.
+func newSMTPError(command string, err error) *ProviderError {
+	return NewRuntimeError(fmt.Sprintf("SMTP %s: %v", command, err))
+}
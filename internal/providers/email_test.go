@@ -0,0 +1,475 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http/httptest"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	pipelinesv1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+
+	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
+)
+
+// fakeSMTP is an in-process SMTP server which accepts the emails from the tests.
+type fakeSMTP struct {
+	listener net.Listener
+	tls      *tls.Config
+	// startTLS advertises STARTTLS, and implicitTLS wraps the connections with TLS.
+	startTLS bool
+	// password is the password of the user "user" for AUTH PLAIN.
+	password string
+	reject   string
+
+	mu       sync.Mutex
+	authed   bool
+	secured  bool
+	from     string
+	rcpts    []string
+	messages [][]byte
+}
+
+func newFakeSMTP(t *testing.T, startTLS, implicitTLS bool) (*fakeSMTP, *x509.CertPool) {
+	// the certificate of httptest is valid for 127.0.0.1.
+	ts := httptest.NewTLSServer(nil)
+	ts.Close()
+	pool := x509.NewCertPool()
+	pool.AddCert(ts.Certificate())
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	s := &fakeSMTP{
+		tls:      &tls.Config{Certificates: ts.TLS.Certificates},
+		startTLS: startTLS,
+		password: "password",
+	}
+	if implicitTLS {
+		l = tls.NewListener(l, s.tls)
+	}
+	s.listener = l
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn, implicitTLS)
+		}
+	}()
+	t.Cleanup(func() { l.Close() })
+	return s, pool
+}
+
+func (s *fakeSMTP) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakeSMTP) serve(conn net.Conn, secured bool) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		arg := strings.TrimSpace(strings.TrimPrefix(line, strings.SplitN(line, " ", 2)[0]))
+		switch cmd {
+		case "EHLO", "HELO":
+			if s.startTLS && !secured {
+				tp.PrintfLine("250-localhost")
+				tp.PrintfLine("250-STARTTLS")
+			} else {
+				tp.PrintfLine("250-localhost")
+			}
+			tp.PrintfLine("250 AUTH PLAIN")
+		case "STARTTLS":
+			tp.PrintfLine("220 ready")
+			tc := tls.Server(conn, s.tls)
+			if err := tc.Handshake(); err != nil {
+				return
+			}
+			conn, secured = tc, true
+			tp = textproto.NewConn(conn)
+		case "AUTH":
+			b, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(arg, "PLAIN "))
+			if string(b) != "\x00user\x00"+s.password {
+				tp.PrintfLine("535 authentication failed")
+				continue
+			}
+			s.mu.Lock()
+			s.authed = true
+			s.mu.Unlock()
+			tp.PrintfLine("235 ok")
+		case "MAIL":
+			s.mu.Lock()
+			s.from = arg
+			s.secured = secured
+			s.mu.Unlock()
+			tp.PrintfLine("250 ok")
+		case "RCPT":
+			if len(s.reject) > 0 && strings.Contains(arg, s.reject) {
+				tp.PrintfLine("550 no such user")
+				continue
+			}
+			s.mu.Lock()
+			s.rcpts = append(s.rcpts, arg)
+			s.mu.Unlock()
+			tp.PrintfLine("250 ok")
+		case "DATA":
+			tp.PrintfLine("354 go ahead")
+			b, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, b)
+			s.mu.Unlock()
+			tp.PrintfLine("250 ok")
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+func TestNewEmail(t *testing.T) {
+	k := fakeclient.NewClientBuilder().
+		WithObjects(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "secret",
+				Namespace: "default",
+			},
+			Data: map[string][]byte{
+				"username": []byte("user"),
+				"password": []byte("password"),
+			},
+		}).
+		Build()
+	for _, c := range []struct {
+		name     string
+		spec     *v1alpha1.EmailSpec
+		wantPort int
+		wantErr  *ProviderError
+	}{
+		{
+			name: "StartTLS",
+			spec: &v1alpha1.EmailSpec{
+				Host:      "smtp.example.com",
+				SecretRef: &corev1.LocalObjectReference{Name: "secret"},
+				From:      "Tekton <tekton@example.com>",
+				To:        []string{"team@example.com"},
+			},
+			wantPort: 587,
+		},
+		{
+			name: "ImplicitTLS",
+			spec: &v1alpha1.EmailSpec{
+				Host: "smtp.example.com",
+				TLS:  pointer.String(EmailTLSImplicit),
+				From: "tekton@example.com",
+			},
+			wantPort: 465,
+		},
+		{
+			name: "Port",
+			spec: &v1alpha1.EmailSpec{
+				Host: "smtp.example.com",
+				Port: pointer.Int32(2525),
+				From: "tekton@example.com",
+			},
+			wantPort: 2525,
+		},
+		{
+			name: "InvalidFrom",
+			spec: &v1alpha1.EmailSpec{
+				Host: "smtp.example.com",
+				From: "tekton",
+			},
+			wantErr: NewInvalidProviderSpecError(""),
+		},
+		{
+			name: "InvalidTemplate",
+			spec: &v1alpha1.EmailSpec{
+				Host:         "smtp.example.com",
+				From:         "tekton@example.com",
+				HTMLTemplate: "{{ .Name ",
+			},
+			wantErr: NewInvalidProviderSpecError(""),
+		},
+		{
+			name: "SecretNotFound",
+			spec: &v1alpha1.EmailSpec{
+				Host:      "smtp.example.com",
+				SecretRef: &corev1.LocalObjectReference{Name: "not-exists-secret"},
+				From:      "tekton@example.com",
+			},
+			wantErr: NewNotFoundPrivateKeyError(""),
+		},
+		{
+			name:    "EmailSpecNotFound",
+			wantErr: NewInvalidProviderSpecError(""),
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			p := &v1alpha1.Provider{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "provider",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.ProviderSpec{
+					Type:  "Email",
+					Email: c.spec,
+				},
+			}
+			a, err := NewEmail(ctx, p, k)
+			if c.wantErr != nil {
+				if assert.NotNil(t, err) {
+					assert.Equal(t, c.wantErr.Code, err.Code)
+				}
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, c.wantPort, a.Port)
+			assert.Nil(t, a.Validate(ctx))
+		})
+	}
+}
+
+func TestEmailGetRecipients(t *testing.T) {
+	pr := newTemplateSamplePipelineRun()
+	pr.Annotations[annotationEmailTo] = "Alice <alice@example.com>, team@example.com"
+	pr.Spec.Params = append(pr.Spec.Params, pipelinesv1beta1.Param{
+		Name:  "notify",
+		Value: *pipelinesv1beta1.NewArrayOrString("bob@example.com,mallory@evil.example"),
+	})
+	a := &Email{
+		To:             []string{"Team <team@example.com>"},
+		ToParam:        "notify",
+		AllowedDomains: []string{"example.com"},
+	}
+	assert.Equal(t, []string{"team@example.com", "alice@example.com", "bob@example.com"}, a.getRecipients(ctx, pr))
+
+	// the recipients of the run are ignored without the allowed domains.
+	a.AllowedDomains = nil
+	assert.Equal(t, []string{"team@example.com"}, a.getRecipients(ctx, pr))
+}
+
+func TestEmailNotify(t *testing.T) {
+	for _, c := range []struct {
+		name        string
+		tlsMode     string
+		startTLS    bool
+		implicitTLS bool
+		password    string
+		reject      string
+		status      corev1.ConditionStatus
+		wantSecured bool
+		wantErr     *ProviderError
+		wantSkipped bool
+	}{
+		{
+			name:        "StartTLS",
+			tlsMode:     EmailTLSStartTLS,
+			startTLS:    true,
+			status:      corev1.ConditionFalse,
+			wantSecured: true,
+		},
+		{
+			name:        "ImplicitTLS",
+			tlsMode:     EmailTLSImplicit,
+			implicitTLS: true,
+			status:      corev1.ConditionFalse,
+			wantSecured: true,
+		},
+		{
+			name:    "None",
+			tlsMode: EmailTLSNone,
+			status:  corev1.ConditionFalse,
+		},
+		{
+			name:    "StartTLSNotSupported",
+			tlsMode: EmailTLSStartTLS,
+			status:  corev1.ConditionFalse,
+			wantErr: NewRuntimeError(""),
+		},
+		{
+			name:     "AuthFailed",
+			tlsMode:  EmailTLSStartTLS,
+			startTLS: true,
+			password: "wrong",
+			status:   corev1.ConditionFalse,
+			wantErr:  NewRuntimeError(""),
+		},
+		{
+			name:     "RecipientRejected",
+			tlsMode:  EmailTLSStartTLS,
+			startTLS: true,
+			reject:   "team@example.com",
+			status:   corev1.ConditionFalse,
+			wantErr:  NewRuntimeError(""),
+		},
+		{
+			name:        "Running",
+			wantSkipped: true,
+			tlsMode:     EmailTLSStartTLS,
+			startTLS:    true,
+			status:      corev1.ConditionUnknown,
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			s, pool := newFakeSMTP(t, c.startTLS, c.implicitTLS)
+			s.reject = c.reject
+			k := fakeclient.NewClientBuilder().Build()
+			p := &v1alpha1.Provider{
+				ObjectMeta: metav1.ObjectMeta{Name: "provider", Namespace: "default"},
+				Spec: v1alpha1.ProviderSpec{
+					Type: "Email",
+					Email: &v1alpha1.EmailSpec{
+						Host:            "127.0.0.1",
+						Port:            pointer.Int32(int32(s.port())),
+						TLS:             pointer.String(c.tlsMode),
+						From:            "Tekton <tekton@example.com>",
+						To:              []string{"team@example.com"},
+						AllowedDomains:  []string{"example.com"},
+						SubjectTemplate: "[{{ .Reason }}] {{ .Name }}\nBcc: injected@example.com",
+					},
+				},
+			}
+			a, perr := NewEmail(ctx, p, k)
+			if !assert.Nil(t, perr) {
+				return
+			}
+			password := c.password
+			if len(password) < 1 {
+				password = "password"
+			}
+			a.Username = "user"
+			a.Password = NewSecretString(password)
+			a.TLSConfig = &tls.Config{RootCAs: pool}
+			a.now = func() time.Time { return time.Date(2021, 7, 1, 0, 1, 0, 0, time.UTC) }
+
+			pr := newTemplateSamplePipelineRun()
+			pr.Status.Conditions[0].Status = c.status
+			pr.Annotations[annotationEmailTo] = "alice@example.com"
+			err := a.Notify(ctx, pr)
+			if c.wantErr != nil {
+				if assert.NotNil(t, err) {
+					assert.Equal(t, c.wantErr.Code, err.Code)
+				}
+				return
+			}
+			if c.wantSkipped {
+				assert.True(t, err.IsSkipped())
+			} else {
+				assert.Nil(t, err)
+			}
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if c.status == corev1.ConditionUnknown {
+				assert.Empty(t, s.messages)
+				return
+			}
+			assert.True(t, s.authed)
+			assert.Equal(t, c.wantSecured, s.secured)
+			assert.Equal(t, "FROM:<tekton@example.com>", s.from)
+			assert.Equal(t, []string{"TO:<team@example.com>", "TO:<alice@example.com>"}, s.rcpts)
+			if !assert.Len(t, s.messages, 1) {
+				return
+			}
+			m, merr := mail.ReadMessage(bytes.NewReader(s.messages[0]))
+			if !assert.Nil(t, merr) {
+				return
+			}
+			assert.Equal(t, `"Tekton" <tekton@example.com>`, m.Header.Get("From"))
+			assert.Equal(t, "team@example.com, alice@example.com", m.Header.Get("To"))
+			assert.Equal(t, "[Failed] sample-run Bcc: injected@example.com", m.Header.Get("Subject"))
+			assert.Empty(t, m.Header.Get("Bcc"))
+			assert.Equal(t, "Thu, 01 Jul 2021 00:01:00 +0000", m.Header.Get("Date"))
+			mediaType, params, merr := mime.ParseMediaType(m.Header.Get("Content-Type"))
+			assert.Nil(t, merr)
+			assert.Equal(t, "multipart/alternative", mediaType)
+			r := multipart.NewReader(m.Body, params["boundary"])
+			var parts []string
+			var bodies []string
+			for {
+				part, perr := r.NextPart()
+				if perr != nil {
+					break
+				}
+				b, _ := ioutil.ReadAll(part)
+				parts = append(parts, part.Header.Get("Content-Type"))
+				bodies = append(bodies, string(b))
+			}
+			assert.Equal(t, []string{"text/plain; charset=utf-8", "text/html; charset=utf-8"}, parts)
+			if assert.Len(t, bodies, 2) {
+				assert.Equal(t, strings.Join([]string{
+					"sample-run.default",
+					"Failed: Tasks Completed: 1 (Failed: 1, Cancelled 0), Skipped: 0",
+					"",
+					"Namespace: default",
+					"Pipeline: sample",
+					"Duration: 1m0s",
+					"Reason: Failed",
+					"Failed: build (sample-run-build) Failed: Tasks Completed: 1 (Failed: 1, Cancelled 0), Skipped: 0",
+					"",
+					"https://dashboard.example.com/#/namespaces/default/pipelineruns/sample-run",
+					"",
+				}, "\n"), bodies[0])
+				assert.Contains(t, bodies[1], `<a href="https://dashboard.example.com/#/namespaces/default/pipelineruns/sample-run">sample-run.default</a>`)
+				assert.Contains(t, bodies[1], "border-left: 6px solid #A30100")
+			}
+		})
+	}
+}
+
+func TestEmailHTMLEscape(t *testing.T) {
+	h, perr := parseEmailHTMLTemplate("")
+	assert.Nil(t, perr)
+	pr := newTemplateSamplePipelineRun()
+	pr.Status.Conditions[0].Message = `<script>alert(1)</script>`
+	var b bytes.Buffer
+	assert.Nil(t, h.Execute(&b, newTemplateData(pr)))
+	assert.NotContains(t, b.String(), "<script>")
+	assert.Contains(t, b.String(), "&lt;script&gt;")
+}
@@ -25,6 +25,11 @@ const (
 	ErrorCodeNotFoundPrivateKey  = ErrorCode("NotFoundPrivateKey")
 	ErrorCodeFailedValidation    = ErrorCode("FailedValidation")
 	ErrorCodeRuntimeError        = ErrorCode("RuntimeError")
+	ErrorCodeChannelNotFound     = ErrorCode("ChannelNotFound")
+	ErrorCodeNotInChannel        = ErrorCode("NotInChannel")
+	ErrorCodeRateLimited         = ErrorCode("RateLimited")
+	// ErrorCodeSkipped is not a failure, but reports that the provider has nothing to send for the run.
+	ErrorCodeSkipped = ErrorCode("Skipped")
 )
 
 type ProviderError struct {
@@ -36,6 +41,11 @@ func (e *ProviderError) Error() string {
 	return fmt.Sprintf("%s: %s", e.Code, e.Message)
 }
 
+// IsSkipped reports whether the provider skipped the run without sending anything.
+func (e *ProviderError) IsSkipped() bool {
+	return e != nil && e.Code == ErrorCodeSkipped
+}
+
 func NewInvalidProviderSpecError(msg string) *ProviderError {
 	return &ProviderError{
 		Code:    ErrorCodeInvalidProviderSpec,
@@ -63,3 +73,31 @@ func NewRuntimeError(msg string) *ProviderError {
 		Message: msg,
 	}
 }
+
+func NewChannelNotFoundError(msg string) *ProviderError {
+	return &ProviderError{
+		Code:    ErrorCodeChannelNotFound,
+		Message: msg,
+	}
+}
+
+func NewNotInChannelError(msg string) *ProviderError {
+	return &ProviderError{
+		Code:    ErrorCodeNotInChannel,
+		Message: msg,
+	}
+}
+
+func NewRateLimitedError(msg string) *ProviderError {
+	return &ProviderError{
+		Code:    ErrorCodeRateLimited,
+		Message: msg,
+	}
+}
+
+func NewSkippedError(msg string) *ProviderError {
+	return &ProviderError{
+		Code:    ErrorCodeSkipped,
+		Message: msg,
+	}
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"knative.dev/pkg/apis"
+
+	pipelinesv1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+)
+
+// The transitions of the runs notified by the event providers.
+const (
+	runTransitionRunning   = "running"
+	runTransitionSucceeded = "succeeded"
+	runTransitionFailed    = "failed"
+	runTransitionCancelled = "cancelled"
+)
+
+const cloudEventTypePrefix = "io.ornew.tekton.integrations.pipelinerun."
+
+// The encodings of the events.
+const (
+	runEventEncodingJSON        = "JSON"
+	runEventEncodingCloudEvents = "CloudEvents"
+)
+
+// getRunTransition returns the transition of the run by its Succeeded condition.
+// The run without the condition is running, like the templates.
+func getRunTransition(c *apis.Condition) string {
+	switch {
+	case c == nil || c.Status == corev1.ConditionUnknown:
+		return runTransitionRunning
+	case c.Status == corev1.ConditionTrue:
+		return runTransitionSucceeded
+	case isCancelled(c):
+		return runTransitionCancelled
+	}
+	return runTransitionFailed
+}
+
+// getRunEventID returns the ID of the event of the transition, which is unique per run and transition,
+// so that the consumers can deduplicate the events sent again.
+func getRunEventID(pr *pipelinesv1beta1.PipelineRun, transition string) string {
+	return fmt.Sprintf("%s-%s", pr.UID, transition)
+}
+
+// runEvent is the built-in JSON of the run.
+type runEvent struct {
+	Name           string            `json:"name"`
+	Namespace      string            `json:"namespace"`
+	UID            string            `json:"uid"`
+	Pipeline       string            `json:"pipeline,omitempty"`
+	Transition     string            `json:"transition"`
+	Status         string            `json:"status"`
+	Reason         string            `json:"reason"`
+	Message        string            `json:"message"`
+	StartTime      *time.Time        `json:"startTime,omitempty"`
+	CompletionTime *time.Time        `json:"completionTime,omitempty"`
+	Duration       string            `json:"duration"`
+	DashboardURL   string            `json:"dashboardURL,omitempty"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	Params         map[string]string `json:"params,omitempty"`
+	Results        map[string]string `json:"results,omitempty"`
+	TaskRuns       []runEventTaskRun `json:"taskRuns,omitempty"`
+}
+
+type runEventTaskRun struct {
+	Name         string `json:"name"`
+	PipelineTask string `json:"pipelineTask"`
+	Status       string `json:"status"`
+	Reason       string `json:"reason"`
+}
+
+func newRunEvent(pr *pipelinesv1beta1.PipelineRun) *runEvent {
+	d := newTemplateData(pr)
+	e := &runEvent{
+		Name:           d.Name,
+		Namespace:      d.Namespace,
+		UID:            string(pr.UID),
+		Pipeline:       d.Pipeline,
+		Transition:     d.Transition,
+		Status:         d.Status,
+		Reason:         d.Reason,
+		Message:        d.Message,
+		StartTime:      d.StartTime,
+		CompletionTime: d.CompletionTime,
+		Duration:       d.Duration.String(),
+		DashboardURL:   d.DashboardURL,
+		Labels:         d.Labels,
+		Params:         d.Params,
+		Results:        d.Results,
+	}
+	for _, t := range d.TaskRuns {
+		e.TaskRuns = append(e.TaskRuns, runEventTaskRun{
+			Name:         t.Name,
+			PipelineTask: t.PipelineTaskName,
+			Status:       t.Status,
+			Reason:       t.Reason,
+		})
+	}
+	return e
+}
+
+// cloudEvent is the structured mode of CloudEvents v1.0 in JSON.
+// See https://github.com/cloudevents/spec/blob/v1.0.1/json-format.md
+type cloudEvent struct {
+	SpecVersion     string      `json:"specversion"`
+	ID              string      `json:"id"`
+	Source          string      `json:"source"`
+	Type            string      `json:"type"`
+	Subject         string      `json:"subject,omitempty"`
+	Time            *time.Time  `json:"time,omitempty"`
+	DataContentType string      `json:"datacontenttype"`
+	Data            interface{} `json:"data"`
+}
+
+// newRunCloudEvent returns the CloudEvent of the transition of the run, which has the built-in JSON of the run as the data.
+func newRunCloudEvent(pr *pipelinesv1beta1.PipelineRun) *cloudEvent {
+	e := newRunEvent(pr)
+	ce := &cloudEvent{
+		SpecVersion:     "1.0",
+		ID:              getRunEventID(pr, e.Transition),
+		Source:          fmt.Sprintf("/apis/tekton.dev/v1beta1/namespaces/%s/pipelineruns/%s", pr.Namespace, pr.Name),
+		Type:            cloudEventTypePrefix + e.Transition,
+		Subject:         e.Pipeline,
+		DataContentType: "application/json",
+		Data:            e,
+	}
+	if c := pr.Status.GetCondition(apis.ConditionSucceeded); c != nil && !c.LastTransitionTime.Inner.IsZero() {
+		t := c.LastTransitionTime.Inner.UTC()
+		ce.Time = &t
+	}
+	return ce
+}
+
+// isRunEventEncoding reports whether the encoding of the events is supported.
+func isRunEventEncoding(encoding string) bool {
+	return encoding == runEventEncodingJSON || encoding == runEventEncodingCloudEvents
+}
+
+// encodeRunEvent encodes the event of the transition of the run, and returns its content type.
+func encodeRunEvent(pr *pipelinesv1beta1.PipelineRun, encoding string) ([]byte, string, *ProviderError) {
+	var event interface{} = newRunEvent(pr)
+	contentType := "application/json"
+	if encoding == runEventEncodingCloudEvents {
+		event = newRunCloudEvent(pr)
+		contentType = "application/cloudevents+json"
+	}
+	b, err := json.Marshal(event)
+	if err != nil {
+		return nil, "", NewRuntimeError(fmt.Sprintf("failed to marshal the event: %v", err))
+	}
+	return b, contentType, nil
+}
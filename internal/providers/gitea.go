@@ -0,0 +1,116 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"knative.dev/pkg/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/go-logr/logr"
+	pipelinesv1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+
+	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
+)
+
+type Gitea struct {
+	BaseURL     string
+	AccessToken SecretBytes
+}
+
+var _ Provider = (*Gitea)(nil)
+
+func NewGitea(ctx context.Context, p *v1alpha1.Provider, k client.Client) (*Gitea, *ProviderError) {
+	s := p.Spec.Gitea
+	if s == nil {
+		return nil, NewInvalidProviderSpecError("missing value .gitea")
+	}
+	if len(s.BaseURL) < 1 {
+		return nil, NewInvalidProviderSpecError("missing value .gitea.baseURL")
+	}
+	if s.AccessToken.SecretRef == nil {
+		return nil, NewInvalidProviderSpecError("missing valid values in .accessToken")
+	}
+	token, perr := getSecretValue(ctx, k, p.Namespace, s.AccessToken.SecretRef, "access-token")
+	if perr != nil {
+		return nil, perr
+	}
+	return &Gitea{
+		BaseURL:     strings.TrimSuffix(s.BaseURL, "/"),
+		AccessToken: NewSecretBytes(token),
+	}, nil
+}
+
+type giteaCommitStatusRequest struct {
+	State       string `json:"state"`
+	TargetURL   string `json:"target_url,omitempty"`
+	Description string `json:"description,omitempty"`
+	Context     string `json:"context"`
+}
+
+func (a *Gitea) Notify(ctx context.Context, pr *pipelinesv1beta1.PipelineRun) *ProviderError {
+	log := logr.FromContext(ctx).WithName("providers.gitea").
+		WithValues("providerType", "Gitea", "pipelineRun", pr.Name)
+	context, perr := getStatusContext(pr)
+	if perr != nil {
+		return perr
+	}
+	ref, perr := getRepositoryRef(pr, "gitea")
+	if perr != nil {
+		return perr
+	}
+	cond := pr.Status.GetCondition(apis.ConditionSucceeded)
+	if cond == nil {
+		log.V(1).Info("PipelineRun has not condition, ignored")
+		return NewSkippedError("PipelineRun has not condition")
+	}
+	status := &giteaCommitStatusRequest{
+		State:       toGiteaCommitStatus(cond.Status),
+		TargetURL:   getDashboardTargetURL(pr),
+		Description: cond.Reason,
+		Context:     context,
+	}
+	u := fmt.Sprintf("%s/api/v1/repos/%s/%s/statuses/%s", a.BaseURL,
+		url.PathEscape(ref.Owner), url.PathEscape(ref.Repo), url.PathEscape(ref.Revision))
+	auth := fmt.Sprintf("token %s", a.AccessToken.GetNoRedactedString())
+	if _, err := postHTTP(ctx, u, auth, status); err != nil {
+		return newHTTPError("Gitea", err)
+	}
+	log.V(2).Info("set commit status", "status", status)
+	return nil
+}
+
+const (
+	GiteaCommitStatusPending = "pending"
+	GiteaCommitStatusSuccess = "success"
+	GiteaCommitStatusFailure = "failure"
+)
+
+func toGiteaCommitStatus(status corev1.ConditionStatus) string {
+	switch status {
+	case corev1.ConditionUnknown:
+		return GiteaCommitStatusPending
+	case corev1.ConditionTrue:
+		return GiteaCommitStatusSuccess
+	}
+	return GiteaCommitStatusFailure
+}
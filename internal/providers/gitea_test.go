@@ -0,0 +1,225 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	knativeapis "knative.dev/pkg/apis"
+	knativeapisduckv1beta1 "knative.dev/pkg/apis/duck/v1beta1"
+
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	pipelinesv1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+
+	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
+)
+
+func TestNewGitea(t *testing.T) {
+	k := fakeclient.NewClientBuilder().
+		WithObjects(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "secret",
+				Namespace: "default",
+			},
+			Data: map[string][]byte{
+				"access-token": []byte("token"),
+			},
+		}).
+		Build()
+	for _, c := range []struct {
+		name    string
+		spec    *v1alpha1.GiteaSpec
+		wantErr *ProviderError
+	}{
+		{
+			name: "Basic",
+			spec: &v1alpha1.GiteaSpec{
+				BaseURL: "https://gitea.example.com/",
+				AccessToken: v1alpha1.AccessTokenSource{
+					SecretRef: &v1alpha1.LocalSecretKeyReference{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "secret"},
+					},
+				},
+			},
+		},
+		{
+			name: "BaseURLNotFound",
+			spec: &v1alpha1.GiteaSpec{
+				AccessToken: v1alpha1.AccessTokenSource{
+					SecretRef: &v1alpha1.LocalSecretKeyReference{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "secret"},
+					},
+				},
+			},
+			wantErr: NewInvalidProviderSpecError(""),
+		},
+		{
+			name: "SecretNotFound",
+			spec: &v1alpha1.GiteaSpec{
+				BaseURL: "https://gitea.example.com",
+				AccessToken: v1alpha1.AccessTokenSource{
+					SecretRef: &v1alpha1.LocalSecretKeyReference{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "not-exists-secret"},
+					},
+				},
+			},
+			wantErr: NewNotFoundPrivateKeyError(""),
+		},
+		{
+			name:    "GiteaSpecNotFound",
+			wantErr: NewInvalidProviderSpecError(""),
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			p := &v1alpha1.Provider{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "provider",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.ProviderSpec{
+					Type:  "Gitea",
+					Gitea: c.spec,
+				},
+			}
+			a, err := NewGitea(ctx, p, k)
+			if c.wantErr != nil {
+				if assert.NotNil(t, err) {
+					assert.Equal(t, c.wantErr.Code, err.Code)
+				}
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, "https://gitea.example.com", a.BaseURL)
+			assert.Equal(t, "token", a.AccessToken.GetNoRedactedString())
+		})
+	}
+}
+
+func TestGiteaNotify(t *testing.T) {
+	for _, c := range []struct {
+		name      string
+		status    corev1.ConditionStatus
+		wantState string
+	}{
+		{name: "Running", status: corev1.ConditionUnknown, wantState: "pending"},
+		{name: "Succeeded", status: corev1.ConditionTrue, wantState: "success"},
+		{name: "Failed", status: corev1.ConditionFalse, wantState: "failure"},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			var (
+				path string
+				auth string
+				body giteaCommitStatusRequest
+			)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path = r.URL.Path
+				auth = r.Header.Get("Authorization")
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("failed to decode request: %v", err)
+				}
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte(`{}`))
+			}))
+			defer srv.Close()
+			pr := &pipelinesv1beta1.PipelineRun{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo",
+					Namespace: "bar",
+					Annotations: map[string]string{
+						"integrations.tekton.ornew.io/context-id":                "test",
+						"integrations.tekton.ornew.io/gitea-owner":               "ornew",
+						"integrations.tekton.ornew.io/gitea-repo":                "tekton-integration",
+						"integrations.tekton.ornew.io/gitea-sha":                 "abc",
+						"integrations.tekton.ornew.io/tekton-dashboard-base-url": "http://dashboard.example.com/",
+					},
+				},
+				Status: pipelinesv1beta1.PipelineRunStatus{
+					Status: knativeapisduckv1beta1.Status{
+						Conditions: []knativeapis.Condition{
+							{
+								Type:   knativeapis.ConditionSucceeded,
+								Status: c.status,
+								Reason: "Reason",
+							},
+						},
+					},
+				},
+			}
+			a := &Gitea{
+				BaseURL:     srv.URL,
+				AccessToken: NewSecretBytes([]byte("token")),
+			}
+			err := a.Notify(ctx, pr)
+			assert.Nil(t, err)
+			assert.Equal(t, "/api/v1/repos/ornew/tekton-integration/statuses/abc", path)
+			assert.Equal(t, "token token", auth)
+			assert.Equal(t, giteaCommitStatusRequest{
+				State:       c.wantState,
+				TargetURL:   "http://dashboard.example.com/#/namespaces/bar/pipelineruns/foo",
+				Description: "Reason",
+				Context:     "tekton: test",
+			}, body)
+		})
+	}
+}
+
+func TestGiteaNotifyError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"message":"token does not have required scope"}`))
+	}))
+	defer srv.Close()
+	pr := &pipelinesv1beta1.PipelineRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "bar",
+			Annotations: map[string]string{
+				"integrations.tekton.ornew.io/context-id": "test",
+				"integrations.tekton.ornew.io/owner":      "ornew",
+				"integrations.tekton.ornew.io/repo":       "tekton-integration",
+				"integrations.tekton.ornew.io/sha":        "abc",
+			},
+		},
+		Status: pipelinesv1beta1.PipelineRunStatus{
+			Status: knativeapisduckv1beta1.Status{
+				Conditions: []knativeapis.Condition{
+					{
+						Type:   knativeapis.ConditionSucceeded,
+						Status: corev1.ConditionTrue,
+					},
+				},
+			},
+		},
+	}
+	a := &Gitea{
+		BaseURL:     srv.URL,
+		AccessToken: NewSecretBytes([]byte("token")),
+	}
+	err := a.Notify(ctx, pr)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, ErrorCodeRuntimeError, err.Code)
+	}
+}
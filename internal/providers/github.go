@@ -20,9 +20,9 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strings"
 
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/types"
 	"knative.dev/pkg/apis"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -34,87 +34,67 @@ import (
 	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
 )
 
-const (
-	annotationGitHubOwner = "integrations.tekton.ornew.io/github-owner"
-	annotationGitHubRepo  = "integrations.tekton.ornew.io/github-repo"
-	annotationGitHubSHA   = "integrations.tekton.ornew.io/github-sha"
-)
-
 type GitHubApp struct {
-	AppId      int64
-	PrivateKey SecretBytes
-	BaseURL    *string
+	AppId          int64
+	PrivateKey     SecretBytes
+	BaseURL        *string
+	InstallationID *int64
+	// Installations maps lower-cased owner logins to installation IDs.
+	Installations map[string]int64
 }
 
 var _ Provider = (*GitHubApp)(nil)
+var _ Validator = (*GitHubApp)(nil)
 
 func NewGitHubApp(ctx context.Context, p *v1alpha1.Provider, k client.Client) (*GitHubApp, *ProviderError) {
 	s := p.Spec.GitHubApp
 	if s == nil {
 		return nil, NewInvalidProviderSpecError("missing value .githubApp")
 	}
-	var key []byte
-	if s.PrivateKey.SecretRef != nil {
-		var secret corev1.Secret
-		ref := types.NamespacedName{
-			Namespace: p.Namespace,
-			Name:      s.PrivateKey.SecretRef.Name,
-		}
-		if err := k.Get(ctx, ref, &secret); err != nil {
-			return nil, NewNotFoundPrivateKeyError(fmt.Sprintf("failed to get secret: %v", err))
-		}
-		if secret.Data == nil {
-			return nil, NewNotFoundPrivateKeyError("data not found in secret")
-		}
-		if pem, ok := secret.Data["private-key.pem"]; ok {
-			key = pem
-		} else {
-			return nil, NewNotFoundPrivateKeyError("missing key private-key.pem")
-		}
-	} else {
+	if s.PrivateKey.SecretRef == nil {
 		return nil, NewInvalidProviderSpecError("missing valid values in .privateKey")
 	}
+	key, perr := getSecretValue(ctx, k, p.Namespace, s.PrivateKey.SecretRef, "private-key.pem")
+	if perr != nil {
+		return nil, perr
+	}
+	installations := make(map[string]int64, len(s.Installations))
+	for _, ins := range s.Installations {
+		owner := strings.ToLower(ins.Owner)
+		if _, ok := installations[owner]; ok {
+			return nil, NewInvalidProviderSpecError(fmt.Sprintf("duplicated owner in .installations: %s", ins.Owner))
+		}
+		installations[owner] = ins.InstallationID
+	}
 	return &GitHubApp{
-		AppId:      s.AppId,
-		PrivateKey: NewSecretBytes(key),
-		BaseURL:    s.BaseURL,
+		AppId:          s.AppId,
+		PrivateKey:     NewSecretBytes(key),
+		BaseURL:        s.BaseURL,
+		InstallationID: s.InstallationID,
+		Installations:  installations,
 	}, nil
 }
 
 func (a *GitHubApp) Notify(ctx context.Context, pr *pipelinesv1beta1.PipelineRun) *ProviderError {
 	log := logr.FromContext(ctx).WithName("providers.githubapp").
 		WithValues("providerType", "GitHubApp", "pipelineRun", pr.Name)
-	contextID := pr.Annotations[annotationContextID]
-	if len(contextID) < 1 {
-		ref := pr.Spec.PipelineRef
-		if ref != nil && len(ref.Name) > 0 {
-			contextID = ref.Name
-		}
-		return NewFailedValidationError("context-id or pipelineRef.name is required")
-	}
-	owner := pr.Annotations[annotationGitHubOwner]
-	repo := pr.Annotations[annotationGitHubRepo]
-	revision := pr.Annotations[annotationGitHubSHA]
-	if len(owner) < 1 || len(repo) < 1 || len(revision) < 1 {
-		return NewFailedValidationError(fmt.Sprintf("required annotations: %s=%s %s=%s %s=%s",
-			annotationGitHubOwner, owner,
-			annotationGitHubRepo, repo,
-			annotationGitHubSHA, revision,
-		))
+	context, perr := getStatusContext(pr)
+	if perr != nil {
+		return perr
 	}
+	repoRef, perr := getRepositoryRef(pr, "github")
+	if perr != nil {
+		return perr
+	}
+	owner, repo, revision := repoRef.Owner, repoRef.Repo, repoRef.Revision
 	cond := pr.Status.GetCondition(apis.ConditionSucceeded)
 	if cond == nil {
 		log.V(1).Info("PipelineRun has not condition, ignored")
-		return nil
+		return NewSkippedError("PipelineRun has not condition")
 	}
 	state := toGithubCommitStatus(cond.Status)
 	description := cond.Reason
-	context := fmt.Sprintf("tekton: %s", contextID)
-	targetURL := ""
-	dashboardBaseURL := pr.Annotations[annotationTektonDashboardBaseURL]
-	if len(dashboardBaseURL) > 0 {
-		targetURL = getDashboardPipelineRunURL(dashboardBaseURL, pr.Namespace, pr.Name)
-	}
+	targetURL := getDashboardTargetURL(pr)
 	status := &github.RepoStatus{
 		State:       &state, // pending, success, error, or failure
 		TargetURL:   &targetURL,
@@ -122,36 +102,96 @@ func (a *GitHubApp) Notify(ctx context.Context, pr *pipelinesv1beta1.PipelineRun
 		Context:     &context,
 	}
 
-	tr := http.DefaultTransport
-	atr, err := ghinstallation.NewAppsTransport(tr, a.AppId, a.PrivateKey.GetNoRedacted())
+	atr, perr := a.newAppsTransport()
+	if perr != nil {
+		return perr
+	}
+	id, perr := a.resolveInstallationID(ctx, atr, owner, repo)
+	if perr != nil {
+		return perr
+	}
+	itr := ghinstallation.NewFromAppsTransport(atr, id)
+	client, err := a.newClient(itr)
 	if err != nil {
-		return NewRuntimeError(fmt.Sprintf("failed to get GitHub App transport: %v", err))
+		return NewRuntimeError(fmt.Sprintf("failed to get GitHub API client: %v", err))
+	}
+	status, _, err = client.Repositories.CreateStatus(ctx, owner, repo, revision, status)
+	if err != nil {
+		return NewRuntimeError(fmt.Sprintf("failed to set GitHub commit status: %v", err))
+	}
+	log.V(2).Info("set commit status", "status", status)
+	return nil
+}
+
+// Validate checks that the configured installations exist and are allowed to
+// write commit statuses.
+func (a *GitHubApp) Validate(ctx context.Context) *ProviderError {
+	ids := make([]int64, 0, len(a.Installations)+1)
+	if a.InstallationID != nil {
+		ids = append(ids, *a.InstallationID)
+	}
+	for _, id := range a.Installations {
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	atr, perr := a.newAppsTransport()
+	if perr != nil {
+		return perr
+	}
+	client, err := a.newClient(atr)
+	if err != nil {
+		return NewRuntimeError(fmt.Sprintf("failed to get GitHub API client: %v", err))
+	}
+	for _, id := range ids {
+		ins, _, err := client.Apps.GetInstallation(ctx, id)
+		if err != nil {
+			return NewFailedValidationError(fmt.Sprintf("failed to get GitHub App installation %d: %v", id, err))
+		}
+		if ins.Permissions == nil || ins.Permissions.GetStatuses() != "write" {
+			return NewFailedValidationError(fmt.Sprintf("GitHub App installation %d does not have statuses:write permission", id))
+		}
+	}
+	return nil
+}
+
+func (a *GitHubApp) newAppsTransport() (*ghinstallation.AppsTransport, *ProviderError) {
+	atr, err := ghinstallation.NewAppsTransport(httpTransport, a.AppId, a.PrivateKey.GetNoRedacted())
+	if err != nil {
+		return nil, NewRuntimeError(fmt.Sprintf("failed to get GitHub App transport: %v", err))
 	}
 	if a.BaseURL != nil {
 		atr.BaseURL = *a.BaseURL
 	}
-	bearerClient := github.NewClient(&http.Client{Transport: atr})
-	ins, _, err := bearerClient.Apps.FindRepositoryInstallation(ctx, owner, repo)
-	if err != nil || ins.ID == nil {
-		return NewRuntimeError(fmt.Sprintf("failed to find GitHub App installation: %v", err))
-	}
-	itr := ghinstallation.NewFromAppsTransport(atr, *ins.ID)
+	return atr, nil
+}
 
-	var client *github.Client
+func (a *GitHubApp) newClient(tr http.RoundTripper) (*github.Client, error) {
 	if a.BaseURL != nil {
-		client, err = github.NewEnterpriseClient(itr.BaseURL, itr.BaseURL, &http.Client{Transport: itr})
-		if err != nil {
-			return NewRuntimeError(fmt.Sprintf("failed to get GitHub Enterprise API client: %v", err))
-		}
-	} else {
-		client = github.NewClient(&http.Client{Transport: itr})
+		return github.NewEnterpriseClient(*a.BaseURL, *a.BaseURL, &http.Client{Transport: tr})
 	}
-	status, _, err = client.Repositories.CreateStatus(ctx, owner, repo, revision, status)
+	return github.NewClient(&http.Client{Transport: tr}), nil
+}
+
+// resolveInstallationID returns the installation for the repository, preferring
+// the installations configured in the spec over looking it up with the API.
+func (a *GitHubApp) resolveInstallationID(ctx context.Context, atr *ghinstallation.AppsTransport, owner, repo string) (int64, *ProviderError) {
+	if id, ok := a.Installations[strings.ToLower(owner)]; ok {
+		return id, nil
+	}
+	if a.InstallationID != nil {
+		return *a.InstallationID, nil
+	}
+	client, err := a.newClient(atr)
 	if err != nil {
-		return NewRuntimeError(fmt.Sprintf("failed to set GitHub commit status: %v", err))
+		return 0, NewRuntimeError(fmt.Sprintf("failed to get GitHub API client: %v", err))
 	}
-	log.V(2).Info("set commit status", "status", status)
-	return nil
+	ins, _, err := client.Apps.FindRepositoryInstallation(ctx, owner, repo)
+	if err != nil || ins.ID == nil {
+		return 0, NewRuntimeError(fmt.Sprintf("failed to find GitHub App installation: %v", err))
+	}
+	return *ins.ID, nil
 }
 
 const (
@@ -18,22 +18,37 @@ package providers
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/utils/pointer"
+	knativeapis "knative.dev/pkg/apis"
+	knativeapisduckv1beta1 "knative.dev/pkg/apis/duck/v1beta1"
 
 	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
 
+	"github.com/go-logr/logr"
+	"github.com/google/go-github/v37/github"
 	pipelinesv1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
 
 	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
 )
 
 var (
-	ctx context.Context = context.TODO()
+	ctx context.Context = logr.NewContext(context.TODO(), logr.Discard())
 )
 
 func TestNewGitHubApp(t *testing.T) {
@@ -223,7 +238,278 @@ func TestNewGitHubApp(t *testing.T) {
 	}
 }
 
+func TestNewGitHubAppInstallations(t *testing.T) {
+	k := fakeclient.NewClientBuilder().
+		WithObjects(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "secret",
+				Namespace: "default",
+			},
+			Data: map[string][]byte{
+				"private-key.pem": []byte("private-key"),
+			},
+		}).
+		Build()
+	newProvider := func(installations ...v1alpha1.GitHubAppInstallation) *v1alpha1.Provider {
+		return &v1alpha1.Provider{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "provider",
+				Namespace: "default",
+			},
+			Spec: v1alpha1.ProviderSpec{
+				Type: "GitHubApp",
+				GitHubApp: &v1alpha1.GitHubAppSpec{
+					AppId: 1,
+					PrivateKey: v1alpha1.PrivateKeySource{
+						SecretRef: &v1alpha1.LocalSecretKeyReference{
+							LocalObjectReference: corev1.LocalObjectReference{
+								Name: "secret",
+							},
+						},
+					},
+					InstallationID: pointer.Int64(10),
+					Installations:  installations,
+				},
+			},
+		}
+	}
+
+	a, err := NewGitHubApp(ctx, newProvider(
+		v1alpha1.GitHubAppInstallation{Owner: "Foo", InstallationID: 11},
+		v1alpha1.GitHubAppInstallation{Owner: "bar", InstallationID: 12},
+	), k)
+	if assert.Nil(t, err) {
+		assert.Equal(t, int64(10), *a.InstallationID)
+		assert.Equal(t, map[string]int64{"foo": 11, "bar": 12}, a.Installations)
+	}
+
+	_, err = NewGitHubApp(ctx, newProvider(
+		v1alpha1.GitHubAppInstallation{Owner: "foo", InstallationID: 11},
+		v1alpha1.GitHubAppInstallation{Owner: "FOO", InstallationID: 12},
+	), k)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, ErrorCodeInvalidProviderSpec, err.Code)
+	}
+}
+
+// fakeGitHub is a minimal GitHub Enterprise API server for the GitHub App.
+type fakeGitHub struct {
+	*httptest.Server
+	// repository installations keyed by "owner/repo"
+	repoInstallations map[string]int64
+	// statuses permission keyed by installation ID
+	permissions map[int64]string
+
+	lookups  int
+	tokens   []string
+	statuses []github.RepoStatus
+}
+
+func newFakeGitHub(t *testing.T) *fakeGitHub {
+	f := &fakeGitHub{
+		repoInstallations: map[string]int64{},
+		permissions:       map[int64]string{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/app/installations/", func(w http.ResponseWriter, r *http.Request) {
+		rest := strings.TrimPrefix(r.URL.Path, "/api/v3/app/installations/")
+		if strings.HasSuffix(rest, "/access_tokens") && r.Method == http.MethodPost {
+			id := strings.TrimSuffix(rest, "/access_tokens")
+			f.tokens = append(f.tokens, id)
+			fmt.Fprintf(w, `{"token":"token-%s","expires_at":%q}`, id, time.Now().Add(time.Hour).Format(time.RFC3339))
+			return
+		}
+		id, err := strconv.ParseInt(rest, 10, 64)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		perm, ok := f.permissions[id]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"id":%d,"permissions":{"statuses":%q}}`, id, perm)
+	})
+	mux.HandleFunc("/api/v3/repos/", func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v3/repos/"), "/")
+		if len(parts) == 3 && parts[2] == "installation" {
+			f.lookups++
+			id, ok := f.repoInstallations[parts[0]+"/"+parts[1]]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			fmt.Fprintf(w, `{"id":%d}`, id)
+			return
+		}
+		if len(parts) == 4 && parts[2] == "statuses" && r.Method == http.MethodPost {
+			var status github.RepoStatus
+			if err := json.NewDecoder(r.Body).Decode(&status); err != nil {
+				t.Errorf("failed to decode status: %v", err)
+			}
+			f.statuses = append(f.statuses, status)
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{}`)
+			return
+		}
+		http.NotFound(w, r)
+	})
+	f.Server = httptest.NewServer(mux)
+	t.Cleanup(f.Close)
+	return f
+}
+
+func newTestPrivateKey(t *testing.T) []byte {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate private key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	})
+}
+
 func TestGitHubAppNotify(t *testing.T) {
-	_ = &pipelinesv1beta1.PipelineRun{}
-	// TODO
+	key := newTestPrivateKey(t)
+	pr := &pipelinesv1beta1.PipelineRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "bar",
+			Annotations: map[string]string{
+				"integrations.tekton.ornew.io/context-id":   "test",
+				"integrations.tekton.ornew.io/github-owner": "ornew",
+				"integrations.tekton.ornew.io/github-repo":  "tekton-integration",
+				"integrations.tekton.ornew.io/github-sha":   "8ebf2b9c",
+			},
+		},
+		Status: pipelinesv1beta1.PipelineRunStatus{
+			Status: knativeapisduckv1beta1.Status{
+				Conditions: []knativeapis.Condition{
+					{
+						Type:   knativeapis.ConditionSucceeded,
+						Status: corev1.ConditionTrue,
+						Reason: "Succeeded",
+					},
+				},
+			},
+		},
+	}
+	for _, c := range []struct {
+		name           string
+		installationID *int64
+		installations  map[string]int64
+		repo           map[string]int64
+		wantToken      string
+		wantLookups    int
+		wantErr        *ProviderError
+	}{
+		{
+			name:        "FindRepositoryInstallation",
+			repo:        map[string]int64{"ornew/tekton-integration": 1},
+			wantToken:   "1",
+			wantLookups: 1,
+		},
+		{
+			name:           "InstallationID",
+			installationID: pointer.Int64(2),
+			wantToken:      "2",
+		},
+		{
+			name:           "InstallationsByOwner",
+			installationID: pointer.Int64(2),
+			installations:  map[string]int64{"ornew": 3},
+			wantToken:      "3",
+		},
+		{
+			name:           "InstallationsFallback",
+			installationID: pointer.Int64(2),
+			installations:  map[string]int64{"other": 3},
+			wantToken:      "2",
+		},
+		{
+			name:        "NotInstalled",
+			wantLookups: 1,
+			wantErr:     NewRuntimeError(""),
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			f := newFakeGitHub(t)
+			f.repoInstallations = c.repo
+			a := &GitHubApp{
+				AppId:          1,
+				PrivateKey:     NewSecretBytes(key),
+				BaseURL:        pointer.String(f.URL + "/api/v3"),
+				InstallationID: c.installationID,
+				Installations:  c.installations,
+			}
+			err := a.Notify(ctx, pr)
+			assert.Equal(t, c.wantLookups, f.lookups)
+			if c.wantErr != nil {
+				if assert.NotNil(t, err) {
+					assert.Equal(t, c.wantErr.Code, err.Code)
+				}
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, []string{c.wantToken}, f.tokens)
+			if assert.Len(t, f.statuses, 1) {
+				assert.Equal(t, "success", f.statuses[0].GetState())
+				assert.Equal(t, "tekton: test", f.statuses[0].GetContext())
+			}
+		})
+	}
+}
+
+func TestGitHubAppValidate(t *testing.T) {
+	key := newTestPrivateKey(t)
+	for _, c := range []struct {
+		name           string
+		installationID *int64
+		installations  map[string]int64
+		wantErr        *ProviderError
+	}{
+		{
+			name: "NoInstallations",
+		},
+		{
+			name:           "Writable",
+			installationID: pointer.Int64(1),
+			installations:  map[string]int64{"foo": 2},
+		},
+		{
+			name:           "ReadOnly",
+			installationID: pointer.Int64(1),
+			installations:  map[string]int64{"foo": 3},
+			wantErr:        NewFailedValidationError(""),
+		},
+		{
+			name:           "NotFound",
+			installationID: pointer.Int64(4),
+			wantErr:        NewFailedValidationError(""),
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			f := newFakeGitHub(t)
+			f.permissions = map[int64]string{1: "write", 2: "write", 3: "read"}
+			a := &GitHubApp{
+				AppId:          1,
+				PrivateKey:     NewSecretBytes(key),
+				BaseURL:        pointer.String(f.URL + "/api/v3"),
+				InstallationID: c.installationID,
+				Installations:  c.installations,
+			}
+			err := a.Validate(ctx)
+			if c.wantErr != nil {
+				if assert.NotNil(t, err) {
+					assert.Equal(t, c.wantErr.Code, err.Code)
+				}
+				return
+			}
+			assert.Nil(t, err)
+		})
+	}
 }
@@ -0,0 +1,174 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"knative.dev/pkg/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/go-logr/logr"
+	pipelinesv1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+
+	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
+)
+
+const (
+	annotationGitLabMergeRequestIID = "integrations.tekton.ornew.io/gitlab-merge-request-iid"
+
+	gitlabDefaultBaseURL = "https://gitlab.com"
+)
+
+type GitLab struct {
+	BaseURL             string
+	AccessToken         SecretBytes
+	MergeRequestComment bool
+}
+
+var _ Provider = (*GitLab)(nil)
+
+func NewGitLab(ctx context.Context, p *v1alpha1.Provider, k client.Client) (*GitLab, *ProviderError) {
+	s := p.Spec.GitLab
+	if s == nil {
+		return nil, NewInvalidProviderSpecError("missing value .gitlab")
+	}
+	if s.AccessToken.SecretRef == nil {
+		return nil, NewInvalidProviderSpecError("missing valid values in .accessToken")
+	}
+	token, perr := getSecretValue(ctx, k, p.Namespace, s.AccessToken.SecretRef, "access-token")
+	if perr != nil {
+		return nil, perr
+	}
+	baseURL := gitlabDefaultBaseURL
+	if s.BaseURL != nil {
+		baseURL = strings.TrimSuffix(*s.BaseURL, "/")
+	}
+	return &GitLab{
+		BaseURL:             baseURL,
+		AccessToken:         NewSecretBytes(token),
+		MergeRequestComment: s.MergeRequestComment,
+	}, nil
+}
+
+type gitlabCommitStatusRequest struct {
+	State       string `json:"state"`
+	Name        string `json:"name"`
+	TargetURL   string `json:"target_url,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+type gitlabNoteRequest struct {
+	Body string `json:"body"`
+}
+
+func (a *GitLab) Notify(ctx context.Context, pr *pipelinesv1beta1.PipelineRun) *ProviderError {
+	log := logr.FromContext(ctx).WithName("providers.gitlab").
+		WithValues("providerType", "GitLab", "pipelineRun", pr.Name)
+	name, perr := getStatusContext(pr)
+	if perr != nil {
+		return perr
+	}
+	ref, perr := getRepositoryRef(pr, "gitlab")
+	if perr != nil {
+		return perr
+	}
+	cond := pr.Status.GetCondition(apis.ConditionSucceeded)
+	if cond == nil {
+		log.V(1).Info("PipelineRun has not condition, ignored")
+		return NewSkippedError("PipelineRun has not condition")
+	}
+	project := url.PathEscape(fmt.Sprintf("%s/%s", ref.Owner, ref.Repo))
+	status := &gitlabCommitStatusRequest{
+		State:       toGitLabCommitStatus(cond),
+		Name:        name,
+		TargetURL:   getDashboardTargetURL(pr),
+		Description: cond.Reason, // max len 255
+	}
+	u := fmt.Sprintf("%s/api/v4/projects/%s/statuses/%s", a.BaseURL, project, url.PathEscape(ref.Revision))
+	if perr := a.post(ctx, u, status); perr != nil {
+		return perr
+	}
+	log.V(2).Info("set commit status", "status", status)
+
+	if !a.MergeRequestComment || cond.Status == corev1.ConditionUnknown {
+		return nil
+	}
+	iid := pr.Annotations[annotationGitLabMergeRequestIID]
+	if len(iid) < 1 {
+		log.V(2).Info("merge request is not annotated, skipped comment")
+		return nil
+	}
+	note := &gitlabNoteRequest{
+		Body: newGitLabNoteBody(pr, name, cond),
+	}
+	u = fmt.Sprintf("%s/api/v4/projects/%s/merge_requests/%s/notes", a.BaseURL, project, url.PathEscape(iid))
+	if perr := a.post(ctx, u, note); perr != nil {
+		return perr
+	}
+	log.V(2).Info("comment on merge request", "mergeRequest", iid)
+	return nil
+}
+
+func (a *GitLab) post(ctx context.Context, url string, payload interface{}) *ProviderError {
+	bearer := fmt.Sprintf("Bearer %s", a.AccessToken.GetNoRedactedString())
+	if _, err := postHTTP(ctx, url, bearer, payload); err != nil {
+		return newHTTPError("GitLab", err)
+	}
+	return nil
+}
+
+func newGitLabNoteBody(pr *pipelinesv1beta1.PipelineRun, name string, cond *apis.Condition) string {
+	b := strings.Builder{}
+	fmt.Fprintf(&b, "**%s** %s: `%s.%s`", name, cond.Reason, pr.Name, pr.Namespace)
+	if len(cond.Message) > 0 {
+		fmt.Fprintf(&b, "\n\n%s", cond.Message)
+	}
+	if targetURL := getDashboardTargetURL(pr); len(targetURL) > 0 {
+		fmt.Fprintf(&b, "\n\n[open dashboard](%s)", targetURL)
+	}
+	return b.String()
+}
+
+const (
+	GitLabCommitStatusPending  = "pending"
+	GitLabCommitStatusRunning  = "running"
+	GitLabCommitStatusSuccess  = "success"
+	GitLabCommitStatusFailed   = "failed"
+	GitLabCommitStatusCanceled = "canceled"
+)
+
+func toGitLabCommitStatus(c *apis.Condition) string {
+	switch c.Status {
+	case corev1.ConditionUnknown:
+		if c.Reason == pipelinesv1beta1.PipelineRunReasonRunning.String() {
+			return GitLabCommitStatusRunning
+		}
+		return GitLabCommitStatusPending
+	case corev1.ConditionTrue:
+		return GitLabCommitStatusSuccess
+	case corev1.ConditionFalse:
+		if isCancelled(c) {
+			return GitLabCommitStatusCanceled
+		}
+	}
+	return GitLabCommitStatusFailed
+}
@@ -0,0 +1,285 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	knativeapis "knative.dev/pkg/apis"
+	knativeapisduckv1beta1 "knative.dev/pkg/apis/duck/v1beta1"
+
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	pipelinesv1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+
+	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
+)
+
+func TestNewGitLab(t *testing.T) {
+	k := fakeclient.NewClientBuilder().
+		WithObjects(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "secret",
+				Namespace: "default",
+			},
+			Data: map[string][]byte{
+				"access-token": []byte("glpat-xxxx"),
+				"custom":       []byte("glpat-yyyy"),
+			},
+		}).
+		Build()
+	for _, c := range []struct {
+		name        string
+		spec        *v1alpha1.GitLabSpec
+		wantBaseURL string
+		wantToken   string
+		wantErr     *ProviderError
+	}{
+		{
+			name: "Basic",
+			spec: &v1alpha1.GitLabSpec{
+				AccessToken: v1alpha1.AccessTokenSource{
+					SecretRef: &v1alpha1.LocalSecretKeyReference{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "secret"},
+					},
+				},
+			},
+			wantBaseURL: "https://gitlab.com",
+			wantToken:   "glpat-xxxx",
+		},
+		{
+			name: "SelfManaged",
+			spec: &v1alpha1.GitLabSpec{
+				BaseURL: pointer.String("https://gitlab.example.com/"),
+				AccessToken: v1alpha1.AccessTokenSource{
+					SecretRef: &v1alpha1.LocalSecretKeyReference{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "secret"},
+						Key:                  pointer.String("custom"),
+					},
+				},
+			},
+			wantBaseURL: "https://gitlab.example.com",
+			wantToken:   "glpat-yyyy",
+		},
+		{
+			name:    "GitLabSpecNotFound",
+			wantErr: NewInvalidProviderSpecError(""),
+		},
+		{
+			name: "SecretNotFound",
+			spec: &v1alpha1.GitLabSpec{
+				AccessToken: v1alpha1.AccessTokenSource{
+					SecretRef: &v1alpha1.LocalSecretKeyReference{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "not-exists-secret"},
+					},
+				},
+			},
+			wantErr: NewNotFoundPrivateKeyError(""),
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			p := &v1alpha1.Provider{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "provider",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.ProviderSpec{
+					Type:   "GitLab",
+					GitLab: c.spec,
+				},
+			}
+			a, err := NewGitLab(ctx, p, k)
+			if c.wantErr != nil {
+				if assert.NotNil(t, err) {
+					assert.Equal(t, c.wantErr.Code, err.Code)
+				}
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, c.wantBaseURL, a.BaseURL)
+			assert.Equal(t, c.wantToken, a.AccessToken.GetNoRedactedString())
+		})
+	}
+}
+
+type gitlabRequest struct {
+	Path string
+	Auth string
+	Body map[string]interface{}
+}
+
+func newFakeGitLab(t *testing.T, requests *[]gitlabRequest) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		*requests = append(*requests, gitlabRequest{
+			Path: r.URL.EscapedPath(),
+			Auth: r.Header.Get("Authorization"),
+			Body: body,
+		})
+		if r.URL.EscapedPath() == "/api/v4/projects/group%2Fmissing/statuses/abc" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"404 Project Not Found"}`))
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newGitLabTestPipelineRun(status corev1.ConditionStatus, reason string, annotations map[string]string) *pipelinesv1beta1.PipelineRun {
+	a := map[string]string{
+		"integrations.tekton.ornew.io/gitlab-owner": "group/subgroup",
+		"integrations.tekton.ornew.io/gitlab-repo":  "project",
+		"integrations.tekton.ornew.io/gitlab-sha":   "abc",
+	}
+	for k, v := range annotations {
+		a[k] = v
+	}
+	return &pipelinesv1beta1.PipelineRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "foo",
+			Namespace:   "bar",
+			Annotations: a,
+		},
+		Spec: pipelinesv1beta1.PipelineRunSpec{
+			PipelineRef: &pipelinesv1beta1.PipelineRef{Name: "build"},
+		},
+		Status: pipelinesv1beta1.PipelineRunStatus{
+			Status: knativeapisduckv1beta1.Status{
+				Conditions: []knativeapis.Condition{
+					{
+						Type:    knativeapis.ConditionSucceeded,
+						Status:  status,
+						Reason:  reason,
+						Message: "Message",
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestGitLabNotify(t *testing.T) {
+	const statusPath = "/api/v4/projects/group%2Fsubgroup%2Fproject/statuses/abc"
+	const notePath = "/api/v4/projects/group%2Fsubgroup%2Fproject/merge_requests/7/notes"
+	for _, c := range []struct {
+		name                string
+		pr                  *pipelinesv1beta1.PipelineRun
+		mergeRequestComment bool
+		wantState           string
+		wantPaths           []string
+		wantErr             *ProviderError
+	}{
+		{
+			name:      "Running",
+			pr:        newGitLabTestPipelineRun(corev1.ConditionUnknown, "Running", nil),
+			wantState: "running",
+			wantPaths: []string{statusPath},
+		},
+		{
+			name:      "Pending",
+			pr:        newGitLabTestPipelineRun(corev1.ConditionUnknown, "PipelineRunPending", nil),
+			wantState: "pending",
+			wantPaths: []string{statusPath},
+		},
+		{
+			name:      "Succeeded",
+			pr:        newGitLabTestPipelineRun(corev1.ConditionTrue, "Succeeded", nil),
+			wantState: "success",
+			wantPaths: []string{statusPath},
+		},
+		{
+			name:      "Cancelled",
+			pr:        newGitLabTestPipelineRun(corev1.ConditionFalse, "Cancelled", nil),
+			wantState: "canceled",
+			wantPaths: []string{statusPath},
+		},
+		{
+			name: "FailedWithMergeRequestComment",
+			pr: newGitLabTestPipelineRun(corev1.ConditionFalse, "Failed", map[string]string{
+				"integrations.tekton.ornew.io/gitlab-merge-request-iid": "7",
+			}),
+			mergeRequestComment: true,
+			wantState:           "failed",
+			wantPaths:           []string{statusPath, notePath},
+		},
+		{
+			name: "RunningWithoutMergeRequestComment",
+			pr: newGitLabTestPipelineRun(corev1.ConditionUnknown, "Running", map[string]string{
+				"integrations.tekton.ornew.io/gitlab-merge-request-iid": "7",
+			}),
+			mergeRequestComment: true,
+			wantState:           "running",
+			wantPaths:           []string{statusPath},
+		},
+		{
+			name: "MissingAnnotations",
+			pr: newGitLabTestPipelineRun(corev1.ConditionTrue, "Succeeded", map[string]string{
+				"integrations.tekton.ornew.io/gitlab-sha": "",
+			}),
+			wantErr: NewFailedValidationError(""),
+		},
+		{
+			name: "ProjectNotFound",
+			pr: newGitLabTestPipelineRun(corev1.ConditionTrue, "Succeeded", map[string]string{
+				"integrations.tekton.ornew.io/gitlab-owner": "group",
+				"integrations.tekton.ornew.io/gitlab-repo":  "missing",
+			}),
+			wantErr: NewRuntimeError(""),
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			var requests []gitlabRequest
+			srv := newFakeGitLab(t, &requests)
+			a := &GitLab{
+				BaseURL:             srv.URL,
+				AccessToken:         NewSecretBytes([]byte("glpat-xxxx")),
+				MergeRequestComment: c.mergeRequestComment,
+			}
+			err := a.Notify(ctx, c.pr)
+			if c.wantErr != nil {
+				if assert.NotNil(t, err) {
+					assert.Equal(t, c.wantErr.Code, err.Code)
+				}
+				return
+			}
+			assert.Nil(t, err)
+			paths := make([]string, 0, len(requests))
+			for _, r := range requests {
+				paths = append(paths, r.Path)
+				assert.Equal(t, "Bearer glpat-xxxx", r.Auth)
+			}
+			assert.Equal(t, c.wantPaths, paths)
+			assert.Equal(t, c.wantState, requests[0].Body["state"])
+			assert.Equal(t, "tekton: build", requests[0].Body["name"])
+		})
+	}
+}
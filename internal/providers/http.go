@@ -0,0 +1,416 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// httpRequestRate and httpRequestBurst limit the requests per host.
+	// Most of the APIs allow more, but the notifications of many runs finishing
+	// at once shouldn't exhaust the limits shared with the other clients.
+	httpRequestRate  = rate.Limit(5)
+	httpRequestBurst = 10
+
+	// httpMaxRetries is the number of the retries of the rate limited requests.
+	httpMaxRetries = 3
+	// httpMaxRetryWait is the longest wait for a rate limit to be reset.
+	// The requests that must wait longer fail with RateLimited.
+	httpMaxRetryWait = 30 * time.Second
+	// httpDefaultRetryWait is the wait when the rate limited response has no hint of the reset.
+	httpDefaultRetryWait = time.Second
+
+	httpTimeout = 2 * time.Minute
+
+	// httpMaxErrorBody is the length of the response body included in the errors.
+	httpMaxErrorBody = 1024
+)
+
+var (
+	httpRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "tekton_integration_http_requests_total",
+		Help: "Total number of the HTTP requests to the providers by host and status code.",
+	}, []string{"host", "code"})
+	httpRateLimitedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "tekton_integration_http_rate_limited_total",
+		Help: "Total number of the HTTP responses rate limited by the providers.",
+	}, []string{"host"})
+	httpRateLimitRemaining = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "tekton_integration_http_rate_limit_remaining",
+		Help: "The remaining requests in the current rate limit window reported by the providers.",
+	}, []string{"host"})
+	httpThrottleSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "tekton_integration_http_throttle_seconds",
+		Help:    "Time the HTTP requests to the providers waited for the rate limits.",
+		Buckets: []float64{0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30},
+	}, []string{"host"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		httpRequestsTotal,
+		httpRateLimitedTotal,
+		httpRateLimitRemaining,
+		httpThrottleSeconds,
+	)
+}
+
+// httpTransport is shared by all providers so that the rate limits apply
+// across the providers and the notifications talking to the same host.
+var httpTransport = newRateLimitTransport(http.DefaultTransport)
+
+var httpClient = &http.Client{
+	Transport: httpTransport,
+	Timeout:   httpTimeout,
+}
+
+// rateLimitTransport throttles the requests with a token bucket per host,
+// and waits for the rate limits reported by the responses.
+// The rate limited requests are retried if they can be sent again.
+type rateLimitTransport struct {
+	base  http.RoundTripper
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+
+	mu    sync.Mutex
+	hosts map[string]*hostRateLimit
+}
+
+type hostRateLimit struct {
+	limiter *rate.Limiter
+	// blockedUntil is when the rate limit reported by the host is reset.
+	blockedUntil time.Time
+}
+
+func newRateLimitTransport(base http.RoundTripper) *rateLimitTransport {
+	return &rateLimitTransport{
+		base:  base,
+		now:   time.Now,
+		sleep: sleepContext,
+		hosts: map[string]*hostRateLimit{},
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+type httpTransportContextKey struct{}
+
+// withHTTPTransport returns a copy of ctx whose requests are sent by the transport instead of
+// the default transport, e.g. to verify the servers with the custom CAs.
+// The requests still share the rate limits of the hosts with the other providers.
+func withHTTPTransport(ctx context.Context, tr http.RoundTripper) context.Context {
+	return context.WithValue(ctx, httpTransportContextKey{}, tr)
+}
+
+// errRateLimited is returned when the host blocks the requests longer than httpMaxRetryWait.
+var errRateLimited = errors.New("rate limited")
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	for attempt := 0; ; attempt++ {
+		if err := t.wait(req.Context(), host); err != nil {
+			return nil, err
+		}
+		r := req
+		if attempt > 0 {
+			r = req.Clone(req.Context())
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				r.Body = body
+			}
+		}
+		base := t.base
+		if tr, ok := req.Context().Value(httpTransportContextKey{}).(http.RoundTripper); ok {
+			base = tr
+		}
+		resp, err := base.RoundTrip(r)
+		if err != nil {
+			return nil, err
+		}
+		httpRequestsTotal.WithLabelValues(host, strconv.Itoa(resp.StatusCode)).Inc()
+		wait, limited := t.observe(host, resp)
+		if !limited {
+			return resp, nil
+		}
+		httpRateLimitedTotal.WithLabelValues(host).Inc()
+		canRetry := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+		if attempt >= httpMaxRetries || wait > httpMaxRetryWait || !canRetry {
+			return resp, nil
+		}
+		// the body must be read to the end to reuse the connection.
+		io.Copy(ioutil.Discard, io.LimitReader(resp.Body, httpMaxErrorBody))
+		resp.Body.Close()
+	}
+}
+
+func (t *rateLimitTransport) host(host string) *hostRateLimit {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	h, ok := t.hosts[host]
+	if !ok {
+		h = &hostRateLimit{limiter: rate.NewLimiter(httpRequestRate, httpRequestBurst)}
+		t.hosts[host] = h
+	}
+	return h
+}
+
+// wait blocks until the request can be sent to the host.
+func (t *rateLimitTransport) wait(ctx context.Context, host string) error {
+	h := t.host(host)
+	start := t.now()
+	t.mu.Lock()
+	d := h.blockedUntil.Sub(start)
+	t.mu.Unlock()
+	if d > httpMaxRetryWait {
+		return fmt.Errorf("%w by %s for %s", errRateLimited, host, d.Round(time.Second))
+	}
+	if d > 0 {
+		if err := t.sleep(ctx, d); err != nil {
+			return err
+		}
+	}
+	if err := h.limiter.Wait(ctx); err != nil {
+		return err
+	}
+	if waited := t.now().Sub(start); waited > 0 {
+		httpThrottleSeconds.WithLabelValues(host).Observe(waited.Seconds())
+	}
+	return nil
+}
+
+// observe reads the rate limit of the response, and blocks the host until the limit is reset.
+// It returns true if the request was rate limited, and how long it should wait for the retry.
+//
+// The limits are reported with Retry-After by Slack, Discord and the secondary
+// rate limits of GitHub, and with X-RateLimit-Remaining and X-RateLimit-Reset by
+// the primary rate limits of GitHub and the most of the other APIs.
+func (t *rateLimitTransport) observe(host string, resp *http.Response) (time.Duration, bool) {
+	now := t.now()
+	remaining := resp.Header.Get("X-RateLimit-Remaining")
+	if n, err := strconv.Atoi(remaining); err == nil {
+		httpRateLimitRemaining.WithLabelValues(host).Set(float64(n))
+	}
+	exhausted := remaining == "0"
+	limited := isRateLimitedResponse(resp)
+	if !limited && !exhausted {
+		return 0, false
+	}
+	wait, ok := parseRetryAfter(resp.Header.Get("Retry-After"), now)
+	if !ok && exhausted {
+		wait, ok = parseRateLimitReset(resp.Header.Get("X-RateLimit-Reset"), now)
+	}
+	if !ok {
+		if !limited {
+			return 0, false
+		}
+		wait = httpDefaultRetryWait
+	}
+	t.mu.Lock()
+	h := t.hosts[host]
+	if until := now.Add(wait); h != nil && until.After(h.blockedUntil) {
+		h.blockedUntil = until
+	}
+	t.mu.Unlock()
+	return wait, limited
+}
+
+// isRateLimitedResponse returns true if the response is rejected by the rate limit.
+// GitHub responds with 403 instead of 429 for both the primary and the secondary rate limits.
+func isRateLimitedResponse(resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusForbidden:
+		return len(resp.Header.Get("Retry-After")) > 0 || resp.Header.Get("X-RateLimit-Remaining") == "0"
+	}
+	return false
+}
+
+// parseRetryAfter parses the Retry-After header in seconds or in the HTTP date.
+func parseRetryAfter(v string, now time.Time) (time.Duration, bool) {
+	if len(v) < 1 {
+		return 0, false
+	}
+	if s, err := strconv.Atoi(v); err == nil {
+		if s < 0 {
+			return 0, false
+		}
+		return time.Duration(s) * time.Second, true
+	}
+	if at, err := http.ParseTime(v); err == nil {
+		return nonNegative(at.Sub(now)), true
+	}
+	return 0, false
+}
+
+// parseRateLimitReset parses the X-RateLimit-Reset header in the UNIX epoch seconds.
+// Discord sends the fractional seconds.
+func parseRateLimitReset(v string, now time.Time) (time.Duration, bool) {
+	s, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, false
+	}
+	at := time.Unix(0, int64(s*float64(time.Second)))
+	return nonNegative(at.Sub(now)), true
+}
+
+func nonNegative(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// httpStatusError is returned for the responses with a non-2xx status code.
+type httpStatusError struct {
+	StatusCode  int
+	Status      string
+	Body        string
+	RateLimited bool
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Status, e.Body)
+}
+
+// isRateLimited returns true if the error is a rate limit that could not be waited for.
+func isRateLimited(err error) bool {
+	var serr *httpStatusError
+	if errors.As(err, &serr) {
+		return serr.RateLimited
+	}
+	return errors.Is(err, errRateLimited)
+}
+
+// newHTTPError converts the error of the request to the API of the service.
+func newHTTPError(service string, err error) *ProviderError {
+	if isRateLimited(err) {
+		return NewRateLimitedError(fmt.Sprintf("rate limited by %s: %v", service, err))
+	}
+	var serr *httpStatusError
+	if errors.As(err, &serr) {
+		return NewRuntimeError(fmt.Sprintf("get an error from %s: %v", service, serr))
+	}
+	return NewRuntimeError(fmt.Sprintf("failed to request %s: %v", service, err))
+}
+
+// newWebhookError converts the error of the request to the webhook of the service.
+// The errors of net/http are not included in the message, since they contain the URL with the credentials.
+func newWebhookError(service string, err error) *ProviderError {
+	var serr *httpStatusError
+	if errors.As(err, &serr) {
+		if serr.RateLimited {
+			return NewRateLimitedError(fmt.Sprintf("rate limited by %s: %s", service, serr.Status))
+		}
+		return NewRuntimeError(fmt.Sprintf("get an error from %s: %v", service, serr))
+	}
+	if isRateLimited(err) {
+		return NewRateLimitedError(fmt.Sprintf("rate limited by %s", service))
+	}
+	return NewRuntimeError(fmt.Sprintf("failed to post to %s", service))
+}
+
+// sendHTTP sends the request with the shared client and returns the body of the response.
+// The response body is always closed, and the non-2xx responses are returned as *httpStatusError.
+func sendHTTP(req *http.Request) ([]byte, error) {
+	return sendHTTPExpecting(req, isSuccessStatusCode)
+}
+
+func isSuccessStatusCode(code int) bool {
+	return code >= 200 && code < 300
+}
+
+// sendHTTPExpecting is sendHTTP returning the responses as *httpStatusError unless expected returns true for the status code.
+func sendHTTPExpecting(req *http.Request, expected func(code int) bool) ([]byte, error) {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if !expected(resp.StatusCode) {
+		if len(b) > httpMaxErrorBody {
+			b = b[:httpMaxErrorBody]
+		}
+		return b, &httpStatusError{
+			StatusCode:  resp.StatusCode,
+			Status:      resp.Status,
+			Body:        string(b),
+			RateLimited: isRateLimitedResponse(resp),
+		}
+	}
+	return b, nil
+}
+
+// doHTTP sends the payload encoded in JSON, or no body if the payload is nil.
+// The Authorization header is set if auth is not empty.
+func doHTTP(ctx context.Context, method, url, auth string, payload interface{}) ([]byte, error) {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal payload: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if len(auth) > 0 {
+		req.Header.Set("Authorization", auth)
+	}
+	return sendHTTP(req)
+}
+
+func postHTTP(ctx context.Context, url, auth string, payload interface{}) ([]byte, error) {
+	return doHTTP(ctx, http.MethodPost, url, auth, payload)
+}
@@ -0,0 +1,264 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeResponse struct {
+	code    int
+	headers map[string]string
+}
+
+func TestRateLimitTransport(t *testing.T) {
+	now := time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)
+	reset := strconv.FormatInt(now.Add(5*time.Second).Unix(), 10)
+	discordReset := fmt.Sprintf("%d.5", now.Add(time.Second).Unix())
+	for _, c := range []struct {
+		name      string
+		responses []fakeResponse
+		wantCode  int
+		wantCalls int
+		wantSlept []time.Duration
+		wantErr   bool
+	}{
+		{
+			name:      "OK",
+			responses: []fakeResponse{{code: http.StatusOK}},
+			wantCode:  http.StatusOK,
+			wantCalls: 1,
+		},
+		{
+			name: "RetryAfter",
+			responses: []fakeResponse{
+				{code: http.StatusTooManyRequests, headers: map[string]string{"Retry-After": "2"}},
+				{code: http.StatusOK},
+			},
+			wantCode:  http.StatusOK,
+			wantCalls: 2,
+			wantSlept: []time.Duration{2 * time.Second},
+		},
+		{
+			name: "RetryAfterDate",
+			responses: []fakeResponse{
+				{code: http.StatusTooManyRequests, headers: map[string]string{"Retry-After": now.Add(3 * time.Second).Format(http.TimeFormat)}},
+				{code: http.StatusOK},
+			},
+			wantCode:  http.StatusOK,
+			wantCalls: 2,
+			wantSlept: []time.Duration{3 * time.Second},
+		},
+		{
+			name: "NoHint",
+			responses: []fakeResponse{
+				{code: http.StatusTooManyRequests},
+				{code: http.StatusOK},
+			},
+			wantCode:  http.StatusOK,
+			wantCalls: 2,
+			wantSlept: []time.Duration{httpDefaultRetryWait},
+		},
+		{
+			name: "GitHubSecondaryRateLimit",
+			responses: []fakeResponse{
+				{code: http.StatusForbidden, headers: map[string]string{"Retry-After": "1"}},
+				{code: http.StatusCreated},
+			},
+			wantCode:  http.StatusCreated,
+			wantCalls: 2,
+			wantSlept: []time.Duration{time.Second},
+		},
+		{
+			name: "GitHubPrimaryRateLimit",
+			responses: []fakeResponse{
+				{code: http.StatusForbidden, headers: map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": reset}},
+				{code: http.StatusOK},
+			},
+			wantCode:  http.StatusOK,
+			wantCalls: 2,
+			wantSlept: []time.Duration{5 * time.Second},
+		},
+		{
+			name: "DiscordRateLimit",
+			responses: []fakeResponse{
+				{code: http.StatusTooManyRequests, headers: map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": discordReset}},
+				{code: http.StatusNoContent},
+			},
+			wantCode:  http.StatusNoContent,
+			wantCalls: 2,
+			wantSlept: []time.Duration{1500 * time.Millisecond},
+		},
+		{
+			name:      "Forbidden",
+			responses: []fakeResponse{{code: http.StatusForbidden}},
+			wantCode:  http.StatusForbidden,
+			wantCalls: 1,
+		},
+		{
+			name: "TooLong",
+			responses: []fakeResponse{
+				{code: http.StatusTooManyRequests, headers: map[string]string{"Retry-After": "60"}},
+			},
+			wantCode:  http.StatusTooManyRequests,
+			wantCalls: 1,
+		},
+		{
+			name: "Exhausted",
+			responses: []fakeResponse{
+				{code: http.StatusTooManyRequests, headers: map[string]string{"Retry-After": "1"}},
+				{code: http.StatusTooManyRequests, headers: map[string]string{"Retry-After": "1"}},
+				{code: http.StatusTooManyRequests, headers: map[string]string{"Retry-After": "1"}},
+				{code: http.StatusTooManyRequests, headers: map[string]string{"Retry-After": "1"}},
+			},
+			wantCode:  http.StatusTooManyRequests,
+			wantCalls: 4,
+			wantSlept: []time.Duration{time.Second, time.Second, time.Second},
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			calls := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, _ := ioutil.ReadAll(r.Body)
+				assert.Equal(t, "payload", string(b))
+				resp := c.responses[calls]
+				calls++
+				for k, v := range resp.headers {
+					w.Header().Set(k, v)
+				}
+				w.WriteHeader(resp.code)
+			}))
+			defer srv.Close()
+
+			current := now
+			var slept []time.Duration
+			tr := newRateLimitTransport(http.DefaultTransport)
+			tr.now = func() time.Time { return current }
+			tr.sleep = func(ctx context.Context, d time.Duration) error {
+				slept = append(slept, d)
+				current = current.Add(d)
+				return nil
+			}
+			req, err := http.NewRequest(http.MethodPost, srv.URL, bytes.NewReader([]byte("payload")))
+			assert.Nil(t, err)
+			resp, err := (&http.Client{Transport: tr}).Do(req)
+			assert.Nil(t, err)
+			resp.Body.Close()
+			assert.Equal(t, c.wantCode, resp.StatusCode)
+			assert.Equal(t, c.wantCalls, calls)
+			assert.Equal(t, c.wantSlept, slept)
+		})
+	}
+}
+
+func TestRateLimitTransportBlocksHost(t *testing.T) {
+	now := time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(now.Add(time.Hour).Unix(), 10))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	tr := newRateLimitTransport(http.DefaultTransport)
+	tr.now = func() time.Time { return now }
+	client := &http.Client{Transport: tr}
+	resp, err := client.Get(srv.URL)
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// the next request fails without sending until the limit is reset.
+	_, err = client.Get(srv.URL)
+	assert.True(t, errors.Is(err, errRateLimited))
+	assert.True(t, isRateLimited(err))
+	assert.Equal(t, 1, calls)
+}
+
+func TestNewHTTPError(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		err  error
+		want ErrorCode
+	}{
+		{
+			name: "Status",
+			err:  &httpStatusError{StatusCode: http.StatusBadRequest, Status: "400 Bad Request"},
+			want: ErrorCodeRuntimeError,
+		},
+		{
+			name: "RateLimited",
+			err:  &httpStatusError{StatusCode: http.StatusTooManyRequests, Status: "429 Too Many Requests", RateLimited: true},
+			want: ErrorCodeRateLimited,
+		},
+		{
+			name: "Blocked",
+			err:  errRateLimited,
+			want: ErrorCodeRateLimited,
+		},
+		{
+			name: "Network",
+			err:  errors.New("connection refused"),
+			want: ErrorCodeRuntimeError,
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.want, newHTTPError("Test", c.err).Code)
+		})
+	}
+}
+
+func TestSendHTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		if r.URL.Path == "/error" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write(bytes.Repeat([]byte("x"), 2*httpMaxErrorBody))
+			return
+		}
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+
+	b, err := postHTTP(ctx, srv.URL, "Bearer token", map[string]string{"a": "b"})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"ok":true}`, string(b))
+
+	_, err = postHTTP(ctx, srv.URL+"/error", "Bearer token", map[string]string{"a": "b"})
+	var serr *httpStatusError
+	if assert.True(t, errors.As(err, &serr)) {
+		assert.Equal(t, http.StatusBadRequest, serr.StatusCode)
+		assert.Len(t, serr.Body, httpMaxErrorBody)
+		assert.False(t, serr.RateLimited)
+	}
+}
@@ -0,0 +1,307 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"context"
+	"crypto/sha512"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"time"
+
+	"knative.dev/pkg/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/Shopify/sarama"
+	"github.com/go-logr/logr"
+	pipelinesv1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"github.com/xdg-go/scram"
+
+	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
+)
+
+const (
+	kafkaClientID = "tekton-integration"
+	// kafkaDefaultTimeout bounds the requests to the brokers if the context has no deadline.
+	kafkaDefaultTimeout = 10 * time.Second
+	// kafkaRetryMax and kafkaRetryBackoff keep the retries of sarama within the deadline.
+	kafkaRetryMax     = 1
+	kafkaRetryBackoff = 100 * time.Millisecond
+)
+
+var kafkaRequiredAcks = map[string]sarama.RequiredAcks{
+	"None":   sarama.NoResponse,
+	"Leader": sarama.WaitForLocal,
+	"All":    sarama.WaitForAll,
+}
+
+// Kafka produces a record per transition of the run to the topic.
+// The records are keyed by the pipeline, so that the records of a pipeline are ordered in a partition.
+type Kafka struct {
+	Brokers      []string
+	Topic        string
+	Encoding     string
+	RequiredAcks sarama.RequiredAcks
+	Version      sarama.KafkaVersion
+
+	TLS        bool
+	CA         []byte
+	ClientCert SecretBytes
+	ClientKey  SecretBytes
+
+	SASLMechanism string
+	SASLUser      string
+	SASLPassword  SecretString
+}
+
+var _ Provider = (*Kafka)(nil)
+var _ Validator = (*Kafka)(nil)
+
+func NewKafka(ctx context.Context, p *v1alpha1.Provider, k client.Client) (*Kafka, *ProviderError) {
+	s := p.Spec.Kafka
+	if s == nil {
+		return nil, NewInvalidProviderSpecError("missing value .kafka")
+	}
+	if len(s.Brokers) < 1 {
+		return nil, NewInvalidProviderSpecError("missing value .kafka.brokers")
+	}
+	if len(s.Topic) < 1 {
+		return nil, NewInvalidProviderSpecError("missing value .kafka.topic")
+	}
+	a := &Kafka{
+		Brokers:      s.Brokers,
+		Topic:        s.Topic,
+		Encoding:     runEventEncodingJSON,
+		RequiredAcks: sarama.WaitForAll,
+		Version:      sarama.V1_0_0_0,
+	}
+	if s.Encoding != nil && len(*s.Encoding) > 0 {
+		if !isRunEventEncoding(*s.Encoding) {
+			return nil, NewInvalidProviderSpecError(fmt.Sprintf("unknown encoding: %s", *s.Encoding))
+		}
+		a.Encoding = *s.Encoding
+	}
+	if s.RequiredAcks != nil && len(*s.RequiredAcks) > 0 {
+		acks, ok := kafkaRequiredAcks[*s.RequiredAcks]
+		if !ok {
+			return nil, NewInvalidProviderSpecError(fmt.Sprintf("unknown requiredAcks: %s", *s.RequiredAcks))
+		}
+		a.RequiredAcks = acks
+	}
+	if s.Version != nil && len(*s.Version) > 0 {
+		v, err := sarama.ParseKafkaVersion(*s.Version)
+		if err != nil {
+			return nil, NewInvalidProviderSpecError(fmt.Sprintf("invalid version: %s", *s.Version))
+		}
+		a.Version = v
+	}
+	if s.TLS != nil {
+		a.TLS = true
+		if s.TLS.SecretRef != nil {
+			data, perr := getSecretData(ctx, k, p.Namespace, s.TLS.SecretRef.Name)
+			if perr != nil {
+				return nil, perr
+			}
+			a.CA = data["ca.crt"]
+			a.ClientCert = NewSecretBytes(data["tls.crt"])
+			a.ClientKey = NewSecretBytes(data["tls.key"])
+		}
+	}
+	if s.SASL != nil {
+		a.SASLMechanism = sarama.SASLTypePlaintext
+		if s.SASL.Mechanism != nil && len(*s.SASL.Mechanism) > 0 {
+			a.SASLMechanism = *s.SASL.Mechanism
+		}
+		data, perr := getSecretData(ctx, k, p.Namespace, s.SASL.SecretRef.Name, "username", "password")
+		if perr != nil {
+			return nil, perr
+		}
+		a.SASLUser = string(data["username"])
+		a.SASLPassword = NewSecretString(string(data["password"]))
+	}
+	if _, perr := a.newConfig(ctx); perr != nil {
+		return nil, perr
+	}
+	return a, nil
+}
+
+// getKafkaTimeout returns the time left until the deadline of ctx,
+// or kafkaDefaultTimeout if ctx has no deadline.
+func getKafkaTimeout(ctx context.Context) time.Duration {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return kafkaDefaultTimeout
+	}
+	// sarama rejects the zero timeouts and the producer timeout is in milliseconds.
+	if timeout := time.Until(deadline); timeout > time.Millisecond {
+		return timeout
+	}
+	return time.Millisecond
+}
+
+// newConfig returns the config of the producer. The config is built on each use,
+// since it has the credentials which must not be logged.
+// The requests to the brokers are bounded by the deadline of ctx.
+func (a *Kafka) newConfig(ctx context.Context) (*sarama.Config, *ProviderError) {
+	timeout := getKafkaTimeout(ctx)
+	c := sarama.NewConfig()
+	c.ClientID = kafkaClientID
+	c.Version = a.Version
+	c.Net.DialTimeout = timeout
+	c.Net.ReadTimeout = timeout
+	c.Net.WriteTimeout = timeout
+	c.Metadata.Timeout = timeout
+	c.Metadata.Retry.Max = kafkaRetryMax
+	c.Metadata.Retry.Backoff = kafkaRetryBackoff
+	c.Producer.Timeout = timeout
+	c.Producer.Retry.Max = kafkaRetryMax
+	c.Producer.Retry.Backoff = kafkaRetryBackoff
+	c.Producer.RequiredAcks = a.RequiredAcks
+	c.Producer.Return.Successes = true
+	if a.TLS {
+		tc := &tls.Config{}
+		if len(a.CA) > 0 {
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(a.CA) {
+				return nil, NewFailedValidationError("no certificates found in ca.crt")
+			}
+			tc.RootCAs = pool
+		}
+		if cert, key := a.ClientCert.GetNoRedacted(), a.ClientKey.GetNoRedacted(); len(cert) > 0 || len(key) > 0 {
+			pair, err := tls.X509KeyPair(cert, key)
+			if err != nil {
+				return nil, NewFailedValidationError(fmt.Sprintf("invalid client certificate: %v", err))
+			}
+			tc.Certificates = []tls.Certificate{pair}
+		}
+		c.Net.TLS.Enable = true
+		c.Net.TLS.Config = tc
+	}
+	if len(a.SASLMechanism) > 0 {
+		c.Net.SASL.Enable = true
+		c.Net.SASL.Mechanism = sarama.SASLMechanism(a.SASLMechanism)
+		c.Net.SASL.User = a.SASLUser
+		c.Net.SASL.Password = a.SASLPassword.GetNoRedactedString()
+		switch a.SASLMechanism {
+		case sarama.SASLTypePlaintext:
+		case sarama.SASLTypeSCRAMSHA256:
+			c.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+				return &kafkaSCRAMClient{HashGeneratorFcn: scram.SHA256}
+			}
+		case sarama.SASLTypeSCRAMSHA512:
+			c.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+				return &kafkaSCRAMClient{HashGeneratorFcn: sha512.New}
+			}
+		default:
+			return nil, NewInvalidProviderSpecError(fmt.Sprintf("unknown SASL mechanism: %s", a.SASLMechanism))
+		}
+	}
+	return c, nil
+}
+
+func (a *Kafka) Notify(ctx context.Context, pr *pipelinesv1beta1.PipelineRun) *ProviderError {
+	log := logr.FromContext(ctx).WithName("providers.kafka").
+		WithValues("providerType", "Kafka", "pipelineRun", pr.Name)
+	cond := pr.Status.GetCondition(apis.ConditionSucceeded)
+	if cond == nil {
+		log.V(1).Info("PipelineRun has not condition, ignored")
+		return NewSkippedError("PipelineRun has not condition")
+	}
+	msg, perr := a.newMessage(pr)
+	if perr != nil {
+		return perr
+	}
+	if err := ctx.Err(); err != nil {
+		return NewRuntimeError(fmt.Sprintf("failed to connect to Kafka: %v", err))
+	}
+	config, perr := a.newConfig(ctx)
+	if perr != nil {
+		return perr
+	}
+	// The producer is closed after each record on purpose. The Providers are resolved
+	// on each reconcile and their brokers and credentials may change between the runs,
+	// so a cached producer could outlive its config. The notifications are rare enough
+	// that the cost of the connection doesn't matter.
+	producer, err := sarama.NewSyncProducer(a.Brokers, config)
+	if err != nil {
+		return NewRuntimeError(fmt.Sprintf("failed to connect to Kafka: %v", err))
+	}
+	defer producer.Close()
+	partition, offset, err := producer.SendMessage(msg)
+	if err != nil {
+		return NewRuntimeError(fmt.Sprintf("failed to produce the record to Kafka: %v", err))
+	}
+	log.V(2).Info("produce record", "topic", a.Topic, "partition", partition, "offset", offset)
+	return nil
+}
+
+// Validate connects to the brokers and checks that the topic exists.
+func (a *Kafka) Validate(ctx context.Context) *ProviderError {
+	config, perr := a.newConfig(ctx)
+	if perr != nil {
+		return perr
+	}
+	c, err := sarama.NewClient(a.Brokers, config)
+	if err != nil {
+		return NewRuntimeError(fmt.Sprintf("failed to connect to Kafka: %v", err))
+	}
+	defer c.Close()
+	if _, err := c.Partitions(a.Topic); err != nil {
+		if errors.Is(err, sarama.ErrUnknownTopicOrPartition) {
+			return NewFailedValidationError(fmt.Sprintf("topic %s is not found", a.Topic))
+		}
+		return NewRuntimeError(fmt.Sprintf("failed to get the partitions of the topic %s: %v", a.Topic, err))
+	}
+	return nil
+}
+
+// newMessage returns the record of the transition of the run, whose key is the identity of the pipeline.
+func (a *Kafka) newMessage(pr *pipelinesv1beta1.PipelineRun) (*sarama.ProducerMessage, *ProviderError) {
+	pipeline, perr := getPipelineIdentity(pr)
+	if perr != nil {
+		return nil, perr
+	}
+	b, contentType, perr := encodeRunEvent(pr, a.Encoding)
+	if perr != nil {
+		return nil, perr
+	}
+	return &sarama.ProducerMessage{
+		Topic: a.Topic,
+		Key:   sarama.StringEncoder(pipeline),
+		Value: sarama.ByteEncoder(b),
+		Headers: []sarama.RecordHeader{
+			{Key: []byte("content-type"), Value: []byte(contentType)},
+		},
+	}, nil
+}
+
+// kafkaSCRAMClient implements sarama.SCRAMClient by xdg-go/scram.
+type kafkaSCRAMClient struct {
+	*scram.ClientConversation
+	scram.HashGeneratorFcn
+}
+
+func (c *kafkaSCRAMClient) Begin(userName, password, authzID string) error {
+	client, err := c.HashGeneratorFcn.NewClient(userName, password, authzID)
+	if err != nil {
+		return err
+	}
+	c.ClientConversation = client.NewConversation()
+	return nil
+}
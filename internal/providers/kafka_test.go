@@ -0,0 +1,350 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
+)
+
+func TestNewKafka(t *testing.T) {
+	k := fakeclient.NewClientBuilder().
+		WithObjects(
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "sasl", Namespace: "default"},
+				Data: map[string][]byte{
+					"username": []byte("tekton"),
+					"password": []byte("pass"),
+				},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "invalid-tls", Namespace: "default"},
+				Data: map[string][]byte{
+					"ca.crt": []byte("not a certificate"),
+				},
+			},
+		).
+		Build()
+	for _, c := range []struct {
+		name         string
+		spec         *v1alpha1.KafkaSpec
+		wantEncoding string
+		wantAcks     sarama.RequiredAcks
+		wantSASL     string
+		wantErr      *ProviderError
+	}{
+		{
+			name:         "Basic",
+			spec:         &v1alpha1.KafkaSpec{Brokers: []string{"kafka:9092"}, Topic: "tekton"},
+			wantEncoding: "JSON",
+			wantAcks:     sarama.WaitForAll,
+		},
+		{
+			name: "SASL",
+			spec: &v1alpha1.KafkaSpec{
+				Brokers:      []string{"kafka:9092"},
+				Topic:        "tekton",
+				Encoding:     pointer.String("CloudEvents"),
+				RequiredAcks: pointer.String("Leader"),
+				Version:      pointer.String("2.8.0"),
+				TLS:          &v1alpha1.KafkaTLS{},
+				SASL: &v1alpha1.KafkaSASL{
+					Mechanism: pointer.String("SCRAM-SHA-512"),
+					SecretRef: corev1.LocalObjectReference{Name: "sasl"},
+				},
+			},
+			wantEncoding: "CloudEvents",
+			wantAcks:     sarama.WaitForLocal,
+			wantSASL:     "SCRAM-SHA-512",
+		},
+		{
+			name: "SASLSecretNotFound",
+			spec: &v1alpha1.KafkaSpec{
+				Brokers: []string{"kafka:9092"},
+				Topic:   "tekton",
+				SASL: &v1alpha1.KafkaSASL{
+					SecretRef: corev1.LocalObjectReference{Name: "not-exists"},
+				},
+			},
+			wantErr: NewNotFoundPrivateKeyError(""),
+		},
+		{
+			name: "InvalidCA",
+			spec: &v1alpha1.KafkaSpec{
+				Brokers: []string{"kafka:9092"},
+				Topic:   "tekton",
+				TLS: &v1alpha1.KafkaTLS{
+					SecretRef: &corev1.LocalObjectReference{Name: "invalid-tls"},
+				},
+			},
+			wantErr: NewFailedValidationError(""),
+		},
+		{
+			name: "InvalidVersion",
+			spec: &v1alpha1.KafkaSpec{
+				Brokers: []string{"kafka:9092"},
+				Topic:   "tekton",
+				Version: pointer.String("latest"),
+			},
+			wantErr: NewInvalidProviderSpecError(""),
+		},
+		{
+			name:    "MissingBrokers",
+			spec:    &v1alpha1.KafkaSpec{Topic: "tekton"},
+			wantErr: NewInvalidProviderSpecError(""),
+		},
+		{
+			name:    "KafkaSpecNotFound",
+			wantErr: NewInvalidProviderSpecError(""),
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			p := &v1alpha1.Provider{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "provider",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.ProviderSpec{
+					Type:  "Kafka",
+					Kafka: c.spec,
+				},
+			}
+			a, err := NewKafka(ctx, p, k)
+			if c.wantErr != nil {
+				if assert.NotNil(t, err) {
+					assert.Equal(t, c.wantErr.Code, err.Code)
+				}
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, c.wantEncoding, a.Encoding)
+			assert.Equal(t, c.wantAcks, a.RequiredAcks)
+			assert.Equal(t, c.wantSASL, a.SASLMechanism)
+			if len(c.wantSASL) > 0 {
+				assert.Equal(t, "pass", a.SASLPassword.GetNoRedactedString())
+			}
+		})
+	}
+}
+
+func TestKafkaNewMessage(t *testing.T) {
+	for _, c := range []struct {
+		name            string
+		encoding        string
+		wantContentType string
+		want            string
+	}{
+		{
+			name:            "JSON",
+			encoding:        "JSON",
+			wantContentType: "application/json",
+			want: `{
+				"name": "sample-run",
+				"namespace": "default",
+				"uid": "6f1f2a60",
+				"pipeline": "sample",
+				"transition": "failed",
+				"status": "False",
+				"reason": "Failed",
+				"message": "Tasks Completed: 1 (Failed: 1, Cancelled 0), Skipped: 0",
+				"startTime": "2021-07-01T00:00:00Z",
+				"completionTime": "2021-07-01T00:01:00Z",
+				"duration": "1m0s",
+				"dashboardURL": "https://dashboard.example.com/#/namespaces/default/pipelineruns/sample-run",
+				"labels": {"tekton.dev/pipeline": "sample"},
+				"params": {"revision": "main"},
+				"results": {"digest": "sha256:0"},
+				"taskRuns": [{"name": "sample-run-build", "pipelineTask": "build", "status": "False", "reason": "Failed"}]
+			}`,
+		},
+		{
+			name:            "CloudEvents",
+			encoding:        "CloudEvents",
+			wantContentType: "application/cloudevents+json",
+			want: `{
+				"specversion": "1.0",
+				"id": "6f1f2a60-failed",
+				"source": "/apis/tekton.dev/v1beta1/namespaces/default/pipelineruns/sample-run",
+				"type": "io.ornew.tekton.integrations.pipelinerun.failed",
+				"subject": "sample",
+				"datacontenttype": "application/json",
+				"data": {
+					"name": "sample-run",
+					"namespace": "default",
+					"uid": "6f1f2a60",
+					"pipeline": "sample",
+					"transition": "failed",
+					"status": "False",
+					"reason": "Failed",
+					"message": "Tasks Completed: 1 (Failed: 1, Cancelled 0), Skipped: 0",
+					"startTime": "2021-07-01T00:00:00Z",
+					"completionTime": "2021-07-01T00:01:00Z",
+					"duration": "1m0s",
+					"dashboardURL": "https://dashboard.example.com/#/namespaces/default/pipelineruns/sample-run",
+					"labels": {"tekton.dev/pipeline": "sample"},
+					"params": {"revision": "main"},
+					"results": {"digest": "sha256:0"},
+					"taskRuns": [{"name": "sample-run-build", "pipelineTask": "build", "status": "False", "reason": "Failed"}]
+				}
+			}`,
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			a := &Kafka{Topic: "tekton", Encoding: c.encoding}
+			pr := newTemplateSamplePipelineRun()
+			pr.UID = "6f1f2a60"
+			msg, perr := a.newMessage(pr)
+			if !assert.Nil(t, perr) {
+				return
+			}
+			assert.Equal(t, "tekton", msg.Topic)
+			assert.Equal(t, sarama.StringEncoder("default/sample"), msg.Key)
+			assert.Equal(t, []sarama.RecordHeader{
+				{Key: []byte("content-type"), Value: []byte(c.wantContentType)},
+			}, msg.Headers)
+			b, err := msg.Value.Encode()
+			assert.Nil(t, err)
+			assert.JSONEq(t, c.want, string(b))
+		})
+	}
+}
+
+func TestKafkaNotify(t *testing.T) {
+	for _, c := range []struct {
+		name      string
+		produce   sarama.KError
+		wantCount int
+		wantErr   *ProviderError
+	}{
+		{
+			name:      "Acknowledged",
+			produce:   sarama.ErrNoError,
+			wantCount: 1,
+		},
+		{
+			name:    "NotAcknowledged",
+			produce: sarama.ErrInvalidMessage,
+			wantErr: NewRuntimeError(""),
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			broker := sarama.NewMockBroker(t, 1)
+			defer broker.Close()
+			broker.SetHandlerByMap(map[string]sarama.MockResponse{
+				"MetadataRequest": sarama.NewMockMetadataResponse(t).
+					SetBroker(broker.Addr(), broker.BrokerID()).
+					SetLeader("tekton", 0, broker.BrokerID()),
+				// the version of ProduceRequest sent with Kafka 1.0.0
+				"ProduceRequest": sarama.NewMockProduceResponse(t).
+					SetVersion(3).
+					SetError("tekton", 0, c.produce),
+			})
+			a := &Kafka{
+				Brokers:      []string{broker.Addr()},
+				Topic:        "tekton",
+				Encoding:     "JSON",
+				RequiredAcks: sarama.WaitForAll,
+				Version:      sarama.V1_0_0_0,
+			}
+			err := a.Notify(ctx, newTemplateSamplePipelineRun())
+			count := 0
+			for _, rr := range broker.History() {
+				if req, ok := rr.Request.(*sarama.ProduceRequest); ok {
+					assert.Equal(t, sarama.WaitForAll, req.RequiredAcks)
+					count++
+				}
+			}
+			if c.wantErr != nil {
+				if assert.NotNil(t, err) {
+					assert.Equal(t, c.wantErr.Code, err.Code)
+				}
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, c.wantCount, count)
+		})
+	}
+}
+
+func TestKafkaNotifyTimeout(t *testing.T) {
+	// the broker accepts the connections, but never responds.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	a := &Kafka{
+		Brokers:      []string{l.Addr().String()},
+		Topic:        "tekton",
+		Encoding:     "JSON",
+		RequiredAcks: sarama.WaitForAll,
+		Version:      sarama.V1_0_0_0,
+	}
+	tctx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := a.Notify(tctx, newTemplateSamplePipelineRun()); assert.NotNil(t, err) {
+		assert.Equal(t, NewRuntimeError("").Code, err.Code)
+	}
+	assert.Less(t, int64(time.Since(start)), int64(2*time.Second))
+}
+
+func TestKafkaValidate(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("tekton", 0, broker.BrokerID()),
+	})
+	a := &Kafka{
+		Brokers:      []string{broker.Addr()},
+		Topic:        "tekton",
+		RequiredAcks: sarama.WaitForAll,
+		Version:      sarama.V1_0_0_0,
+	}
+	assert.Nil(t, a.Validate(ctx))
+
+	a.Topic = "not-exists"
+	if err := a.Validate(ctx); assert.NotNil(t, err) {
+		assert.Equal(t, NewFailedValidationError("").Code, err.Code)
+	}
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"knative.dev/pkg/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/go-logr/logr"
+	pipelinesv1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+
+	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
+)
+
+// Mattermost posts the messages to the channels by a bot account with the REST API v4.
+type Mattermost struct {
+	APIURL              string
+	AccessToken         SecretString
+	ChannelIDs          []string
+	ReplyFailureDetails bool
+}
+
+var _ Provider = (*Mattermost)(nil)
+
+func NewMattermost(ctx context.Context, p *v1alpha1.Provider, k client.Client) (*Mattermost, *ProviderError) {
+	s := p.Spec.Mattermost
+	if s == nil {
+		return nil, NewInvalidProviderSpecError("missing value .mattermost")
+	}
+	if len(s.BaseURL) < 1 {
+		return nil, NewInvalidProviderSpecError("missing value .mattermost.baseURL")
+	}
+	if s.AccessToken.SecretRef == nil {
+		return nil, NewInvalidProviderSpecError("missing valid values in .accessToken")
+	}
+	token, perr := getSecretValue(ctx, k, p.Namespace, s.AccessToken.SecretRef, "access-token")
+	if perr != nil {
+		return nil, perr
+	}
+	return &Mattermost{
+		APIURL:              strings.TrimSuffix(s.BaseURL, "/") + "/api/v4",
+		AccessToken:         NewSecretString(strings.TrimSpace(string(token))),
+		ChannelIDs:          s.ChannelIDs,
+		ReplyFailureDetails: s.ReplyFailureDetails,
+	}, nil
+}
+
+type mattermostPost struct {
+	ID        string               `json:"id,omitempty"`
+	ChannelID string               `json:"channel_id"`
+	RootID    string               `json:"root_id,omitempty"`
+	Message   string               `json:"message,omitempty"`
+	Props     *mattermostPostProps `json:"props,omitempty"`
+}
+
+type mattermostPostProps struct {
+	Attachments []chatAttachment `json:"attachments"`
+}
+
+type mattermostErrorResponse struct {
+	ID      string `json:"id"`
+	Message string `json:"message"`
+}
+
+func (a *Mattermost) Notify(ctx context.Context, pr *pipelinesv1beta1.PipelineRun) *ProviderError {
+	log := logr.FromContext(ctx).WithName("providers.mattermost").
+		WithValues("providerType", "Mattermost", "pipelineRun", pr.Name)
+	cond := pr.Status.GetCondition(apis.ConditionSucceeded)
+	if cond == nil {
+		log.V(1).Info("PipelineRun has not condition, ignored")
+		return NewSkippedError("PipelineRun has not condition")
+	}
+	if cond.Status == corev1.ConditionUnknown {
+		log.V(2).Info("this run is not finished yet, skipped")
+		return NewSkippedError("this run is not finished yet")
+	}
+	m := newChatMessage(pr)
+	details := ""
+	if cond.Status == corev1.ConditionFalse && a.ReplyFailureDetails {
+		details = formatFailureDetails(m.FailedTasks, "**%s**")
+	}
+	for _, channel := range a.ChannelIDs {
+		post, perr := a.createPost(ctx, &mattermostPost{
+			ChannelID: channel,
+			Props: &mattermostPostProps{
+				Attachments: []chatAttachment{newChatAttachment(m)},
+			},
+		})
+		if perr != nil {
+			return perr
+		}
+		log.V(2).Info("post message", "postID", post.ID)
+		if len(details) < 1 {
+			continue
+		}
+		// the root_id makes the post a reply in the thread of the message.
+		if _, perr := a.createPost(ctx, &mattermostPost{
+			ChannelID: channel,
+			RootID:    post.ID,
+			Message:   details,
+		}); perr != nil {
+			return perr
+		}
+		log.V(2).Info("reply", "postID", post.ID)
+	}
+	return nil
+}
+
+func (a *Mattermost) createPost(ctx context.Context, payload *mattermostPost) (*mattermostPost, *ProviderError) {
+	bearer := fmt.Sprintf("Bearer %s", a.AccessToken.GetNoRedactedString())
+	b, err := postHTTP(ctx, a.APIURL+"/posts", bearer, payload)
+	if err != nil {
+		return nil, newMattermostError(payload.ChannelID, err)
+	}
+	var r mattermostPost
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, NewRuntimeError(fmt.Sprintf("failed to unmarshal Mattermost response message: %v", err))
+	}
+	return &r, nil
+}
+
+// newMattermostError converts the error response, which has the ID of the error and the message.
+func newMattermostError(channel string, err error) *ProviderError {
+	var serr *httpStatusError
+	if errors.As(err, &serr) && !serr.RateLimited {
+		var r mattermostErrorResponse
+		_ = json.Unmarshal([]byte(serr.Body), &r)
+		switch serr.StatusCode {
+		case http.StatusNotFound:
+			return NewChannelNotFoundError(fmt.Sprintf("Mattermost channel %s: %s", channel, r.Message))
+		case http.StatusForbidden:
+			return NewNotInChannelError(fmt.Sprintf("Mattermost channel %s: %s", channel, r.Message))
+		}
+	}
+	return newHTTPError("Mattermost", err)
+}
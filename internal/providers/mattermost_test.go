@@ -0,0 +1,225 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
+)
+
+func TestNewMattermost(t *testing.T) {
+	k := fakeclient.NewClientBuilder().
+		WithObjects(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "secret",
+				Namespace: "default",
+			},
+			Data: map[string][]byte{
+				"access-token": []byte("token\n"),
+			},
+		}).
+		Build()
+	for _, c := range []struct {
+		name    string
+		spec    *v1alpha1.MattermostSpec
+		wantErr *ProviderError
+	}{
+		{
+			name: "Basic",
+			spec: &v1alpha1.MattermostSpec{
+				BaseURL: "https://mattermost.example.com/",
+				AccessToken: v1alpha1.AccessTokenSource{
+					SecretRef: &v1alpha1.LocalSecretKeyReference{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "secret"},
+					},
+				},
+				ChannelIDs: []string{"channel"},
+			},
+		},
+		{
+			name: "SecretNotFound",
+			spec: &v1alpha1.MattermostSpec{
+				BaseURL: "https://mattermost.example.com",
+				AccessToken: v1alpha1.AccessTokenSource{
+					SecretRef: &v1alpha1.LocalSecretKeyReference{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "not-exists-secret"},
+					},
+				},
+				ChannelIDs: []string{"channel"},
+			},
+			wantErr: NewNotFoundPrivateKeyError(""),
+		},
+		{
+			name: "BaseURLNotFound",
+			spec: &v1alpha1.MattermostSpec{
+				AccessToken: v1alpha1.AccessTokenSource{
+					SecretRef: &v1alpha1.LocalSecretKeyReference{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "secret"},
+					},
+				},
+				ChannelIDs: []string{"channel"},
+			},
+			wantErr: NewInvalidProviderSpecError(""),
+		},
+		{
+			name:    "MattermostSpecNotFound",
+			wantErr: NewInvalidProviderSpecError(""),
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			p := &v1alpha1.Provider{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "provider",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.ProviderSpec{
+					Type:       "Mattermost",
+					Mattermost: c.spec,
+				},
+			}
+			a, err := NewMattermost(ctx, p, k)
+			if c.wantErr != nil {
+				if assert.NotNil(t, err) {
+					assert.Equal(t, c.wantErr.Code, err.Code)
+				}
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, "https://mattermost.example.com/api/v4", a.APIURL)
+			assert.Equal(t, "token", a.AccessToken.GetNoRedactedString())
+		})
+	}
+}
+
+func TestMattermostNotify(t *testing.T) {
+	attachment := `{
+		"fallback": "Failed: sample-run.default",
+		"color": "#A30100",
+		"title": "sample-run.default",
+		"title_link": "https://dashboard.example.com/#/namespaces/default/pipelineruns/sample-run",
+		"text": "Failed: Tasks Completed: 1 (Failed: 1, Cancelled 0), Skipped: 0",
+		"fields": [
+			{"title": "Namespace", "value": "default", "short": true},
+			{"title": "Pipeline", "value": "sample", "short": true},
+			{"title": "Duration", "value": "1m0s", "short": true},
+			{"title": "Reason", "value": "Failed", "short": true}
+		]
+	}`
+	for _, c := range []struct {
+		name        string
+		status      corev1.ConditionStatus
+		reply       bool
+		respCode    int
+		want        []string
+		wantErr     *ProviderError
+		wantSkipped bool
+	}{
+		{
+			name:     "Failed",
+			status:   corev1.ConditionFalse,
+			respCode: http.StatusCreated,
+			want: []string{
+				`{"channel_id": "C1", "props": {"attachments": [` + attachment + `]}}`,
+			},
+		},
+		{
+			name:     "ReplyFailureDetails",
+			status:   corev1.ConditionFalse,
+			reply:    true,
+			respCode: http.StatusCreated,
+			want: []string{
+				`{"channel_id": "C1", "props": {"attachments": [` + attachment + `]}}`,
+				`{"channel_id": "C1", "root_id": "P1", "message": "**build** (sample-run-build) Failed: Tasks Completed: 1 (Failed: 1, Cancelled 0), Skipped: 0"}`,
+			},
+		},
+		{
+			name:        "Running",
+			wantSkipped: true,
+			status:      corev1.ConditionUnknown,
+		},
+		{
+			name:     "NotInChannel",
+			status:   corev1.ConditionTrue,
+			respCode: http.StatusForbidden,
+			wantErr:  NewNotInChannelError(""),
+		},
+		{
+			name:     "ChannelNotFound",
+			status:   corev1.ConditionTrue,
+			respCode: http.StatusNotFound,
+			wantErr:  NewChannelNotFoundError(""),
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			var bodies []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/api/v4/posts", r.URL.Path)
+				assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+				var body json.RawMessage
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("failed to decode request: %v", err)
+				}
+				bodies = append(bodies, string(body))
+				w.WriteHeader(c.respCode)
+				if c.respCode != http.StatusCreated {
+					w.Write([]byte(`{"id": "api.context.permissions.app_error", "message": "error", "status_code": 403}`))
+					return
+				}
+				fmt.Fprintf(w, `{"id": "P%d", "channel_id": "C1"}`, len(bodies))
+			}))
+			defer srv.Close()
+			pr := newTemplateSamplePipelineRun()
+			pr.Status.Conditions[0].Status = c.status
+			a := &Mattermost{
+				APIURL:              srv.URL + "/api/v4",
+				AccessToken:         NewSecretString("token"),
+				ChannelIDs:          []string{"C1"},
+				ReplyFailureDetails: c.reply,
+			}
+			err := a.Notify(ctx, pr)
+			if c.wantErr != nil {
+				if assert.NotNil(t, err) {
+					assert.Equal(t, c.wantErr.Code, err.Code)
+				}
+				return
+			}
+			if c.wantSkipped {
+				assert.True(t, err.IsSkipped())
+			} else {
+				assert.Nil(t, err)
+			}
+			if assert.Len(t, bodies, len(c.want)) {
+				for i := range c.want {
+					assert.JSONEq(t, c.want[i], bodies[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"knative.dev/pkg/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/go-logr/logr"
+	pipelinesv1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+
+	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
+)
+
+const (
+	adaptiveCardContentType = "application/vnd.microsoft.card.adaptive"
+	adaptiveCardSchema      = "http://adaptivecards.io/schemas/adaptive-card.json"
+	adaptiveCardVersion     = "1.4"
+)
+
+// MicrosoftTeams posts the Adaptive Cards to a Microsoft Teams incoming webhook or Workflows.
+type MicrosoftTeams struct {
+	URL SecretString
+}
+
+var _ Provider = (*MicrosoftTeams)(nil)
+
+func NewMicrosoftTeams(ctx context.Context, p *v1alpha1.Provider, k client.Client) (*MicrosoftTeams, *ProviderError) {
+	s := p.Spec.MicrosoftTeams
+	if s == nil {
+		return nil, NewInvalidProviderSpecError("missing value .microsoftTeams")
+	}
+	u, perr := getWebhookURL(ctx, k, p.Namespace, s.URL)
+	if perr != nil {
+		return nil, perr
+	}
+	return &MicrosoftTeams{
+		URL: u,
+	}, nil
+}
+
+func (a *MicrosoftTeams) Notify(ctx context.Context, pr *pipelinesv1beta1.PipelineRun) *ProviderError {
+	log := logr.FromContext(ctx).WithName("providers.microsoftteams").
+		WithValues("providerType", "MicrosoftTeams", "pipelineRun", pr.Name)
+	cond := pr.Status.GetCondition(apis.ConditionSucceeded)
+	if cond == nil {
+		log.V(1).Info("PipelineRun has not condition, ignored")
+		return NewSkippedError("PipelineRun has not condition")
+	}
+	if cond.Status == corev1.ConditionUnknown {
+		log.V(2).Info("this run is not finished yet, skipped")
+		return NewSkippedError("this run is not finished yet")
+	}
+	payload := newTeamsMessageFromPipelineRun(pr)
+	if _, err := postHTTP(ctx, a.URL.GetNoRedactedString(), "", payload); err != nil {
+		return newWebhookError("Microsoft Teams", err)
+	}
+	log.V(2).Info("post message")
+	return nil
+}
+
+type teamsMessage struct {
+	Type        string            `json:"type"`
+	Attachments []teamsAttachment `json:"attachments"`
+}
+
+type teamsAttachment struct {
+	ContentType string       `json:"contentType"`
+	Content     adaptiveCard `json:"content"`
+}
+
+type adaptiveCard struct {
+	Schema  string                `json:"$schema"`
+	Type    string                `json:"type"`
+	Version string                `json:"version"`
+	Body    []adaptiveCardElement `json:"body"`
+	Actions []adaptiveCardAction  `json:"actions,omitempty"`
+	MSTeams *adaptiveCardMSTeams  `json:"msteams,omitempty"`
+}
+
+type adaptiveCardElement struct {
+	Type   string                `json:"type"`
+	Text   string                `json:"text,omitempty"`
+	Size   string                `json:"size,omitempty"`
+	Weight string                `json:"weight,omitempty"`
+	Color  string                `json:"color,omitempty"`
+	Wrap   bool                  `json:"wrap,omitempty"`
+	Style  string                `json:"style,omitempty"`
+	Bleed  bool                  `json:"bleed,omitempty"`
+	Items  []adaptiveCardElement `json:"items,omitempty"`
+	Facts  []adaptiveCardFact    `json:"facts,omitempty"`
+}
+
+type adaptiveCardFact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+type adaptiveCardAction struct {
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+type adaptiveCardMSTeams struct {
+	Width string `json:"width"`
+}
+
+func newTeamsMessageFromPipelineRun(pr *pipelinesv1beta1.PipelineRun) *teamsMessage {
+	m := newChatMessage(pr)
+	style, color := getAdaptiveCardColor(m.Status)
+	body := []adaptiveCardElement{
+		{
+			Type:  "Container",
+			Style: style,
+			Bleed: true,
+			Items: []adaptiveCardElement{
+				{
+					Type:   "TextBlock",
+					Text:   m.Title,
+					Size:   "Medium",
+					Weight: "Bolder",
+					Wrap:   true,
+				},
+				{
+					Type:   "TextBlock",
+					Text:   m.Reason,
+					Color:  color,
+					Weight: "Bolder",
+				},
+			},
+		},
+	}
+	if len(m.Message) > 0 {
+		body = append(body, adaptiveCardElement{
+			Type: "TextBlock",
+			Text: m.Message,
+			Wrap: true,
+		})
+	}
+	var facts []adaptiveCardFact
+	for _, f := range m.Facts() {
+		facts = append(facts, adaptiveCardFact{Title: f.Title, Value: f.Value})
+	}
+	body = append(body, adaptiveCardElement{
+		Type:  "FactSet",
+		Facts: facts,
+	})
+	card := adaptiveCard{
+		Schema:  adaptiveCardSchema,
+		Type:    "AdaptiveCard",
+		Version: adaptiveCardVersion,
+		Body:    body,
+		MSTeams: &adaptiveCardMSTeams{Width: "Full"},
+	}
+	if len(m.DashboardURL) > 0 {
+		card.Actions = []adaptiveCardAction{
+			{Type: "Action.OpenUrl", Title: "Open Dashboard", URL: m.DashboardURL},
+		}
+	}
+	return &teamsMessage{
+		Type: "message",
+		Attachments: []teamsAttachment{
+			{ContentType: adaptiveCardContentType, Content: card},
+		},
+	}
+}
+
+// getAdaptiveCardColor returns the style of the container and the color of the text for the status.
+func getAdaptiveCardColor(s corev1.ConditionStatus) (string, string) {
+	switch s {
+	case corev1.ConditionUnknown:
+		return "warning", "Warning"
+	case corev1.ConditionTrue:
+		return "good", "Good"
+	}
+	return "attention", "Attention"
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
+)
+
+func TestNewMicrosoftTeams(t *testing.T) {
+	k := fakeclient.NewClientBuilder().
+		WithObjects(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "secret",
+				Namespace: "default",
+			},
+			Data: map[string][]byte{
+				"url": []byte("https://example.webhook.office.com/webhookb2/xxxx"),
+			},
+		}).
+		Build()
+	for _, c := range []struct {
+		name    string
+		spec    *v1alpha1.MicrosoftTeamsSpec
+		wantErr *ProviderError
+	}{
+		{
+			name: "Basic",
+			spec: &v1alpha1.MicrosoftTeamsSpec{
+				URL: v1alpha1.WebhookURLSource{
+					SecretRef: &v1alpha1.LocalSecretKeyReference{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "secret"},
+					},
+				},
+			},
+		},
+		{
+			name: "SecretNotFound",
+			spec: &v1alpha1.MicrosoftTeamsSpec{
+				URL: v1alpha1.WebhookURLSource{
+					SecretRef: &v1alpha1.LocalSecretKeyReference{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "not-exists-secret"},
+					},
+				},
+			},
+			wantErr: NewNotFoundPrivateKeyError(""),
+		},
+		{
+			name:    "MicrosoftTeamsSpecNotFound",
+			wantErr: NewInvalidProviderSpecError(""),
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			p := &v1alpha1.Provider{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "provider",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.ProviderSpec{
+					Type:           "MicrosoftTeams",
+					MicrosoftTeams: c.spec,
+				},
+			}
+			a, err := NewMicrosoftTeams(ctx, p, k)
+			if c.wantErr != nil {
+				if assert.NotNil(t, err) {
+					assert.Equal(t, c.wantErr.Code, err.Code)
+				}
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, "https://example.webhook.office.com/webhookb2/xxxx", a.URL.GetNoRedactedString())
+		})
+	}
+}
+
+func TestMicrosoftTeamsNotify(t *testing.T) {
+	for _, c := range []struct {
+		name        string
+		status      corev1.ConditionStatus
+		respCode    int
+		want        string
+		wantErr     *ProviderError
+		wantSkipped bool
+	}{
+		{
+			name:     "Failed",
+			status:   corev1.ConditionFalse,
+			respCode: http.StatusOK,
+			want: `{
+				"type": "message",
+				"attachments": [{
+					"contentType": "application/vnd.microsoft.card.adaptive",
+					"content": {
+						"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+						"type": "AdaptiveCard",
+						"version": "1.4",
+						"body": [
+							{"type": "Container", "style": "attention", "bleed": true, "items": [
+								{"type": "TextBlock", "text": "sample-run.default", "size": "Medium", "weight": "Bolder", "wrap": true},
+								{"type": "TextBlock", "text": "Failed", "color": "Attention", "weight": "Bolder"}
+							]},
+							{"type": "TextBlock", "text": "Tasks Completed: 1 (Failed: 1, Cancelled 0), Skipped: 0", "wrap": true},
+							{"type": "FactSet", "facts": [
+								{"title": "Namespace", "value": "default"},
+								{"title": "Pipeline", "value": "sample"},
+								{"title": "Duration", "value": "1m0s"},
+								{"title": "Reason", "value": "Failed"}
+							]}
+						],
+						"actions": [
+							{"type": "Action.OpenUrl", "title": "Open Dashboard", "url": "https://dashboard.example.com/#/namespaces/default/pipelineruns/sample-run"}
+						],
+						"msteams": {"width": "Full"}
+					}
+				}]
+			}`,
+		},
+		{
+			name:        "Running",
+			wantSkipped: true,
+			status:      corev1.ConditionUnknown,
+		},
+		{
+			name:     "BadRequest",
+			status:   corev1.ConditionTrue,
+			respCode: http.StatusBadRequest,
+			wantErr:  NewRuntimeError(""),
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			var bodies []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/webhookb2/xxxx", r.URL.Path)
+				var body json.RawMessage
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("failed to decode request: %v", err)
+				}
+				bodies = append(bodies, string(body))
+				w.WriteHeader(c.respCode)
+				w.Write([]byte("1"))
+			}))
+			defer srv.Close()
+			pr := newTemplateSamplePipelineRun()
+			pr.Status.Conditions[0].Status = c.status
+			a := &MicrosoftTeams{
+				URL: NewSecretString(srv.URL + "/webhookb2/xxxx"),
+			}
+			err := a.Notify(ctx, pr)
+			if c.wantErr != nil {
+				if assert.NotNil(t, err) {
+					assert.Equal(t, c.wantErr.Code, err.Code)
+					assert.NotContains(t, err.Message, srv.URL)
+				}
+				return
+			}
+			if c.wantSkipped {
+				assert.True(t, err.IsSkipped())
+			} else {
+				assert.Nil(t, err)
+			}
+			if len(c.want) < 1 {
+				assert.Empty(t, bodies)
+				return
+			}
+			if assert.Len(t, bodies, 1) {
+				assert.JSONEq(t, c.want, bodies[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,253 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"knative.dev/pkg/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/go-logr/logr"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nkeys"
+	pipelinesv1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+
+	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
+)
+
+const (
+	natsClientName = "tekton-integration"
+	// natsDefaultSubject falls back to the name of the run without the pipeline,
+	// and replaces the dots in the names which separate the tokens.
+	natsDefaultSubject = `tekton.{{ .Namespace }}.{{ or .Pipeline .Name | replace "." "_" }}.{{ .Transition }}`
+)
+
+// NATS publishes a message per transition of the run to the subject.
+type NATS struct {
+	Servers   []string
+	Subject   *template.Template
+	Encoding  string
+	JetStream bool
+	// Credentials is the credentials file of the user, or empty.
+	Credentials SecretBytes
+	// NKeySeed is the NKEY seed of the user, or empty.
+	NKeySeed SecretBytes
+}
+
+var _ Provider = (*NATS)(nil)
+var _ Validator = (*NATS)(nil)
+
+func NewNATS(ctx context.Context, p *v1alpha1.Provider, k client.Client) (*NATS, *ProviderError) {
+	s := p.Spec.NATS
+	if s == nil {
+		return nil, NewInvalidProviderSpecError("missing value .nats")
+	}
+	if len(s.Servers) < 1 {
+		return nil, NewInvalidProviderSpecError("missing value .nats.servers")
+	}
+	if s.CredentialsSecretRef != nil && s.NKeySecretRef != nil {
+		return nil, NewInvalidProviderSpecError("credentialsSecretRef and nkeySecretRef are exclusive")
+	}
+	a := &NATS{
+		Servers:   s.Servers,
+		Encoding:  runEventEncodingJSON,
+		JetStream: s.JetStream,
+	}
+	subject := natsDefaultSubject
+	if s.Subject != nil && len(*s.Subject) > 0 {
+		subject = *s.Subject
+	}
+	t, perr := parseTemplate("subject", subject)
+	if perr != nil {
+		return nil, perr
+	}
+	a.Subject = t
+	if s.Encoding != nil && len(*s.Encoding) > 0 {
+		if !isRunEventEncoding(*s.Encoding) {
+			return nil, NewInvalidProviderSpecError(fmt.Sprintf("unknown encoding: %s", *s.Encoding))
+		}
+		a.Encoding = *s.Encoding
+	}
+	if s.CredentialsSecretRef != nil {
+		creds, perr := getSecretValue(ctx, k, p.Namespace, s.CredentialsSecretRef, "user.creds")
+		if perr != nil {
+			return nil, perr
+		}
+		a.Credentials = NewSecretBytes(creds)
+	}
+	if s.NKeySecretRef != nil {
+		seed, perr := getSecretValue(ctx, k, p.Namespace, s.NKeySecretRef, "seed")
+		if perr != nil {
+			return nil, perr
+		}
+		a.NKeySeed = NewSecretBytes(seed)
+	}
+	if _, perr := a.newOptions(); perr != nil {
+		return nil, perr
+	}
+	return a, nil
+}
+
+// newOptions returns the options of the connection with the credentials.
+func (a *NATS) newOptions() ([]nats.Option, *ProviderError) {
+	opts := []nats.Option{nats.Name(natsClientName)}
+	if creds := a.Credentials.GetNoRedacted(); len(creds) > 0 {
+		jwt, err := nkeys.ParseDecoratedJWT(creds)
+		if err != nil {
+			return nil, NewFailedValidationError(fmt.Sprintf("invalid credentials file: %v", err))
+		}
+		kp, err := nkeys.ParseDecoratedNKey(creds)
+		if err != nil {
+			return nil, NewFailedValidationError(fmt.Sprintf("invalid credentials file: %v", err))
+		}
+		opts = append(opts, nats.UserJWT(
+			func() (string, error) { return jwt, nil },
+			kp.Sign,
+		))
+	}
+	if seed := a.NKeySeed.GetNoRedacted(); len(seed) > 0 {
+		kp, err := nkeys.FromSeed([]byte(strings.TrimSpace(string(seed))))
+		if err != nil {
+			return nil, NewFailedValidationError(fmt.Sprintf("invalid NKEY seed: %v", err))
+		}
+		pub, err := kp.PublicKey()
+		if err != nil {
+			return nil, NewFailedValidationError(fmt.Sprintf("invalid NKEY seed: %v", err))
+		}
+		opts = append(opts, nats.Nkey(pub, kp.Sign))
+	}
+	return opts, nil
+}
+
+// withNATSTimeout returns the context with the default timeout of NATS unless it has the deadline,
+// since the context of the requests to NATS requires the deadline.
+func withNATSTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, nats.DefaultTimeout)
+}
+
+// connect connects to the servers in the deadline of the context.
+func (a *NATS) connect(ctx context.Context) (*nats.Conn, *ProviderError) {
+	opts, perr := a.newOptions()
+	if perr != nil {
+		return nil, perr
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		opts = append(opts, nats.Timeout(time.Until(deadline)))
+	}
+	nc, err := nats.Connect(strings.Join(a.Servers, ","), opts...)
+	if err != nil {
+		return nil, NewRuntimeError(fmt.Sprintf("failed to connect to NATS: %v", err))
+	}
+	return nc, nil
+}
+
+func (a *NATS) Notify(ctx context.Context, pr *pipelinesv1beta1.PipelineRun) *ProviderError {
+	log := logr.FromContext(ctx).WithName("providers.nats").
+		WithValues("providerType", "NATS", "pipelineRun", pr.Name)
+	cond := pr.Status.GetCondition(apis.ConditionSucceeded)
+	if cond == nil {
+		log.V(1).Info("PipelineRun has not condition, ignored")
+		return NewSkippedError("PipelineRun has not condition")
+	}
+	msg, perr := a.newMessage(pr)
+	if perr != nil {
+		return perr
+	}
+	ctx, cancel := withNATSTimeout(ctx)
+	defer cancel()
+	nc, perr := a.connect(ctx)
+	if perr != nil {
+		return perr
+	}
+	defer nc.Close()
+	if a.JetStream {
+		js, err := nc.JetStream()
+		if err != nil {
+			return NewRuntimeError(fmt.Sprintf("failed to use JetStream: %v", err))
+		}
+		ack, err := js.PublishMsg(msg, nats.Context(ctx))
+		if err != nil {
+			return NewRuntimeError(fmt.Sprintf("failed to publish to JetStream: %v", err))
+		}
+		log.V(2).Info("publish message", "subject", msg.Subject, "stream", ack.Stream, "sequence", ack.Sequence, "duplicate", ack.Duplicate)
+		return nil
+	}
+	if err := nc.PublishMsg(msg); err != nil {
+		return NewRuntimeError(fmt.Sprintf("failed to publish to NATS: %v", err))
+	}
+	if err := nc.FlushWithContext(ctx); err != nil {
+		return NewRuntimeError(fmt.Sprintf("failed to publish to NATS: %v", err))
+	}
+	log.V(2).Info("publish message", "subject", msg.Subject)
+	return nil
+}
+
+// Validate renders the subject with the sample run, and connects to the servers.
+func (a *NATS) Validate(ctx context.Context) *ProviderError {
+	if _, perr := a.newSubject(newTemplateSamplePipelineRun()); perr != nil {
+		return perr
+	}
+	ctx, cancel := withNATSTimeout(ctx)
+	defer cancel()
+	nc, perr := a.connect(ctx)
+	if perr != nil {
+		return perr
+	}
+	nc.Close()
+	return nil
+}
+
+// newMessage returns the message of the transition of the run.
+// The Nats-Msg-Id header is unique per run and transition, by which JetStream deduplicates the messages.
+func (a *NATS) newMessage(pr *pipelinesv1beta1.PipelineRun) (*nats.Msg, *ProviderError) {
+	subject, perr := a.newSubject(pr)
+	if perr != nil {
+		return nil, perr
+	}
+	data, contentType, perr := encodeRunEvent(pr, a.Encoding)
+	if perr != nil {
+		return nil, perr
+	}
+	msg := nats.NewMsg(subject)
+	msg.Data = data
+	msg.Header.Set("Content-Type", contentType)
+	msg.Header.Set(nats.MsgIdHdr, getRunEventID(pr, getRunTransition(pr.Status.GetCondition(apis.ConditionSucceeded))))
+	return msg, nil
+}
+
+// newSubject renders the subject, which must consist of the non-empty tokens without the wildcards.
+func (a *NATS) newSubject(pr *pipelinesv1beta1.PipelineRun) (string, *ProviderError) {
+	b, perr := executeTemplate(a.Subject, pr)
+	if perr != nil {
+		return "", perr
+	}
+	subject := strings.TrimSpace(string(b))
+	for _, token := range strings.Split(subject, ".") {
+		if len(token) < 1 || strings.ContainsAny(token, " \t\r\n*>") {
+			return "", NewFailedValidationError(fmt.Sprintf("invalid subject: %q", subject))
+		}
+	}
+	return subject, nil
+}
@@ -0,0 +1,394 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/nats-io/jwt/v2"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nkeys"
+	"github.com/stretchr/testify/assert"
+	pipelinesv1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
+)
+
+// runNATSServer runs the embedded NATS server with JetStream.
+func runNATSServer(t *testing.T, opts *server.Options) *server.Server {
+	opts.Host = "127.0.0.1"
+	opts.Port = -1
+	opts.NoLog = true
+	opts.NoSigs = true
+	opts.JetStream = true
+	opts.StoreDir = t.TempDir()
+	s, err := server.NewServer(opts)
+	if err != nil {
+		t.Fatalf("failed to create NATS server: %v", err)
+	}
+	go s.Start()
+	if !s.ReadyForConnections(5 * time.Second) {
+		t.Fatal("NATS server is not ready")
+	}
+	t.Cleanup(s.Shutdown)
+	return s
+}
+
+func newNATSTestUser(t *testing.T) (nkeys.KeyPair, string, []byte) {
+	user, err := nkeys.CreateUser()
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	pub, err := user.PublicKey()
+	if err != nil {
+		t.Fatalf("failed to get public key: %v", err)
+	}
+	seed, err := user.Seed()
+	if err != nil {
+		t.Fatalf("failed to get seed: %v", err)
+	}
+	return user, pub, seed
+}
+
+func TestNewNATS(t *testing.T) {
+	_, pub, seed := newNATSTestUser(t)
+	account, err := nkeys.CreateAccount()
+	if err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+	token, err := jwt.NewUserClaims(pub).Encode(account)
+	if err != nil {
+		t.Fatalf("failed to encode JWT: %v", err)
+	}
+	creds, err := jwt.FormatUserConfig(token, seed)
+	if err != nil {
+		t.Fatalf("failed to format credentials: %v", err)
+	}
+	k := fakeclient.NewClientBuilder().
+		WithObjects(
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "nats", Namespace: "default"},
+				Data: map[string][]byte{
+					"user.creds": creds,
+					"seed":       seed,
+					"invalid":    []byte("invalid"),
+				},
+			},
+		).
+		Build()
+	secretRef := func(key *string) *v1alpha1.LocalSecretKeyReference {
+		return &v1alpha1.LocalSecretKeyReference{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "nats"},
+			Key:                  key,
+		}
+	}
+	for _, c := range []struct {
+		name         string
+		spec         *v1alpha1.NATSSpec
+		wantEncoding string
+		wantOptions  int
+		wantErr      *ProviderError
+	}{
+		{
+			name:         "Basic",
+			spec:         &v1alpha1.NATSSpec{Servers: []string{"nats://nats:4222"}},
+			wantEncoding: "JSON",
+			wantOptions:  1,
+		},
+		{
+			name: "Credentials",
+			spec: &v1alpha1.NATSSpec{
+				Servers:              []string{"nats://nats:4222"},
+				Encoding:             pointer.String("CloudEvents"),
+				JetStream:            true,
+				CredentialsSecretRef: secretRef(nil),
+			},
+			wantEncoding: "CloudEvents",
+			wantOptions:  2,
+		},
+		{
+			name: "NKey",
+			spec: &v1alpha1.NATSSpec{
+				Servers:       []string{"nats://nats:4222"},
+				NKeySecretRef: secretRef(nil),
+			},
+			wantEncoding: "JSON",
+			wantOptions:  2,
+		},
+		{
+			name: "InvalidCredentials",
+			spec: &v1alpha1.NATSSpec{
+				Servers:              []string{"nats://nats:4222"},
+				CredentialsSecretRef: secretRef(pointer.String("invalid")),
+			},
+			wantErr: NewFailedValidationError(""),
+		},
+		{
+			name: "InvalidNKey",
+			spec: &v1alpha1.NATSSpec{
+				Servers:       []string{"nats://nats:4222"},
+				NKeySecretRef: secretRef(pointer.String("invalid")),
+			},
+			wantErr: NewFailedValidationError(""),
+		},
+		{
+			name: "ExclusiveCredentials",
+			spec: &v1alpha1.NATSSpec{
+				Servers:              []string{"nats://nats:4222"},
+				CredentialsSecretRef: secretRef(nil),
+				NKeySecretRef:        secretRef(nil),
+			},
+			wantErr: NewInvalidProviderSpecError(""),
+		},
+		{
+			name: "MissingKey",
+			spec: &v1alpha1.NATSSpec{
+				Servers:       []string{"nats://nats:4222"},
+				NKeySecretRef: secretRef(pointer.String("not-exists")),
+			},
+			wantErr: NewNotFoundPrivateKeyError(""),
+		},
+		{
+			name: "InvalidSubject",
+			spec: &v1alpha1.NATSSpec{
+				Servers: []string{"nats://nats:4222"},
+				Subject: pointer.String("tekton.{{ .Name"),
+			},
+			wantErr: NewInvalidProviderSpecError(""),
+		},
+		{
+			name:    "MissingServers",
+			spec:    &v1alpha1.NATSSpec{},
+			wantErr: NewInvalidProviderSpecError(""),
+		},
+		{
+			name:    "NATSSpecNotFound",
+			wantErr: NewInvalidProviderSpecError(""),
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			p := &v1alpha1.Provider{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "provider",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.ProviderSpec{
+					Type: "NATS",
+					NATS: c.spec,
+				},
+			}
+			a, err := NewNATS(ctx, p, k)
+			if c.wantErr != nil {
+				if assert.NotNil(t, err) {
+					assert.Equal(t, c.wantErr.Code, err.Code)
+				}
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, c.wantEncoding, a.Encoding)
+			opts, err := a.newOptions()
+			assert.Nil(t, err)
+			assert.Len(t, opts, c.wantOptions)
+		})
+	}
+}
+
+func TestNATSNotify(t *testing.T) {
+	s := runNATSServer(t, &server.Options{})
+	nc, err := nats.Connect(s.ClientURL())
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer nc.Close()
+	sub, err := nc.SubscribeSync("tekton.>")
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	tmpl, perr := parseTemplate("subject", natsDefaultSubject)
+	if !assert.Nil(t, perr) {
+		return
+	}
+	a := &NATS{
+		Servers:  []string{s.ClientURL()},
+		Subject:  tmpl,
+		Encoding: "CloudEvents",
+	}
+	pr := newTemplateSamplePipelineRun()
+	pr.UID = "6f1f2a60"
+	if !assert.Nil(t, a.Notify(ctx, pr)) {
+		return
+	}
+	msg, err := sub.NextMsg(5 * time.Second)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, "tekton.default.sample.failed", msg.Subject)
+	assert.Equal(t, "application/cloudevents+json", msg.Header.Get("Content-Type"))
+	assert.Equal(t, "6f1f2a60-failed", msg.Header.Get(nats.MsgIdHdr))
+	var event cloudEvent
+	if assert.Nil(t, json.Unmarshal(msg.Data, &event)) {
+		assert.Equal(t, "6f1f2a60-failed", event.ID)
+		assert.Equal(t, "io.ornew.tekton.integrations.pipelinerun.failed", event.Type)
+	}
+
+	// the subject of the run without the pipeline has the name of the run.
+	pr.Spec.PipelineRef = nil
+	if assert.Nil(t, a.Notify(ctx, pr)) {
+		msg, err := sub.NextMsg(5 * time.Second)
+		if assert.Nil(t, err) {
+			assert.Equal(t, "tekton.default.sample-run.failed", msg.Subject)
+		}
+	}
+
+	// the dots in the name don't separate the tokens.
+	pr.Spec.PipelineRef = &pipelinesv1beta1.PipelineRef{Name: "sample.v2"}
+	if assert.Nil(t, a.Notify(ctx, pr)) {
+		msg, err := sub.NextMsg(5 * time.Second)
+		if assert.Nil(t, err) {
+			assert.Equal(t, "tekton.default.sample_v2.failed", msg.Subject)
+		}
+	}
+
+	// the subject with an empty token is invalid.
+	a.Subject, perr = parseTemplate("subject", "tekton.{{ .Pipeline }}")
+	if !assert.Nil(t, perr) {
+		return
+	}
+	pr.Spec.PipelineRef = nil
+	if err := a.Notify(ctx, pr); assert.NotNil(t, err) {
+		assert.Equal(t, NewFailedValidationError("").Code, err.Code)
+	}
+}
+
+func TestNATSNotifyTimeout(t *testing.T) {
+	tmpl, perr := parseTemplate("subject", natsDefaultSubject)
+	if !assert.Nil(t, perr) {
+		return
+	}
+	// the server accepts the connections, but never responds.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	a := &NATS{
+		Servers:  []string{"nats://" + l.Addr().String()},
+		Subject:  tmpl,
+		Encoding: "JSON",
+	}
+	tctx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := a.Notify(tctx, newTemplateSamplePipelineRun()); assert.NotNil(t, err) {
+		assert.Equal(t, NewRuntimeError("").Code, err.Code)
+	}
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+}
+
+func TestNATSNotifyJetStream(t *testing.T) {
+	s := runNATSServer(t, &server.Options{})
+	nc, err := nats.Connect(s.ClientURL())
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer nc.Close()
+	js, err := nc.JetStream()
+	if err != nil {
+		t.Fatalf("failed to use JetStream: %v", err)
+	}
+	tmpl, perr := parseTemplate("subject", natsDefaultSubject)
+	if !assert.Nil(t, perr) {
+		return
+	}
+	a := &NATS{
+		Servers:   []string{s.ClientURL()},
+		Subject:   tmpl,
+		Encoding:  "JSON",
+		JetStream: true,
+	}
+	pr := newTemplateSamplePipelineRun()
+	pr.UID = "6f1f2a60"
+
+	// no stream acknowledges the message.
+	if err := a.Notify(ctx, pr); assert.NotNil(t, err) {
+		assert.Equal(t, NewRuntimeError("").Code, err.Code)
+	}
+
+	if _, err := js.AddStream(&nats.StreamConfig{Name: "TEKTON", Subjects: []string{"tekton.>"}}); err != nil {
+		t.Fatalf("failed to add stream: %v", err)
+	}
+	// the transition sent again is deduplicated by Nats-Msg-Id.
+	assert.Nil(t, a.Notify(ctx, pr))
+	assert.Nil(t, a.Notify(ctx, pr))
+	pr.Status.Conditions[0].Status = corev1.ConditionTrue
+	pr.Status.Conditions[0].Reason = "Succeeded"
+	assert.Nil(t, a.Notify(ctx, pr))
+
+	info, err := js.StreamInfo("TEKTON")
+	if assert.Nil(t, err) {
+		assert.Equal(t, uint64(2), info.State.Msgs)
+	}
+	msg, err := js.GetMsg("TEKTON", 2)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "tekton.default.sample.succeeded", msg.Subject)
+		assert.Equal(t, "6f1f2a60-succeeded", msg.Header.Get(nats.MsgIdHdr))
+	}
+}
+
+func TestNATSNKey(t *testing.T) {
+	_, pub, seed := newNATSTestUser(t)
+	s := runNATSServer(t, &server.Options{
+		Nkeys: []*server.NkeyUser{{Nkey: pub}},
+	})
+	tmpl, perr := parseTemplate("subject", natsDefaultSubject)
+	if !assert.Nil(t, perr) {
+		return
+	}
+	a := &NATS{
+		Servers:  []string{s.ClientURL()},
+		Subject:  tmpl,
+		Encoding: "JSON",
+		NKeySeed: NewSecretBytes(seed),
+	}
+	assert.Nil(t, a.Validate(ctx))
+
+	_, _, other := newNATSTestUser(t)
+	a.NKeySeed = NewSecretBytes(other)
+	if err := a.Validate(ctx); assert.NotNil(t, err) {
+		assert.Equal(t, NewRuntimeError("").Code, err.Code)
+	}
+}
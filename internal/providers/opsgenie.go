@@ -0,0 +1,226 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"knative.dev/pkg/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/go-logr/logr"
+	pipelinesv1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+
+	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
+)
+
+const (
+	opsgenieDefaultPriority = "P3"
+	opsgenieSource          = "Tekton"
+)
+
+// The limits of the fields of the alerts.
+// See https://docs.opsgenie.com/docs/alert-api#create-alert
+const (
+	opsgenieMessageLimit     = 130
+	opsgenieDescriptionLimit = 15000
+	opsgenieTagLimit         = 50
+	opsgenieTagsLimit        = 20
+)
+
+var opsgenieBaseURLs = map[string]string{
+	"US": "https://api.opsgenie.com",
+	"EU": "https://api.eu.opsgenie.com",
+}
+
+var opsgeniePriorityPattern = regexp.MustCompile(`^P[1-5]$`)
+
+// Opsgenie creates the alerts of the failed pipelines, and closes them when the pipelines succeed.
+type Opsgenie struct {
+	BaseURL          string
+	APIKey           SecretBytes
+	Priority         string
+	ReasonPriorities map[string]string
+	Tags             []string
+	TagLabels        []string
+	Outcomes         *pipelineOutcomes
+}
+
+var _ Provider = (*Opsgenie)(nil)
+
+func NewOpsgenie(ctx context.Context, p *v1alpha1.Provider, k client.Client) (*Opsgenie, *ProviderError) {
+	s := p.Spec.Opsgenie
+	if s == nil {
+		return nil, NewInvalidProviderSpecError("missing value .opsgenie")
+	}
+	if s.APIKey.SecretRef == nil {
+		return nil, NewInvalidProviderSpecError("missing valid values in .apiKey")
+	}
+	baseURL := opsgenieBaseURLs["US"]
+	if s.Region != nil && len(*s.Region) > 0 {
+		u, ok := opsgenieBaseURLs[*s.Region]
+		if !ok {
+			return nil, NewInvalidProviderSpecError(fmt.Sprintf("unknown region: %s", *s.Region))
+		}
+		baseURL = u
+	}
+	if s.BaseURL != nil && len(*s.BaseURL) > 0 {
+		baseURL = strings.TrimSuffix(*s.BaseURL, "/")
+	}
+	priority := opsgenieDefaultPriority
+	if s.Priority != nil && len(*s.Priority) > 0 {
+		priority = *s.Priority
+	}
+	if !opsgeniePriorityPattern.MatchString(priority) {
+		return nil, NewInvalidProviderSpecError(fmt.Sprintf("invalid priority: %s", priority))
+	}
+	for reason, p := range s.ReasonPriorities {
+		if !opsgeniePriorityPattern.MatchString(p) {
+			return nil, NewInvalidProviderSpecError(fmt.Sprintf("invalid priority of the reason %s: %s", reason, p))
+		}
+	}
+	key, perr := getSecretValue(ctx, k, p.Namespace, s.APIKey.SecretRef, "api-key")
+	if perr != nil {
+		return nil, perr
+	}
+	return &Opsgenie{
+		BaseURL:          baseURL,
+		APIKey:           NewSecretBytes(key),
+		Priority:         priority,
+		ReasonPriorities: s.ReasonPriorities,
+		Tags:             s.Tags,
+		TagLabels:        s.TagLabels,
+		Outcomes:         newPipelineOutcomes(p, k),
+	}, nil
+}
+
+func (a *Opsgenie) Notify(ctx context.Context, pr *pipelinesv1beta1.PipelineRun) *ProviderError {
+	log := logr.FromContext(ctx).WithName("providers.opsgenie").
+		WithValues("providerType", "Opsgenie", "pipelineRun", pr.Name)
+	cond := pr.Status.GetCondition(apis.ConditionSucceeded)
+	if cond == nil {
+		log.V(1).Info("PipelineRun has not condition, ignored")
+		return NewSkippedError("PipelineRun has not condition")
+	}
+	if cond.Status == corev1.ConditionUnknown {
+		log.V(2).Info("this run is not finished yet, skipped")
+		return NewSkippedError("this run is not finished yet")
+	}
+	return notifyPipelineAlert(ctx, log, a, a.Outcomes, pr)
+}
+
+func (a *Opsgenie) triggerAlert(ctx context.Context, pr *pipelinesv1beta1.PipelineRun, pipeline string) *ProviderError {
+	return a.post(ctx, "/v2/alerts", a.newAlert(pr, pipeline))
+}
+
+func (a *Opsgenie) resolveAlert(ctx context.Context, pr *pipelinesv1beta1.PipelineRun, pipeline string) *ProviderError {
+	path := fmt.Sprintf("/v2/alerts/%s/close?identifierType=alias", url.PathEscape(getOpsgenieAlias(pipeline)))
+	return a.post(ctx, path, &opsgenieCloseAlertRequest{
+		Source: opsgenieSource,
+		Note:   fmt.Sprintf("Recovered by %s/%s", pr.Namespace, pr.Name),
+	})
+}
+
+func (a *Opsgenie) post(ctx context.Context, path string, payload interface{}) *ProviderError {
+	if _, err := postHTTP(ctx, a.BaseURL+path, "GenieKey "+a.APIKey.GetNoRedactedString(), payload); err != nil {
+		return newHTTPError("Opsgenie", err)
+	}
+	return nil
+}
+
+type opsgenieCreateAlertRequest struct {
+	Message     string            `json:"message"`
+	Alias       string            `json:"alias"`
+	Description string            `json:"description,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Details     map[string]string `json:"details,omitempty"`
+	Entity      string            `json:"entity,omitempty"`
+	Source      string            `json:"source,omitempty"`
+	Priority    string            `json:"priority,omitempty"`
+}
+
+type opsgenieCloseAlertRequest struct {
+	Source string `json:"source,omitempty"`
+	Note   string `json:"note,omitempty"`
+}
+
+// getOpsgenieAlias returns the alias of the alerts of the pipeline,
+// so that the failures of the pipeline are deduplicated into one open alert.
+func getOpsgenieAlias(pipeline string) string {
+	return "tekton/" + pipeline
+}
+
+func (a *Opsgenie) newAlert(pr *pipelinesv1beta1.PipelineRun, pipeline string) *opsgenieCreateAlertRequest {
+	m := newChatMessage(pr)
+	description := m.Message
+	if details := formatFailureDetails(m.FailedTasks, "%s"); len(details) > 0 {
+		description += "\n\n" + details
+	}
+	alert := &opsgenieCreateAlertRequest{
+		Message:     truncateRunes(m.Summary(), opsgenieMessageLimit),
+		Alias:       getOpsgenieAlias(pipeline),
+		Description: truncateRunes(description, opsgenieDescriptionLimit),
+		Tags:        a.getTags(pr),
+		Details: map[string]string{
+			"pipelineRun": pr.Name,
+			"namespace":   pr.Namespace,
+			"reason":      m.Reason,
+			"duration":    m.Duration,
+		},
+		Entity:   pipeline,
+		Source:   opsgenieSource,
+		Priority: a.Priority,
+	}
+	if p, ok := a.ReasonPriorities[m.Reason]; ok {
+		alert.Priority = p
+	}
+	if len(m.Pipeline) > 0 {
+		alert.Details["pipeline"] = m.Pipeline
+	}
+	if len(m.DashboardURL) > 0 {
+		alert.Details["dashboard"] = m.DashboardURL
+	}
+	return alert
+}
+
+// getTags returns the static tags and the labels of the run as key:value.
+// The tags are truncated to the limits, and the duplicated tags are removed.
+func (a *Opsgenie) getTags(pr *pipelinesv1beta1.PipelineRun) []string {
+	seen := map[string]bool{}
+	var tags []string
+	add := func(tag string) {
+		tag = truncateRunes(tag, opsgenieTagLimit)
+		if len(tag) > 0 && !seen[tag] && len(tags) < opsgenieTagsLimit {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	for _, tag := range a.Tags {
+		add(tag)
+	}
+	for _, key := range a.TagLabels {
+		if v, ok := pr.Labels[key]; ok {
+			add(fmt.Sprintf("%s:%s", key, v))
+		}
+	}
+	return tags
+}
@@ -0,0 +1,337 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
+)
+
+func TestNewOpsgenie(t *testing.T) {
+	k := fakeclient.NewClientBuilder().
+		WithObjects(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "secret",
+				Namespace: "default",
+			},
+			Data: map[string][]byte{
+				"api-key": []byte("4P1K3Y"),
+			},
+		}).
+		Build()
+	apiKey := v1alpha1.APIKeySource{
+		SecretRef: &v1alpha1.LocalSecretKeyReference{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "secret"},
+		},
+	}
+	for _, c := range []struct {
+		name         string
+		spec         *v1alpha1.OpsgenieSpec
+		wantBaseURL  string
+		wantPriority string
+		wantErr      *ProviderError
+	}{
+		{
+			name:         "Basic",
+			spec:         &v1alpha1.OpsgenieSpec{APIKey: apiKey},
+			wantBaseURL:  "https://api.opsgenie.com",
+			wantPriority: "P3",
+		},
+		{
+			name: "EU",
+			spec: &v1alpha1.OpsgenieSpec{
+				APIKey:   apiKey,
+				Region:   pointer.String("EU"),
+				Priority: pointer.String("P1"),
+			},
+			wantBaseURL:  "https://api.eu.opsgenie.com",
+			wantPriority: "P1",
+		},
+		{
+			name: "BaseURL",
+			spec: &v1alpha1.OpsgenieSpec{
+				APIKey:  apiKey,
+				Region:  pointer.String("EU"),
+				BaseURL: pointer.String("https://opsgenie.example.com/"),
+			},
+			wantBaseURL:  "https://opsgenie.example.com",
+			wantPriority: "P3",
+		},
+		{
+			name: "UnknownRegion",
+			spec: &v1alpha1.OpsgenieSpec{
+				APIKey: apiKey,
+				Region: pointer.String("JP"),
+			},
+			wantErr: NewInvalidProviderSpecError(""),
+		},
+		{
+			name: "InvalidReasonPriority",
+			spec: &v1alpha1.OpsgenieSpec{
+				APIKey:           apiKey,
+				ReasonPriorities: map[string]string{"PipelineRunTimeout": "P9"},
+			},
+			wantErr: NewInvalidProviderSpecError(""),
+		},
+		{
+			name: "SecretNotFound",
+			spec: &v1alpha1.OpsgenieSpec{
+				APIKey: v1alpha1.APIKeySource{
+					SecretRef: &v1alpha1.LocalSecretKeyReference{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "not-exists-secret"},
+					},
+				},
+			},
+			wantErr: NewNotFoundPrivateKeyError(""),
+		},
+		{
+			name:    "OpsgenieSpecNotFound",
+			wantErr: NewInvalidProviderSpecError(""),
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			p := &v1alpha1.Provider{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "provider",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.ProviderSpec{
+					Type:     "Opsgenie",
+					Opsgenie: c.spec,
+				},
+			}
+			a, err := NewOpsgenie(ctx, p, k)
+			if c.wantErr != nil {
+				if assert.NotNil(t, err) {
+					assert.Equal(t, c.wantErr.Code, err.Code)
+				}
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, "4P1K3Y", a.APIKey.GetNoRedactedString())
+			assert.Equal(t, c.wantBaseURL, a.BaseURL)
+			assert.Equal(t, c.wantPriority, a.Priority)
+		})
+	}
+}
+
+func TestOpsgenieNotify(t *testing.T) {
+	earlier := metav1.NewTime(time.Date(2021, 6, 30, 0, 0, 0, 0, time.UTC))
+	for _, c := range []struct {
+		name        string
+		status      corev1.ConditionStatus
+		reason      string
+		last        *v1alpha1.PipelineOutcome
+		respCode    int
+		wantPath    string
+		want        string
+		wantStatus  metav1.ConditionStatus
+		wantErr     *ProviderError
+		wantSkipped bool
+	}{
+		{
+			name:     "Create",
+			status:   corev1.ConditionFalse,
+			reason:   "Failed",
+			respCode: http.StatusAccepted,
+			wantPath: "/v2/alerts",
+			want: `{
+				"message": "Failed: sample-run.default",
+				"alias": "tekton/default/sample",
+				"description": "Tasks Completed: 1 (Failed: 1, Cancelled 0), Skipped: 0\n\nbuild (sample-run-build) Failed: Tasks Completed: 1 (Failed: 1, Cancelled 0), Skipped: 0",
+				"tags": ["tekton", "tekton.dev/pipeline:sample"],
+				"details": {
+					"pipelineRun": "sample-run",
+					"namespace": "default",
+					"pipeline": "sample",
+					"reason": "Failed",
+					"duration": "1m0s",
+					"dashboard": "https://dashboard.example.com/#/namespaces/default/pipelineruns/sample-run"
+				},
+				"entity": "default/sample",
+				"source": "Tekton",
+				"priority": "P3"
+			}`,
+			wantStatus: metav1.ConditionFalse,
+		},
+		{
+			name:     "ReasonPriority",
+			status:   corev1.ConditionFalse,
+			reason:   "PipelineRunTimeout",
+			respCode: http.StatusAccepted,
+			wantPath: "/v2/alerts",
+			want: `{
+				"message": "PipelineRunTimeout: sample-run.default",
+				"alias": "tekton/default/sample",
+				"description": "Tasks Completed: 1 (Failed: 1, Cancelled 0), Skipped: 0\n\nbuild (sample-run-build) PipelineRunTimeout: Tasks Completed: 1 (Failed: 1, Cancelled 0), Skipped: 0",
+				"tags": ["tekton", "tekton.dev/pipeline:sample"],
+				"details": {
+					"pipelineRun": "sample-run",
+					"namespace": "default",
+					"pipeline": "sample",
+					"reason": "PipelineRunTimeout",
+					"duration": "1m0s",
+					"dashboard": "https://dashboard.example.com/#/namespaces/default/pipelineruns/sample-run"
+				},
+				"entity": "default/sample",
+				"source": "Tekton",
+				"priority": "P1"
+			}`,
+			wantStatus: metav1.ConditionFalse,
+		},
+		{
+			name:       "Close",
+			status:     corev1.ConditionTrue,
+			reason:     "Succeeded",
+			last:       &v1alpha1.PipelineOutcome{Status: metav1.ConditionFalse, PipelineRun: "previous-run", StartTime: &earlier},
+			respCode:   http.StatusAccepted,
+			wantPath:   "/v2/alerts/tekton%2Fdefault%2Fsample/close?identifierType=alias",
+			want:       `{"source": "Tekton", "note": "Recovered by default/sample-run"}`,
+			wantStatus: metav1.ConditionTrue,
+		},
+		{
+			name:       "SucceededAgain",
+			status:     corev1.ConditionTrue,
+			reason:     "Succeeded",
+			last:       &v1alpha1.PipelineOutcome{Status: metav1.ConditionTrue, PipelineRun: "previous-run", StartTime: &earlier},
+			wantStatus: metav1.ConditionTrue,
+		},
+		{
+			name:        "Cancelled",
+			wantSkipped: true,
+			status:      corev1.ConditionFalse,
+			reason:      "Cancelled",
+		},
+		{
+			name:        "Running",
+			wantSkipped: true,
+			status:      corev1.ConditionUnknown,
+			reason:      "Running",
+		},
+		{
+			name:     "Unauthorized",
+			status:   corev1.ConditionFalse,
+			reason:   "Failed",
+			respCode: http.StatusUnauthorized,
+			wantErr:  NewRuntimeError(""),
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			var paths, bodies []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "GenieKey 4P1K3Y", r.Header.Get("Authorization"))
+				var body json.RawMessage
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("failed to decode request: %v", err)
+				}
+				paths = append(paths, r.RequestURI)
+				bodies = append(bodies, string(body))
+				w.WriteHeader(c.respCode)
+				w.Write([]byte(`{"result":"Request will be processed","took":0.1,"requestId":"1"}`))
+			}))
+			defer srv.Close()
+
+			p := &v1alpha1.Provider{
+				ObjectMeta: metav1.ObjectMeta{Name: "provider", Namespace: "default"},
+			}
+			if c.last != nil {
+				last := *c.last
+				last.Pipeline = "default/sample"
+				p.Status.Pipelines = []v1alpha1.PipelineOutcome{last}
+			}
+			k := newOutcomesTestClient(t, p).Build()
+			assert.Nil(t, k.Get(ctx, types.NamespacedName{Namespace: "default", Name: "provider"}, p))
+			a := &Opsgenie{
+				BaseURL:          srv.URL,
+				APIKey:           NewSecretBytes([]byte("4P1K3Y")),
+				Priority:         "P3",
+				ReasonPriorities: map[string]string{"PipelineRunTimeout": "P1"},
+				Tags:             []string{"tekton"},
+				TagLabels:        []string{"tekton.dev/pipeline", "not-exists"},
+				Outcomes:         newPipelineOutcomes(p, k),
+			}
+			pr := newTemplateSamplePipelineRun()
+			pr.Status.Conditions[0].Status = c.status
+			pr.Status.Conditions[0].Reason = c.reason
+			err := a.Notify(ctx, pr)
+			if c.wantErr != nil {
+				if assert.NotNil(t, err) {
+					assert.Equal(t, c.wantErr.Code, err.Code)
+					assert.NotContains(t, err.Message, "4P1K3Y")
+				}
+				return
+			}
+			if c.wantSkipped {
+				assert.True(t, err.IsSkipped())
+			} else {
+				assert.Nil(t, err)
+			}
+			if len(c.want) < 1 {
+				assert.Empty(t, bodies)
+			} else if assert.Len(t, bodies, 1) {
+				assert.Equal(t, c.wantPath, paths[0])
+				assert.JSONEq(t, c.want, bodies[0])
+			}
+
+			var got v1alpha1.Provider
+			assert.Nil(t, k.Get(ctx, types.NamespacedName{Namespace: "default", Name: "provider"}, &got))
+			if len(c.wantStatus) < 1 {
+				assert.Empty(t, got.Status.Pipelines)
+				return
+			}
+			if assert.Len(t, got.Status.Pipelines, 1) {
+				assert.Equal(t, c.wantStatus, got.Status.Pipelines[0].Status)
+			}
+		})
+	}
+}
+
+func TestOpsgenieGetTags(t *testing.T) {
+	pr := newTemplateSamplePipelineRun()
+	pr.Labels["team"] = "platform"
+	pr.Labels["long"] = strings.Repeat("x", 100)
+	a := &Opsgenie{
+		Tags:      []string{"tekton", "team:platform"},
+		TagLabels: []string{"team", "long"},
+	}
+	tags := a.getTags(pr)
+	if assert.Len(t, tags, 3) {
+		assert.Equal(t, []string{"tekton", "team:platform"}, tags[:2])
+		assert.Equal(t, opsgenieTagLimit, runeLen(tags[2]))
+	}
+
+	for i := 0; i < 30; i++ {
+		a.Tags = append(a.Tags, strings.Repeat("t", i+1))
+	}
+	assert.Len(t, a.getTags(pr), opsgenieTagsLimit)
+}
@@ -0,0 +1,201 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"knative.dev/pkg/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/go-logr/logr"
+	pipelinesv1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+
+	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
+)
+
+const (
+	// maxPipelineOutcomes is the maximum number of the outcomes in the status of a Provider.
+	maxPipelineOutcomes = 256
+	// pipelineOutcomeTTL is the age of the last run after which the outcome of the pipeline is removed.
+	pipelineOutcomeTTL = 30 * 24 * time.Hour
+)
+
+// pipelineOutcomes tracks the last outcome of each pipeline in the status of the Provider.
+// The alerting providers use it to resolve the alerts when a later run of the failed pipeline succeeds,
+// since the annotations of a run can not tell the outcomes of the other runs.
+type pipelineOutcomes struct {
+	Provider *v1alpha1.Provider
+	Client   client.Client
+}
+
+func newPipelineOutcomes(p *v1alpha1.Provider, k client.Client) *pipelineOutcomes {
+	return &pipelineOutcomes{
+		Provider: p.DeepCopy(),
+		Client:   k,
+	}
+}
+
+// getPipelineIdentity returns the namespace and the context-id of the run, e.g. default/sample
+func getPipelineIdentity(pr *pipelinesv1beta1.PipelineRun) (string, *ProviderError) {
+	id, err := getContextID(pr)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/%s", pr.Namespace, id), nil
+}
+
+// Get returns the last outcome of the pipeline, or nil if the pipeline has not been notified.
+func (o *pipelineOutcomes) Get(pipeline string) *v1alpha1.PipelineOutcome {
+	for i := range o.Provider.Status.Pipelines {
+		if o.Provider.Status.Pipelines[i].Pipeline == pipeline {
+			return &o.Provider.Status.Pipelines[i]
+		}
+	}
+	return nil
+}
+
+// Set records the outcome of the run as the last outcome of the pipeline.
+// The status is patched with the optimistic lock, and retried with the latest Provider on conflict.
+func (o *pipelineOutcomes) Set(ctx context.Context, pipeline string, pr *pipelinesv1beta1.PipelineRun, status corev1.ConditionStatus) *ProviderError {
+	outcome := v1alpha1.PipelineOutcome{
+		Pipeline:    pipeline,
+		Status:      metav1.ConditionStatus(status),
+		PipelineRun: pr.Name,
+		StartTime:   pr.Status.StartTime.DeepCopy(),
+	}
+	refresh := false
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if refresh {
+			nn := types.NamespacedName{Namespace: o.Provider.Namespace, Name: o.Provider.Name}
+			if err := o.Client.Get(ctx, nn, o.Provider); err != nil {
+				return err
+			}
+		}
+		refresh = true
+		patch := client.MergeFromWithOptions(o.Provider.DeepCopy(), client.MergeFromWithOptimisticLock{})
+		if last := o.Get(pipeline); last != nil {
+			*last = outcome
+		} else {
+			o.Provider.Status.Pipelines = append(o.Provider.Status.Pipelines, outcome)
+		}
+		o.Provider.Status.Pipelines = prunePipelineOutcomes(o.Provider.Status.Pipelines, pipeline, time.Now())
+		return o.Client.Status().Patch(ctx, o.Provider, patch)
+	})
+	if err != nil {
+		return NewRuntimeError(fmt.Sprintf("failed to save the outcome of the pipeline %s: %v", pipeline, err))
+	}
+	return nil
+}
+
+// prunePipelineOutcomes removes the outcomes whose last run started more than pipelineOutcomeTTL ago,
+// and then the oldest outcomes over maxPipelineOutcomes, so that the status of the Provider does not grow
+// without bound. The outcomes without the start time are the oldest. The outcome of keep is never removed.
+func prunePipelineOutcomes(outcomes []v1alpha1.PipelineOutcome, keep string, now time.Time) []v1alpha1.PipelineOutcome {
+	pruned := make([]v1alpha1.PipelineOutcome, 0, len(outcomes))
+	for _, o := range outcomes {
+		if o.Pipeline != keep && o.StartTime != nil && now.Sub(o.StartTime.Time) > pipelineOutcomeTTL {
+			continue
+		}
+		pruned = append(pruned, o)
+	}
+	over := len(pruned) - maxPipelineOutcomes
+	if over <= 0 {
+		return pruned
+	}
+	oldest := make([]int, 0, len(pruned))
+	for i := range pruned {
+		if pruned[i].Pipeline != keep {
+			oldest = append(oldest, i)
+		}
+	}
+	sort.SliceStable(oldest, func(i, j int) bool {
+		a, b := pruned[oldest[i]].StartTime, pruned[oldest[j]].StartTime
+		if a == nil || b == nil {
+			return a == nil && b != nil
+		}
+		return a.Before(b)
+	})
+	removed := make(map[int]bool, over)
+	for _, i := range oldest[:over] {
+		removed[i] = true
+	}
+	kept := make([]v1alpha1.PipelineOutcome, 0, maxPipelineOutcomes)
+	for i := range pruned {
+		if !removed[i] {
+			kept = append(kept, pruned[i])
+		}
+	}
+	return kept
+}
+
+// isOutdatedRun reports whether the run started before the last run of the pipeline,
+// whose outcome must not be overwritten by the older run.
+func isOutdatedRun(last *v1alpha1.PipelineOutcome, pr *pipelinesv1beta1.PipelineRun) bool {
+	if last == nil || last.StartTime == nil || pr.Status.StartTime == nil {
+		return false
+	}
+	return pr.Status.StartTime.Before(last.StartTime)
+}
+
+// pipelineAlerter is implemented by the alerting providers.
+type pipelineAlerter interface {
+	// triggerAlert raises the alert of the failed run of the pipeline.
+	triggerAlert(ctx context.Context, pr *pipelinesv1beta1.PipelineRun, pipeline string) *ProviderError
+	// resolveAlert resolves the alert of the pipeline, which is recovered by the succeeded run.
+	resolveAlert(ctx context.Context, pr *pipelinesv1beta1.PipelineRun, pipeline string) *ProviderError
+}
+
+// notifyPipelineAlert triggers the alert when the finished run failed, and resolves it
+// when the run succeeded after the last failure of the pipeline. The cancelled runs and
+// the runs older than the last run of the pipeline are ignored.
+func notifyPipelineAlert(ctx context.Context, log logr.Logger, a pipelineAlerter, outcomes *pipelineOutcomes, pr *pipelinesv1beta1.PipelineRun) *ProviderError {
+	cond := pr.Status.GetCondition(apis.ConditionSucceeded)
+	if isCancelled(cond) {
+		log.V(1).Info("this run is cancelled, skipped")
+		return NewSkippedError("this run is cancelled")
+	}
+	pipeline, perr := getPipelineIdentity(pr)
+	if perr != nil {
+		return perr
+	}
+	last := outcomes.Get(pipeline)
+	if isOutdatedRun(last, pr) {
+		log.V(1).Info("a later run of the pipeline is already notified, skipped", "lastPipelineRun", last.PipelineRun)
+		return NewSkippedError("a later run of the pipeline is already notified")
+	}
+	switch {
+	case cond.Status == corev1.ConditionFalse:
+		if perr := a.triggerAlert(ctx, pr, pipeline); perr != nil {
+			return perr
+		}
+		log.V(2).Info("trigger alert", "pipeline", pipeline)
+	case last != nil && last.Status == metav1.ConditionFalse:
+		if perr := a.resolveAlert(ctx, pr, pipeline); perr != nil {
+			return perr
+		}
+		log.V(2).Info("resolve alert", "pipeline", pipeline)
+	}
+	return outcomes.Set(ctx, pipeline, pr, cond.Status)
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
+)
+
+func newOutcomesTestClient(t *testing.T, p *v1alpha1.Provider) *fakeclient.ClientBuilder {
+	s := runtime.NewScheme()
+	assert.Nil(t, corev1.AddToScheme(s))
+	assert.Nil(t, v1alpha1.AddToScheme(s))
+	return fakeclient.NewClientBuilder().WithScheme(s).WithObjects(p)
+}
+
+func TestPipelineOutcomes(t *testing.T) {
+	start := metav1.NewTime(time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC))
+	p := &v1alpha1.Provider{
+		ObjectMeta: metav1.ObjectMeta{Name: "provider", Namespace: "default"},
+		Status: v1alpha1.ProviderStatus{
+			Pipelines: []v1alpha1.PipelineOutcome{
+				{Pipeline: "default/other", Status: metav1.ConditionTrue, PipelineRun: "other-run"},
+			},
+		},
+	}
+	k := newOutcomesTestClient(t, p).Build()
+	var stale v1alpha1.Provider
+	assert.Nil(t, k.Get(ctx, types.NamespacedName{Namespace: "default", Name: "provider"}, &stale))
+
+	// the Provider is updated after it is read.
+	updated := stale.DeepCopy()
+	updated.Status.ObservedGeneration = 1
+	assert.Nil(t, k.Status().Update(ctx, updated))
+
+	o := newPipelineOutcomes(&stale, k)
+	pr := newTemplateSamplePipelineRun()
+	assert.Nil(t, o.Get("default/sample"))
+	assert.Nil(t, o.Set(ctx, "default/sample", pr, corev1.ConditionFalse))
+
+	var got v1alpha1.Provider
+	assert.Nil(t, k.Get(ctx, types.NamespacedName{Namespace: "default", Name: "provider"}, &got))
+	assert.Equal(t, int64(1), got.Status.ObservedGeneration)
+	if assert.Len(t, got.Status.Pipelines, 2) {
+		assert.Equal(t, "default/other", got.Status.Pipelines[0].Pipeline)
+		assert.Equal(t, "default/sample", got.Status.Pipelines[1].Pipeline)
+		assert.Equal(t, metav1.ConditionFalse, got.Status.Pipelines[1].Status)
+		assert.Equal(t, "sample-run", got.Status.Pipelines[1].PipelineRun)
+		assert.True(t, start.Equal(got.Status.Pipelines[1].StartTime))
+	}
+
+	// the outcome of the pipeline is replaced.
+	pr.Name = "sample-run-2"
+	assert.Nil(t, o.Set(ctx, "default/sample", pr, corev1.ConditionTrue))
+	assert.Nil(t, k.Get(ctx, types.NamespacedName{Namespace: "default", Name: "provider"}, &got))
+	if assert.Len(t, got.Status.Pipelines, 2) {
+		assert.Equal(t, metav1.ConditionTrue, got.Status.Pipelines[1].Status)
+		assert.Equal(t, "sample-run-2", got.Status.Pipelines[1].PipelineRun)
+	}
+}
+
+func TestPrunePipelineOutcomes(t *testing.T) {
+	now := time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *metav1.Time {
+		t := metav1.NewTime(now.Add(-d))
+		return &t
+	}
+	many := func(n int, first ...v1alpha1.PipelineOutcome) []v1alpha1.PipelineOutcome {
+		outcomes := append([]v1alpha1.PipelineOutcome{}, first...)
+		for i := len(first); i < n; i++ {
+			outcomes = append(outcomes, v1alpha1.PipelineOutcome{
+				Pipeline:  fmt.Sprintf("default/p%d", i),
+				StartTime: at(time.Duration(n-i) * time.Minute),
+			})
+		}
+		return outcomes
+	}
+	for _, c := range []struct {
+		name      string
+		outcomes  []v1alpha1.PipelineOutcome
+		keep      string
+		wantLen   int
+		wantFirst string
+	}{
+		{
+			name: "Expired",
+			outcomes: []v1alpha1.PipelineOutcome{
+				{Pipeline: "default/expired", StartTime: at(pipelineOutcomeTTL + time.Hour)},
+				{Pipeline: "default/recent", StartTime: at(time.Hour)},
+			},
+			keep:      "default/recent",
+			wantLen:   1,
+			wantFirst: "default/recent",
+		},
+		{
+			name: "ExpiredKept",
+			outcomes: []v1alpha1.PipelineOutcome{
+				{Pipeline: "default/expired", StartTime: at(pipelineOutcomeTTL + time.Hour)},
+			},
+			keep:      "default/expired",
+			wantLen:   1,
+			wantFirst: "default/expired",
+		},
+		{
+			name: "NoStartTime",
+			outcomes: []v1alpha1.PipelineOutcome{
+				{Pipeline: "default/unknown"},
+			},
+			keep:      "default/sample",
+			wantLen:   1,
+			wantFirst: "default/unknown",
+		},
+		{
+			name:      "OverCapacity",
+			outcomes:  many(maxPipelineOutcomes + 2),
+			keep:      "default/p0",
+			wantLen:   maxPipelineOutcomes,
+			wantFirst: "default/p0",
+		},
+		{
+			name:      "OverCapacityNoStartTime",
+			outcomes:  many(maxPipelineOutcomes+1, v1alpha1.PipelineOutcome{Pipeline: "default/unknown"}),
+			keep:      "default/p1",
+			wantLen:   maxPipelineOutcomes,
+			wantFirst: "default/p1",
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			got := prunePipelineOutcomes(c.outcomes, c.keep, now)
+			if assert.Len(t, got, c.wantLen) {
+				assert.Equal(t, c.wantFirst, got[0].Pipeline)
+			}
+		})
+	}
+}
+
+func TestIsOutdatedRun(t *testing.T) {
+	start := metav1.NewTime(time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC))
+	before := metav1.NewTime(start.Add(-time.Minute))
+	after := metav1.NewTime(start.Add(time.Minute))
+	pr := newTemplateSamplePipelineRun()
+	assert.False(t, isOutdatedRun(nil, pr))
+	assert.False(t, isOutdatedRun(&v1alpha1.PipelineOutcome{}, pr))
+	assert.False(t, isOutdatedRun(&v1alpha1.PipelineOutcome{StartTime: &start}, pr))
+	assert.False(t, isOutdatedRun(&v1alpha1.PipelineOutcome{StartTime: &before}, pr))
+	assert.True(t, isOutdatedRun(&v1alpha1.PipelineOutcome{StartTime: &after}, pr))
+}
@@ -0,0 +1,230 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"knative.dev/pkg/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/go-logr/logr"
+	pipelinesv1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+
+	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
+)
+
+const (
+	pagerDutyDefaultBaseURL  = "https://events.pagerduty.com"
+	pagerDutyDefaultSeverity = "error"
+	// pagerDutySummaryLimit is the max length of the summary of the events.
+	pagerDutySummaryLimit = 1024
+)
+
+// PagerDuty triggers the incidents of the failed pipelines with the Events API v2,
+// and resolves them when the pipelines succeed.
+type PagerDuty struct {
+	BaseURL       string
+	RoutingKey    SecretBytes
+	Severity      string
+	CustomDetails map[string]*template.Template
+	Outcomes      *pipelineOutcomes
+}
+
+var _ Provider = (*PagerDuty)(nil)
+var _ Validator = (*PagerDuty)(nil)
+
+func NewPagerDuty(ctx context.Context, p *v1alpha1.Provider, k client.Client) (*PagerDuty, *ProviderError) {
+	s := p.Spec.PagerDuty
+	if s == nil {
+		return nil, NewInvalidProviderSpecError("missing value .pagerDuty")
+	}
+	if s.RoutingKey.SecretRef == nil {
+		return nil, NewInvalidProviderSpecError("missing valid values in .routingKey")
+	}
+	key, perr := getSecretValue(ctx, k, p.Namespace, s.RoutingKey.SecretRef, "routing-key")
+	if perr != nil {
+		return nil, perr
+	}
+	a := &PagerDuty{
+		BaseURL:       pagerDutyDefaultBaseURL,
+		RoutingKey:    NewSecretBytes(key),
+		Severity:      pagerDutyDefaultSeverity,
+		CustomDetails: map[string]*template.Template{},
+		Outcomes:      newPipelineOutcomes(p, k),
+	}
+	if s.BaseURL != nil && len(*s.BaseURL) > 0 {
+		a.BaseURL = strings.TrimSuffix(*s.BaseURL, "/")
+	}
+	if s.Severity != nil && len(*s.Severity) > 0 {
+		a.Severity = *s.Severity
+	}
+	for name, text := range s.CustomDetails {
+		t, perr := parseTemplate(name, text)
+		if perr != nil {
+			return nil, perr
+		}
+		a.CustomDetails[name] = t
+	}
+	return a, nil
+}
+
+func (a *PagerDuty) Notify(ctx context.Context, pr *pipelinesv1beta1.PipelineRun) *ProviderError {
+	log := logr.FromContext(ctx).WithName("providers.pagerduty").
+		WithValues("providerType", "PagerDuty", "pipelineRun", pr.Name)
+	cond := pr.Status.GetCondition(apis.ConditionSucceeded)
+	if cond == nil {
+		log.V(1).Info("PipelineRun has not condition, ignored")
+		return NewSkippedError("PipelineRun has not condition")
+	}
+	if cond.Status == corev1.ConditionUnknown {
+		log.V(2).Info("this run is not finished yet, skipped")
+		return NewSkippedError("this run is not finished yet")
+	}
+	return notifyPipelineAlert(ctx, log, a, a.Outcomes, pr)
+}
+
+func (a *PagerDuty) triggerAlert(ctx context.Context, pr *pipelinesv1beta1.PipelineRun, pipeline string) *ProviderError {
+	event, perr := a.newTriggerEvent(pr, pipeline)
+	if perr != nil {
+		return perr
+	}
+	return a.send(ctx, event)
+}
+
+func (a *PagerDuty) resolveAlert(ctx context.Context, pr *pipelinesv1beta1.PipelineRun, pipeline string) *ProviderError {
+	return a.send(ctx, a.newEvent("resolve", pipeline))
+}
+
+// Validate renders the custom details with the sample run.
+func (a *PagerDuty) Validate(ctx context.Context) *ProviderError {
+	_, perr := a.newCustomDetails(newTemplateSamplePipelineRun())
+	return perr
+}
+
+func (a *PagerDuty) send(ctx context.Context, event *pagerDutyEvent) *ProviderError {
+	if _, err := postHTTP(ctx, a.BaseURL+"/v2/enqueue", "", event); err != nil {
+		return newHTTPError("PagerDuty", err)
+	}
+	return nil
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+	Client      string            `json:"client,omitempty"`
+	ClientURL   string            `json:"client_url,omitempty"`
+	Links       []pagerDutyLink   `json:"links,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string                 `json:"summary"`
+	Source        string                 `json:"source"`
+	Severity      string                 `json:"severity"`
+	Timestamp     string                 `json:"timestamp,omitempty"`
+	Component     string                 `json:"component,omitempty"`
+	Group         string                 `json:"group,omitempty"`
+	Class         string                 `json:"class,omitempty"`
+	CustomDetails map[string]interface{} `json:"custom_details,omitempty"`
+}
+
+type pagerDutyLink struct {
+	Href string `json:"href"`
+	Text string `json:"text,omitempty"`
+}
+
+// getPagerDutyDedupKey returns the deduplication key of the pipeline,
+// so that the failures of the pipeline are grouped into one incident until it is resolved.
+func getPagerDutyDedupKey(pipeline string) string {
+	return "tekton/" + pipeline
+}
+
+func (a *PagerDuty) newEvent(action, pipeline string) *pagerDutyEvent {
+	return &pagerDutyEvent{
+		RoutingKey:  a.RoutingKey.GetNoRedactedString(),
+		EventAction: action,
+		DedupKey:    getPagerDutyDedupKey(pipeline),
+	}
+}
+
+func (a *PagerDuty) newTriggerEvent(pr *pipelinesv1beta1.PipelineRun, pipeline string) (*pagerDutyEvent, *ProviderError) {
+	details, perr := a.newCustomDetails(pr)
+	if perr != nil {
+		return nil, perr
+	}
+	m := newChatMessage(pr)
+	event := a.newEvent("trigger", pipeline)
+	event.Payload = &pagerDutyPayload{
+		Summary:       truncateRunes(m.Summary(), pagerDutySummaryLimit),
+		Source:        pipeline,
+		Severity:      a.Severity,
+		Component:     m.Pipeline,
+		Group:         m.Namespace,
+		Class:         m.Reason,
+		CustomDetails: details,
+	}
+	if t := pr.Status.CompletionTime; t != nil {
+		event.Payload.Timestamp = t.UTC().Format(time.RFC3339)
+	}
+	if len(m.DashboardURL) > 0 {
+		event.Client = "Tekton Dashboard"
+		event.ClientURL = m.DashboardURL
+		event.Links = []pagerDutyLink{{Href: m.DashboardURL, Text: "Tekton Dashboard"}}
+	}
+	return event, nil
+}
+
+// newCustomDetails returns the built-in details of the run overridden by the custom details.
+func (a *PagerDuty) newCustomDetails(pr *pipelinesv1beta1.PipelineRun) (map[string]interface{}, *ProviderError) {
+	details := map[string]interface{}{
+		"pipelineRun": pr.Name,
+		"namespace":   pr.Namespace,
+	}
+	if cond := pr.Status.GetCondition(apis.ConditionSucceeded); cond != nil {
+		details["reason"] = cond.Reason
+		details["message"] = cond.Message
+	}
+	if pr.Spec.PipelineRef != nil {
+		details["pipeline"] = pr.Spec.PipelineRef.Name
+	}
+	if tasks := getChatFailedTasks(pr); len(tasks) > 0 {
+		details["failedTasks"] = formatFailureDetails(tasks, "%s")
+	}
+	if u := getDashboardTargetURL(pr); len(u) > 0 {
+		details["dashboard"] = u
+	}
+	names := make([]string, 0, len(a.CustomDetails))
+	for name := range a.CustomDetails {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		b, perr := executeTemplate(a.CustomDetails[name], pr)
+		if perr != nil {
+			return nil, perr
+		}
+		details[name] = string(b)
+	}
+	return details, nil
+}
@@ -0,0 +1,306 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
+)
+
+func TestNewPagerDuty(t *testing.T) {
+	k := fakeclient.NewClientBuilder().
+		WithObjects(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "secret",
+				Namespace: "default",
+			},
+			Data: map[string][]byte{
+				"routing-key": []byte("R0UT1NGKEY"),
+			},
+		}).
+		Build()
+	for _, c := range []struct {
+		name         string
+		spec         *v1alpha1.PagerDutySpec
+		wantBaseURL  string
+		wantSeverity string
+		wantErr      *ProviderError
+	}{
+		{
+			name: "Basic",
+			spec: &v1alpha1.PagerDutySpec{
+				RoutingKey: v1alpha1.RoutingKeySource{
+					SecretRef: &v1alpha1.LocalSecretKeyReference{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "secret"},
+					},
+				},
+			},
+			wantBaseURL:  "https://events.pagerduty.com",
+			wantSeverity: "error",
+		},
+		{
+			name: "Options",
+			spec: &v1alpha1.PagerDutySpec{
+				RoutingKey: v1alpha1.RoutingKeySource{
+					SecretRef: &v1alpha1.LocalSecretKeyReference{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "secret"},
+					},
+				},
+				BaseURL:  pointer.String("https://events.eu.pagerduty.com/"),
+				Severity: pointer.String("critical"),
+			},
+			wantBaseURL:  "https://events.eu.pagerduty.com",
+			wantSeverity: "critical",
+		},
+		{
+			name: "InvalidTemplate",
+			spec: &v1alpha1.PagerDutySpec{
+				RoutingKey: v1alpha1.RoutingKeySource{
+					SecretRef: &v1alpha1.LocalSecretKeyReference{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "secret"},
+					},
+				},
+				CustomDetails: map[string]string{"revision": "{{ .Params.revision"},
+			},
+			wantErr: NewInvalidProviderSpecError(""),
+		},
+		{
+			name: "SecretNotFound",
+			spec: &v1alpha1.PagerDutySpec{
+				RoutingKey: v1alpha1.RoutingKeySource{
+					SecretRef: &v1alpha1.LocalSecretKeyReference{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "not-exists-secret"},
+					},
+				},
+			},
+			wantErr: NewNotFoundPrivateKeyError(""),
+		},
+		{
+			name:    "PagerDutySpecNotFound",
+			wantErr: NewInvalidProviderSpecError(""),
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			p := &v1alpha1.Provider{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "provider",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.ProviderSpec{
+					Type:      "PagerDuty",
+					PagerDuty: c.spec,
+				},
+			}
+			a, err := NewPagerDuty(ctx, p, k)
+			if c.wantErr != nil {
+				if assert.NotNil(t, err) {
+					assert.Equal(t, c.wantErr.Code, err.Code)
+				}
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, "R0UT1NGKEY", a.RoutingKey.GetNoRedactedString())
+			assert.Equal(t, c.wantBaseURL, a.BaseURL)
+			assert.Equal(t, c.wantSeverity, a.Severity)
+		})
+	}
+}
+
+func TestPagerDutyNotify(t *testing.T) {
+	start := metav1.NewTime(time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC))
+	earlier := metav1.NewTime(start.Add(-time.Hour))
+	later := metav1.NewTime(start.Add(time.Hour))
+	for _, c := range []struct {
+		name        string
+		status      corev1.ConditionStatus
+		reason      string
+		last        *v1alpha1.PipelineOutcome
+		respCode    int
+		want        string
+		wantStatus  metav1.ConditionStatus
+		wantErr     *ProviderError
+		wantSkipped bool
+	}{
+		{
+			name:     "Trigger",
+			status:   corev1.ConditionFalse,
+			reason:   "Failed",
+			respCode: http.StatusAccepted,
+			want: `{
+				"routing_key": "R0UT1NGKEY",
+				"event_action": "trigger",
+				"dedup_key": "tekton/default/sample",
+				"payload": {
+					"summary": "Failed: sample-run.default",
+					"source": "default/sample",
+					"severity": "critical",
+					"timestamp": "2021-07-01T00:01:00Z",
+					"component": "sample",
+					"group": "default",
+					"class": "Failed",
+					"custom_details": {
+						"pipelineRun": "sample-run",
+						"namespace": "default",
+						"pipeline": "sample",
+						"reason": "Failed",
+						"message": "Tasks Completed: 1 (Failed: 1, Cancelled 0), Skipped: 0",
+						"failedTasks": "build (sample-run-build) Failed: Tasks Completed: 1 (Failed: 1, Cancelled 0), Skipped: 0",
+						"dashboard": "https://dashboard.example.com/#/namespaces/default/pipelineruns/sample-run",
+						"revision": "main"
+					}
+				},
+				"client": "Tekton Dashboard",
+				"client_url": "https://dashboard.example.com/#/namespaces/default/pipelineruns/sample-run",
+				"links": [{"href": "https://dashboard.example.com/#/namespaces/default/pipelineruns/sample-run", "text": "Tekton Dashboard"}]
+			}`,
+			wantStatus: metav1.ConditionFalse,
+		},
+		{
+			name:     "Resolve",
+			status:   corev1.ConditionTrue,
+			reason:   "Succeeded",
+			last:     &v1alpha1.PipelineOutcome{Status: metav1.ConditionFalse, PipelineRun: "previous-run", StartTime: &earlier},
+			respCode: http.StatusAccepted,
+			want: `{
+				"routing_key": "R0UT1NGKEY",
+				"event_action": "resolve",
+				"dedup_key": "tekton/default/sample"
+			}`,
+			wantStatus: metav1.ConditionTrue,
+		},
+		{
+			name:       "SucceededAgain",
+			status:     corev1.ConditionTrue,
+			reason:     "Succeeded",
+			last:       &v1alpha1.PipelineOutcome{Status: metav1.ConditionTrue, PipelineRun: "previous-run", StartTime: &earlier},
+			wantStatus: metav1.ConditionTrue,
+		},
+		{
+			name:       "FirstSucceeded",
+			status:     corev1.ConditionTrue,
+			reason:     "Succeeded",
+			wantStatus: metav1.ConditionTrue,
+		},
+		{
+			name:        "Outdated",
+			wantSkipped: true,
+			status:      corev1.ConditionFalse,
+			reason:      "Failed",
+			last:        &v1alpha1.PipelineOutcome{Status: metav1.ConditionTrue, PipelineRun: "previous-run", StartTime: &later},
+			wantStatus:  metav1.ConditionTrue,
+		},
+		{
+			name:        "Cancelled",
+			wantSkipped: true,
+			status:      corev1.ConditionFalse,
+			reason:      "Cancelled",
+		},
+		{
+			name:        "Running",
+			wantSkipped: true,
+			status:      corev1.ConditionUnknown,
+			reason:      "Running",
+		},
+		{
+			name:     "BadRequest",
+			status:   corev1.ConditionFalse,
+			reason:   "Failed",
+			respCode: http.StatusBadRequest,
+			wantErr:  NewRuntimeError(""),
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			var bodies []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/v2/enqueue", r.URL.Path)
+				var body json.RawMessage
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("failed to decode request: %v", err)
+				}
+				bodies = append(bodies, string(body))
+				w.WriteHeader(c.respCode)
+				w.Write([]byte(`{"status":"success","dedup_key":"tekton/default/sample"}`))
+			}))
+			defer srv.Close()
+
+			p := &v1alpha1.Provider{
+				ObjectMeta: metav1.ObjectMeta{Name: "provider", Namespace: "default"},
+			}
+			if c.last != nil {
+				last := *c.last
+				last.Pipeline = "default/sample"
+				p.Status.Pipelines = []v1alpha1.PipelineOutcome{last}
+			}
+			k := newOutcomesTestClient(t, p).Build()
+			assert.Nil(t, k.Get(ctx, types.NamespacedName{Namespace: "default", Name: "provider"}, p))
+			revision, _ := parseTemplate("revision", "{{ .Params.revision }}")
+			a := &PagerDuty{
+				BaseURL:       srv.URL,
+				RoutingKey:    NewSecretBytes([]byte("R0UT1NGKEY")),
+				Severity:      "critical",
+				CustomDetails: map[string]*template.Template{"revision": revision},
+				Outcomes:      newPipelineOutcomes(p, k),
+			}
+			pr := newTemplateSamplePipelineRun()
+			pr.Status.Conditions[0].Status = c.status
+			pr.Status.Conditions[0].Reason = c.reason
+			err := a.Notify(ctx, pr)
+			if c.wantErr != nil {
+				if assert.NotNil(t, err) {
+					assert.Equal(t, c.wantErr.Code, err.Code)
+				}
+				return
+			}
+			if c.wantSkipped {
+				assert.True(t, err.IsSkipped())
+			} else {
+				assert.Nil(t, err)
+			}
+			if len(c.want) < 1 {
+				assert.Empty(t, bodies)
+			} else if assert.Len(t, bodies, 1) {
+				assert.JSONEq(t, c.want, bodies[0])
+			}
+
+			var got v1alpha1.Provider
+			assert.Nil(t, k.Get(ctx, types.NamespacedName{Namespace: "default", Name: "provider"}, &got))
+			if len(c.wantStatus) < 1 {
+				assert.Empty(t, got.Status.Pipelines)
+				return
+			}
+			if assert.Len(t, got.Status.Pipelines, 1) {
+				assert.Equal(t, c.wantStatus, got.Status.Pipelines[0].Status)
+			}
+		})
+	}
+}
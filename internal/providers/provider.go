@@ -21,9 +21,15 @@ import (
 	"fmt"
 	"strings"
 
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/pkg/apis"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/prometheus/client_golang/prometheus"
 	pipelinesv1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 
 	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
 )
@@ -31,12 +37,54 @@ import (
 const (
 	annotationContextID              = "integrations.tekton.ornew.io/context-id"
 	annotationTektonDashboardBaseURL = "integrations.tekton.ornew.io/tekton-dashboard-base-url"
+
+	annotationRepositoryOwner = "integrations.tekton.ornew.io/owner"
+	annotationRepositoryRepo  = "integrations.tekton.ornew.io/repo"
+	annotationRepositorySHA   = "integrations.tekton.ornew.io/sha"
 )
 
+var secretLookupFailuresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "tekton_integration_secret_lookup_failures_total",
+	Help: "Total number of the failures to read the credentials from the secrets by namespace and reason.",
+}, []string{"namespace", "reason"})
+
+func init() {
+	metrics.Registry.MustRegister(secretLookupFailuresTotal)
+}
+
 type Provider interface {
 	Notify(ctx context.Context, pr *pipelinesv1beta1.PipelineRun) *ProviderError
 }
 
+// ProgressNotifier is implemented by providers that report the progress of
+// running runs. NotifyProgress is called when a task of the run is completed
+// without changing the status of the run.
+type ProgressNotifier interface {
+	// ReportsProgress returns true if the provider is configured to report the progress.
+	ReportsProgress() bool
+	NotifyProgress(ctx context.Context, pr *pipelinesv1beta1.PipelineRun) *ProviderError
+}
+
+// Validator is implemented by providers that can verify their settings
+// against the external service before sending any notification.
+type Validator interface {
+	Validate(ctx context.Context) *ProviderError
+}
+
+type notificationContextKey struct{}
+
+// WithNotification returns a copy of ctx with the Notification being dispatched,
+// from which the providers read the per-Notification settings.
+func WithNotification(ctx context.Context, n *v1alpha1.Notification) context.Context {
+	return context.WithValue(ctx, notificationContextKey{}, n)
+}
+
+// notificationFromContext returns the Notification being dispatched, or nil.
+func notificationFromContext(ctx context.Context) *v1alpha1.Notification {
+	n, _ := ctx.Value(notificationContextKey{}).(*v1alpha1.Notification)
+	return n
+}
+
 func ResolveProvider(ctx context.Context, p *v1alpha1.Provider, k8s client.Client) (app Provider, err *ProviderError) {
 	switch p.Spec.Type {
 	case "GitHubApp":
@@ -45,6 +93,54 @@ func ResolveProvider(ctx context.Context, p *v1alpha1.Provider, k8s client.Clien
 	case "SlackApp":
 		app, err = NewSlackApp(ctx, p, k8s)
 		return
+	case "GitLab":
+		app, err = NewGitLab(ctx, p, k8s)
+		return
+	case "Bitbucket":
+		app, err = NewBitbucket(ctx, p, k8s)
+		return
+	case "Gitea":
+		app, err = NewGitea(ctx, p, k8s)
+		return
+	case "AzureDevOps":
+		app, err = NewAzureDevOps(ctx, p, k8s)
+		return
+	case "SlackWebhook":
+		app, err = NewSlackWebhook(ctx, p, k8s)
+		return
+	case "MicrosoftTeams":
+		app, err = NewMicrosoftTeams(ctx, p, k8s)
+		return
+	case "Discord":
+		app, err = NewDiscord(ctx, p, k8s)
+		return
+	case "Mattermost":
+		app, err = NewMattermost(ctx, p, k8s)
+		return
+	case "RocketChat":
+		app, err = NewRocketChat(ctx, p, k8s)
+		return
+	case "Email":
+		app, err = NewEmail(ctx, p, k8s)
+		return
+	case "PagerDuty":
+		app, err = NewPagerDuty(ctx, p, k8s)
+		return
+	case "Opsgenie":
+		app, err = NewOpsgenie(ctx, p, k8s)
+		return
+	case "Alertmanager":
+		app, err = NewAlertmanager(ctx, p, k8s)
+		return
+	case "Webhook":
+		app, err = NewWebhook(ctx, p, k8s)
+		return
+	case "Kafka":
+		app, err = NewKafka(ctx, p, k8s)
+		return
+	case "NATS":
+		app, err = NewNATS(ctx, p, k8s)
+		return
 	}
 	return nil, NewInvalidProviderSpecError(fmt.Sprintf("unknown provider type: %v", p.Spec.Type))
 }
@@ -52,3 +148,158 @@ func ResolveProvider(ctx context.Context, p *v1alpha1.Provider, k8s client.Clien
 func getDashboardPipelineRunURL(base, namespace, name string) string {
 	return fmt.Sprintf("%s/#/namespaces/%s/pipelineruns/%s", strings.TrimSuffix(base, "/"), namespace, name)
 }
+
+// getDashboardTargetURL returns the dashboard URL of the run, or empty if the
+// dashboard annotation is not set.
+func getDashboardTargetURL(pr *pipelinesv1beta1.PipelineRun) string {
+	base := pr.Annotations[annotationTektonDashboardBaseURL]
+	if len(base) < 1 {
+		return ""
+	}
+	return getDashboardPipelineRunURL(base, pr.Namespace, pr.Name)
+}
+
+// getContextID returns the context-id annotation, defaulting to pipelineRef.name.
+func getContextID(pr *pipelinesv1beta1.PipelineRun) (string, *ProviderError) {
+	if id := pr.Annotations[annotationContextID]; len(id) > 0 {
+		return id, nil
+	}
+	if ref := pr.Spec.PipelineRef; ref != nil && len(ref.Name) > 0 {
+		return ref.Name, nil
+	}
+	return "", NewFailedValidationError("context-id or pipelineRef.name is required")
+}
+
+// getStatusContext returns the name of the commit status for the run.
+func getStatusContext(pr *pipelinesv1beta1.PipelineRun) (string, *ProviderError) {
+	id, err := getContextID(pr)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("tekton: %s", id), nil
+}
+
+// isCancelled reports whether the run was cancelled by the user.
+func isCancelled(c *apis.Condition) bool {
+	switch c.Reason {
+	case pipelinesv1beta1.PipelineRunReasonCancelled.String(),
+		pipelinesv1beta1.PipelineRunSpecStatusCancelledDeprecated:
+		return true
+	}
+	return false
+}
+
+// repositoryRef identifies a commit in a repository of a source code management service.
+type repositoryRef struct {
+	Owner    string
+	Repo     string
+	Revision string
+}
+
+// getRepositoryRef reads the repository annotations of the SCM,
+// e.g. integrations.tekton.ornew.io/github-owner for "github".
+// The SCM-agnostic annotations, e.g. integrations.tekton.ornew.io/owner,
+// are used if the SCM-specific ones are not set.
+func getRepositoryRef(pr *pipelinesv1beta1.PipelineRun, scm string) (*repositoryRef, *ProviderError) {
+	ownerKey := fmt.Sprintf("integrations.tekton.ornew.io/%s-owner", scm)
+	repoKey := fmt.Sprintf("integrations.tekton.ornew.io/%s-repo", scm)
+	shaKey := fmt.Sprintf("integrations.tekton.ornew.io/%s-sha", scm)
+	ref := &repositoryRef{
+		Owner:    getAnnotation(pr, ownerKey, annotationRepositoryOwner),
+		Repo:     getAnnotation(pr, repoKey, annotationRepositoryRepo),
+		Revision: getAnnotation(pr, shaKey, annotationRepositorySHA),
+	}
+	if len(ref.Owner) < 1 || len(ref.Repo) < 1 || len(ref.Revision) < 1 {
+		return nil, NewFailedValidationError(fmt.Sprintf("required annotations: %s=%s %s=%s %s=%s",
+			ownerKey, ref.Owner,
+			repoKey, ref.Repo,
+			shaKey, ref.Revision,
+		))
+	}
+	return ref, nil
+}
+
+// getAnnotation returns the value of the first non-empty annotation of the keys.
+func getAnnotation(pr *pipelinesv1beta1.PipelineRun, keys ...string) string {
+	for _, key := range keys {
+		if v := pr.Annotations[key]; len(v) > 0 {
+			return v
+		}
+	}
+	return ""
+}
+
+// getParam returns the string value of the parameter of the run.
+func getParam(pr *pipelinesv1beta1.PipelineRun, name string) string {
+	for _, p := range pr.Spec.Params {
+		if p.Name == name && p.Value.Type == pipelinesv1beta1.ParamTypeString {
+			return p.Value.StringVal
+		}
+	}
+	return ""
+}
+
+// getSecretValue reads the value from the secret in the namespace of the provider.
+// If the key is not specified in the reference, defaultKey is used.
+func getSecretValue(ctx context.Context, k client.Client, namespace string, ref *v1alpha1.LocalSecretKeyReference, defaultKey string) ([]byte, *ProviderError) {
+	key := defaultKey
+	if ref.Key != nil && len(*ref.Key) > 0 {
+		key = *ref.Key
+	}
+	data, err := getSecretData(ctx, k, namespace, ref.Name, key)
+	if err != nil {
+		return nil, err
+	}
+	return data[key], nil
+}
+
+// getConfigMapValue reads the value of the key in the ConfigMap in the namespace of the provider.
+func getConfigMapValue(ctx context.Context, k client.Client, namespace string, ref *v1alpha1.LocalConfigMapKeyReference, defaultKey string) (string, *ProviderError) {
+	key := defaultKey
+	if ref.Key != nil && len(*ref.Key) > 0 {
+		key = *ref.Key
+	}
+	var cm corev1.ConfigMap
+	if err := k.Get(ctx, types.NamespacedName{Namespace: namespace, Name: ref.Name}, &cm); err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", NewFailedValidationError(fmt.Sprintf("ConfigMap %s is not found", ref.Name))
+		}
+		return "", NewRuntimeError(fmt.Sprintf("failed to get ConfigMap %s: %v", ref.Name, err))
+	}
+	data, ok := cm.Data[key]
+	if !ok {
+		return "", NewFailedValidationError(fmt.Sprintf("ConfigMap %s has no key %s", ref.Name, key))
+	}
+	return data, nil
+}
+
+// getSecretData reads the data of the secret in the namespace of the provider
+// and checks that all the required keys exist.
+func getSecretData(ctx context.Context, k client.Client, namespace, name string, required ...string) (map[string][]byte, *ProviderError) {
+	var secret corev1.Secret
+	nn := types.NamespacedName{
+		Namespace: namespace,
+		Name:      name,
+	}
+	if err := k.Get(ctx, nn, &secret); err != nil {
+		reason := "Error"
+		if apierrors.IsNotFound(err) {
+			reason = "NotFound"
+		} else if apierrors.IsForbidden(err) {
+			reason = "Forbidden"
+		}
+		secretLookupFailuresTotal.WithLabelValues(namespace, reason).Inc()
+		return nil, NewNotFoundPrivateKeyError(fmt.Sprintf("failed to get secret: %v", err))
+	}
+	if secret.Data == nil {
+		secretLookupFailuresTotal.WithLabelValues(namespace, "MissingData").Inc()
+		return nil, NewNotFoundPrivateKeyError("data not found in secret")
+	}
+	for _, key := range required {
+		if _, ok := secret.Data[key]; !ok {
+			secretLookupFailuresTotal.WithLabelValues(namespace, "MissingKey").Inc()
+			return nil, NewNotFoundPrivateKeyError(fmt.Sprintf("missing key %s", key))
+		}
+	}
+	return secret.Data, nil
+}
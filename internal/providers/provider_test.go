@@ -0,0 +1,94 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestGetSecretData(t *testing.T) {
+	k := fakeclient.NewClientBuilder().
+		WithObjects(
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "token", Namespace: "secrets"},
+				Data: map[string][]byte{
+					"token": []byte("xoxb-0000"),
+				},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "empty", Namespace: "secrets"},
+			},
+		).
+		Build()
+	for _, c := range []struct {
+		name       string
+		secretName string
+		required   []string
+		wantReason string
+		wantErr    *ProviderError
+	}{
+		{
+			name:       "Found",
+			secretName: "token",
+			required:   []string{"token"},
+		},
+		{
+			name:       "NotFound",
+			secretName: "not-exists",
+			wantReason: "NotFound",
+			wantErr:    NewNotFoundPrivateKeyError(""),
+		},
+		{
+			name:       "MissingData",
+			secretName: "empty",
+			wantReason: "MissingData",
+			wantErr:    NewNotFoundPrivateKeyError(""),
+		},
+		{
+			name:       "MissingKey",
+			secretName: "token",
+			required:   []string{"password"},
+			wantReason: "MissingKey",
+			wantErr:    NewNotFoundPrivateKeyError(""),
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			var before float64
+			if len(c.wantReason) > 0 {
+				before = testutil.ToFloat64(secretLookupFailuresTotal.WithLabelValues("secrets", c.wantReason))
+			}
+			data, err := getSecretData(ctx, k, "secrets", c.secretName, c.required...)
+			if c.wantErr != nil {
+				if assert.NotNil(t, err) {
+					assert.Equal(t, c.wantErr.Code, err.Code)
+				}
+				assert.Equal(t, before+1, testutil.ToFloat64(secretLookupFailuresTotal.WithLabelValues("secrets", c.wantReason)))
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, "xoxb-0000", string(data["token"]))
+		})
+	}
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"knative.dev/pkg/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/go-logr/logr"
+	pipelinesv1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+
+	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
+)
+
+// RocketChat posts the messages to the channels with the REST API chat.postMessage.
+type RocketChat struct {
+	APIURL    string
+	UserID    string
+	AuthToken SecretString
+	Channels  []string
+	Alias     string
+}
+
+var _ Provider = (*RocketChat)(nil)
+
+func NewRocketChat(ctx context.Context, p *v1alpha1.Provider, k client.Client) (*RocketChat, *ProviderError) {
+	s := p.Spec.RocketChat
+	if s == nil {
+		return nil, NewInvalidProviderSpecError("missing value .rocketChat")
+	}
+	if len(s.BaseURL) < 1 {
+		return nil, NewInvalidProviderSpecError("missing value .rocketChat.baseURL")
+	}
+	data, perr := getSecretData(ctx, k, p.Namespace, s.SecretRef.Name, "user-id", "auth-token")
+	if perr != nil {
+		return nil, perr
+	}
+	a := &RocketChat{
+		APIURL:    strings.TrimSuffix(s.BaseURL, "/") + "/api/v1",
+		UserID:    strings.TrimSpace(string(data["user-id"])),
+		AuthToken: NewSecretString(strings.TrimSpace(string(data["auth-token"]))),
+		Channels:  s.Channels,
+	}
+	if s.Alias != nil {
+		a.Alias = *s.Alias
+	}
+	return a, nil
+}
+
+type rocketChatPostMessageRequest struct {
+	// Channel is the name of the channel with # or the username with @.
+	Channel     string           `json:"channel,omitempty"`
+	RoomID      string           `json:"roomId,omitempty"`
+	Alias       string           `json:"alias,omitempty"`
+	Text        string           `json:"text,omitempty"`
+	Attachments []chatAttachment `json:"attachments,omitempty"`
+}
+
+type rocketChatResponse struct {
+	Success   bool   `json:"success"`
+	Error     string `json:"error,omitempty"`
+	ErrorType string `json:"errorType,omitempty"`
+}
+
+func (a *RocketChat) Notify(ctx context.Context, pr *pipelinesv1beta1.PipelineRun) *ProviderError {
+	log := logr.FromContext(ctx).WithName("providers.rocketchat").
+		WithValues("providerType", "RocketChat", "pipelineRun", pr.Name)
+	cond := pr.Status.GetCondition(apis.ConditionSucceeded)
+	if cond == nil {
+		log.V(1).Info("PipelineRun has not condition, ignored")
+		return NewSkippedError("PipelineRun has not condition")
+	}
+	if cond.Status == corev1.ConditionUnknown {
+		log.V(2).Info("this run is not finished yet, skipped")
+		return NewSkippedError("this run is not finished yet")
+	}
+	attachment := newChatAttachment(newChatMessage(pr))
+	for _, channel := range a.Channels {
+		payload := &rocketChatPostMessageRequest{
+			Alias:       a.Alias,
+			Attachments: []chatAttachment{attachment},
+		}
+		if strings.HasPrefix(channel, "#") || strings.HasPrefix(channel, "@") {
+			payload.Channel = channel
+		} else {
+			payload.RoomID = channel
+		}
+		if perr := a.postMessage(ctx, payload); perr != nil {
+			return perr
+		}
+		log.V(2).Info("post message", "channel", channel)
+	}
+	return nil
+}
+
+func (a *RocketChat) postMessage(ctx context.Context, payload *rocketChatPostMessageRequest) *ProviderError {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return NewRuntimeError(fmt.Sprintf("failed to marshal Rocket.Chat message: %v", err))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.APIURL+"/chat.postMessage", bytes.NewReader(data))
+	if err != nil {
+		return NewRuntimeError(fmt.Sprintf("failed to create Rocket.Chat request: %v", err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-User-Id", a.UserID)
+	req.Header.Set("X-Auth-Token", a.AuthToken.GetNoRedactedString())
+	b, err := sendHTTP(req)
+	if err != nil {
+		return newRocketChatError(err)
+	}
+	var r rocketChatResponse
+	if err := json.Unmarshal(b, &r); err != nil {
+		return NewRuntimeError(fmt.Sprintf("failed to unmarshal Rocket.Chat response message: %v", err))
+	}
+	if !r.Success {
+		return NewRuntimeError(fmt.Sprintf("Rocket.Chat chat.postMessage: %s", r.Error))
+	}
+	return nil
+}
+
+// newRocketChatError converts the error response, which has the type of the error.
+func newRocketChatError(err error) *ProviderError {
+	var serr *httpStatusError
+	if errors.As(err, &serr) && !serr.RateLimited {
+		var r rocketChatResponse
+		_ = json.Unmarshal([]byte(serr.Body), &r)
+		switch r.ErrorType {
+		case "invalid-channel", "error-invalid-room", "error-room-not-found":
+			return NewChannelNotFoundError(fmt.Sprintf("Rocket.Chat chat.postMessage: %s", r.Error))
+		case "error-not-allowed":
+			return NewNotInChannelError(fmt.Sprintf("Rocket.Chat chat.postMessage: %s", r.Error))
+		}
+	}
+	return newHTTPError("Rocket.Chat chat.postMessage", err)
+}
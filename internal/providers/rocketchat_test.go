@@ -0,0 +1,212 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
+)
+
+func TestNewRocketChat(t *testing.T) {
+	k := fakeclient.NewClientBuilder().
+		WithObjects(
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "secret",
+					Namespace: "default",
+				},
+				Data: map[string][]byte{
+					"user-id":    []byte("user"),
+					"auth-token": []byte("token"),
+				},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "no-token",
+					Namespace: "default",
+				},
+				Data: map[string][]byte{
+					"user-id": []byte("user"),
+				},
+			},
+		).
+		Build()
+	for _, c := range []struct {
+		name    string
+		spec    *v1alpha1.RocketChatSpec
+		wantErr *ProviderError
+	}{
+		{
+			name: "Basic",
+			spec: &v1alpha1.RocketChatSpec{
+				BaseURL:   "https://rocketchat.example.com/",
+				SecretRef: corev1.LocalObjectReference{Name: "secret"},
+				Channels:  []string{"#general"},
+				Alias:     pointer.String("Tekton"),
+			},
+		},
+		{
+			name: "MissingAuthToken",
+			spec: &v1alpha1.RocketChatSpec{
+				BaseURL:   "https://rocketchat.example.com",
+				SecretRef: corev1.LocalObjectReference{Name: "no-token"},
+				Channels:  []string{"#general"},
+			},
+			wantErr: NewNotFoundPrivateKeyError(""),
+		},
+		{
+			name:    "RocketChatSpecNotFound",
+			wantErr: NewInvalidProviderSpecError(""),
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			p := &v1alpha1.Provider{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "provider",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.ProviderSpec{
+					Type:       "RocketChat",
+					RocketChat: c.spec,
+				},
+			}
+			a, err := NewRocketChat(ctx, p, k)
+			if c.wantErr != nil {
+				if assert.NotNil(t, err) {
+					assert.Equal(t, c.wantErr.Code, err.Code)
+				}
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, "https://rocketchat.example.com/api/v1", a.APIURL)
+			assert.Equal(t, "user", a.UserID)
+			assert.Equal(t, "token", a.AuthToken.GetNoRedactedString())
+			assert.Equal(t, "Tekton", a.Alias)
+		})
+	}
+}
+
+func TestRocketChatNotify(t *testing.T) {
+	attachments := `[{
+		"fallback": "Failed: sample-run.default",
+		"color": "#A30100",
+		"title": "sample-run.default",
+		"title_link": "https://dashboard.example.com/#/namespaces/default/pipelineruns/sample-run",
+		"text": "Failed: Tasks Completed: 1 (Failed: 1, Cancelled 0), Skipped: 0",
+		"fields": [
+			{"title": "Namespace", "value": "default", "short": true},
+			{"title": "Pipeline", "value": "sample", "short": true},
+			{"title": "Duration", "value": "1m0s", "short": true},
+			{"title": "Reason", "value": "Failed", "short": true}
+		]
+	}]`
+	for _, c := range []struct {
+		name        string
+		status      corev1.ConditionStatus
+		respCode    int
+		respBody    string
+		want        []string
+		wantErr     *ProviderError
+		wantSkipped bool
+	}{
+		{
+			name:     "Failed",
+			status:   corev1.ConditionFalse,
+			respCode: http.StatusOK,
+			respBody: `{"success": true}`,
+			want: []string{
+				`{"channel": "#general", "alias": "Tekton", "attachments": ` + attachments + `}`,
+				`{"roomId": "ROOM", "alias": "Tekton", "attachments": ` + attachments + `}`,
+			},
+		},
+		{
+			name:        "Running",
+			wantSkipped: true,
+			status:      corev1.ConditionUnknown,
+		},
+		{
+			name:     "ChannelNotFound",
+			status:   corev1.ConditionTrue,
+			respCode: http.StatusBadRequest,
+			respBody: `{"success": false, "error": "[invalid-channel]", "errorType": "invalid-channel"}`,
+			wantErr:  NewChannelNotFoundError(""),
+		},
+		{
+			name:     "Unauthorized",
+			status:   corev1.ConditionTrue,
+			respCode: http.StatusUnauthorized,
+			respBody: `{"status": "error", "message": "You must be logged in to do this."}`,
+			wantErr:  NewRuntimeError(""),
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			var bodies []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/api/v1/chat.postMessage", r.URL.Path)
+				assert.Equal(t, "user", r.Header.Get("X-User-Id"))
+				assert.Equal(t, "token", r.Header.Get("X-Auth-Token"))
+				var body json.RawMessage
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("failed to decode request: %v", err)
+				}
+				bodies = append(bodies, string(body))
+				w.WriteHeader(c.respCode)
+				w.Write([]byte(c.respBody))
+			}))
+			defer srv.Close()
+			pr := newTemplateSamplePipelineRun()
+			pr.Status.Conditions[0].Status = c.status
+			a := &RocketChat{
+				APIURL:    srv.URL + "/api/v1",
+				UserID:    "user",
+				AuthToken: NewSecretString("token"),
+				Channels:  []string{"#general", "ROOM"},
+				Alias:     "Tekton",
+			}
+			err := a.Notify(ctx, pr)
+			if c.wantErr != nil {
+				if assert.NotNil(t, err) {
+					assert.Equal(t, c.wantErr.Code, err.Code)
+				}
+				return
+			}
+			if c.wantSkipped {
+				assert.True(t, err.IsSkipped())
+			} else {
+				assert.Nil(t, err)
+			}
+			if assert.Len(t, bodies, len(c.want)) {
+				for i := range c.want {
+					assert.JSONEq(t, c.want[i], bodies[i])
+				}
+			}
+		})
+	}
+}
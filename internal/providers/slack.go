@@ -17,16 +17,13 @@ limitations under the License.
 package providers
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"net/http"
 	"strings"
+	"text/template"
 
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/types"
 	"knative.dev/pkg/apis"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -37,105 +34,348 @@ import (
 )
 
 const (
-	slackPostMessageURL = "https://slack.com/api/chat.postMessage"
+	slackDefaultAPIURL = "https://slack.com/api"
+
+	annotationSlackMessages = "integrations.tekton.ornew.io/slack-messages"
 )
 
 type SlackApp struct {
-	AccessToken SecretBytes
-	Channels    []v1alpha1.SlackChannel
+	APIURL              string
+	AccessToken         SecretBytes
+	Channels            []v1alpha1.SlackChannel
+	AutoJoin            bool
+	Template            *template.Template
+	UpdateMessage       bool
+	ReplyFailureDetails bool
+	MentionAuthors      *slackUserMapping
+	Interactivity       *slackInteractivity
+
+	// ProviderKey is the namespaced name of the provider,
+	// which identifies the messages posted by this provider.
+	ProviderKey string
+	Client      client.Client
 }
 
 var _ Provider = (*SlackApp)(nil)
+var _ ProgressNotifier = (*SlackApp)(nil)
 
 func NewSlackApp(ctx context.Context, p *v1alpha1.Provider, k client.Client) (*SlackApp, *ProviderError) {
 	s := p.Spec.SlackApp
 	if s == nil {
 		return nil, NewInvalidProviderSpecError("missing value .slackApp")
 	}
-	var key []byte
-	if s.AccessToken.SecretRef != nil {
-		var secret corev1.Secret
-		ref := types.NamespacedName{
-			Namespace: p.Namespace,
-			Name:      s.AccessToken.SecretRef.Name,
-		}
-		if err := k.Get(ctx, ref, &secret); err != nil {
-			return nil, NewNotFoundPrivateKeyError(fmt.Sprintf("failed to get secret: %v", err))
+	if s.AccessToken.SecretRef == nil {
+		return nil, NewInvalidProviderSpecError("missing valid values in .accessToken")
+	}
+	key, perr := getSecretValue(ctx, k, p.Namespace, s.AccessToken.SecretRef, "access-token")
+	if perr != nil {
+		return nil, perr
+	}
+	var tmpl *template.Template
+	if len(s.MessageTemplate) > 0 {
+		tmpl, perr = parseTemplate("messageTemplate", s.MessageTemplate)
+		if perr != nil {
+			return nil, perr
 		}
-		if secret.Data == nil {
-			return nil, NewNotFoundPrivateKeyError("data not found in secret")
+	}
+	var mentionAuthors *slackUserMapping
+	if s.MentionAuthors != nil {
+		mentionAuthors, perr = newSlackUserMapping(ctx, k, p.Namespace, s.MentionAuthors)
+		if perr != nil {
+			return nil, perr
 		}
-		if pem, ok := secret.Data["access-token"]; ok {
-			key = pem
-		} else {
-			return nil, NewNotFoundPrivateKeyError("missing key access-token")
+	}
+	var interactivity *slackInteractivity
+	if s.Interactivity != nil {
+		interactivity, perr = newSlackInteractivity(ctx, k, p.Namespace, s.Interactivity)
+		if perr != nil {
+			return nil, perr
 		}
-	} else {
-		return nil, NewInvalidProviderSpecError("missing valid values in .privateKey")
+	}
+	apiURL := slackDefaultAPIURL
+	if s.BaseURL != nil && len(*s.BaseURL) > 0 {
+		apiURL = strings.TrimSuffix(*s.BaseURL, "/")
 	}
 	return &SlackApp{
-		AccessToken: NewSecretBytes(key),
-		Channels:    s.Channels,
+		APIURL:              apiURL,
+		AccessToken:         NewSecretBytes(key),
+		Channels:            s.Channels,
+		AutoJoin:            s.AutoJoin,
+		Template:            tmpl,
+		UpdateMessage:       s.UpdateMessage,
+		ReplyFailureDetails: s.ReplyFailureDetails,
+		MentionAuthors:      mentionAuthors,
+		Interactivity:       interactivity,
+		ProviderKey:         fmt.Sprintf("%s/%s", p.Namespace, p.Name),
+		Client:              k,
 	}, nil
 }
 
-func postHTTP(url string, auth string, payload interface{}) (*http.Response, error) {
-	client := &http.Client{}
-	data, err := json.Marshal(payload)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal payload: %w", err)
-	}
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(data))
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", auth)
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	return resp, nil
+// slackMessageRef identifies a message posted to a channel.
+type slackMessageRef struct {
+	Channel   string `json:"channel"`
+	Timestamp string `json:"ts"`
 }
 
 func (a *SlackApp) Notify(ctx context.Context, pr *pipelinesv1beta1.PipelineRun) *ProviderError {
 	log := logr.FromContext(ctx).WithName("providers.slackapp").
 		WithValues("providerType", "SlackApp", "pipelineRun", pr.Name)
 	cond := pr.Status.GetCondition(apis.ConditionSucceeded)
-	switch cond.Status {
-	case corev1.ConditionTrue:
-	case corev1.ConditionFalse:
-	case corev1.ConditionUnknown:
+	if cond == nil {
+		log.V(1).Info("PipelineRun has not condition, ignored")
+		return NewSkippedError("PipelineRun has not condition")
+	}
+	if cond.Status == corev1.ConditionUnknown && !a.UpdateMessage {
 		log.V(2).Info("this run is not finished yet, skipped")
-		return nil
+		return NewSkippedError("this run is not finished yet")
 	}
-	for _, channel := range a.Channels {
-		c, perr := resolveSlackChannel(channel)
+	mentions := ""
+	if isMentionTarget(cond) {
+		mentions = a.getMentions(ctx, pr)
+	}
+	var refs []slackMessageRef
+	if a.UpdateMessage {
+		refs = getSlackMessageRefs(pr, a.ProviderKey)
+	}
+	var replies []string
+	if len(refs) > 0 {
+		if perr := a.updateMessages(ctx, pr, refs); perr != nil {
+			return perr
+		}
+		// the mentions in the updated messages don't notify the users.
+		if len(mentions) > 0 {
+			replies = append(replies, mentions)
+		}
+	} else {
+		var perr *ProviderError
+		refs, perr = a.postMessages(ctx, pr, mentions)
 		if perr != nil {
 			return perr
 		}
-		payload := newSlackMessageFromPipelineRun(pr)
-		payload.Channel = c
+		if a.UpdateMessage {
+			if perr := a.saveMessageRefs(ctx, pr, refs); perr != nil {
+				return perr
+			}
+		}
+	}
+	if cond.Status == corev1.ConditionFalse && a.ReplyFailureDetails {
+		if details := newSlackFailureDetails(pr); len(details) > 0 {
+			replies = append(replies, details)
+		}
+	}
+	if len(replies) > 0 {
+		return a.reply(ctx, refs, strings.Join(replies, "\n"))
+	}
+	return nil
+}
+
+// ReportsProgress returns true if the messages are updated.
+func (a *SlackApp) ReportsProgress() bool {
+	return a.UpdateMessage
+}
+
+// NotifyProgress updates the messages posted when the run started.
+func (a *SlackApp) NotifyProgress(ctx context.Context, pr *pipelinesv1beta1.PipelineRun) *ProviderError {
+	if !a.UpdateMessage {
+		return NewSkippedError("the messages are not updated")
+	}
+	refs := getSlackMessageRefs(pr, a.ProviderKey)
+	if len(refs) == 0 {
+		return NewSkippedError("no message is posted for the run")
+	}
+	return a.updateMessages(ctx, pr, refs)
+}
+
+func (a *SlackApp) postMessages(ctx context.Context, pr *pipelinesv1beta1.PipelineRun, mentions string) ([]slackMessageRef, *ProviderError) {
+	log := logr.FromContext(ctx).WithName("providers.slackapp")
+	refs := make([]slackMessageRef, 0, len(a.Channels))
+	for _, channel := range a.Channels {
+		c, perr := a.resolveChannel(ctx, channel)
+		if perr != nil {
+			return nil, perr
+		}
+		payload, perr := a.newMessage(pr, c, "", mentions)
+		if perr != nil {
+			return nil, perr
+		}
 		log.V(2).Info("payload", "payload", payload)
-		bearer := fmt.Sprintf("Bearer %s", a.AccessToken.GetNoRedactedString())
-		resp, err := postHTTP(slackPostMessageURL, bearer, payload)
-		if err != nil {
-			return NewRuntimeError(fmt.Sprintf("failed to post Slack message: %v", err))
+		var r slackPostMessageResponse
+		if perr := a.call(ctx, "chat.postMessage", payload, &r); perr != nil {
+			return nil, perr
+		}
+		log.V(2).Info("post message", "response", r)
+		if r.Channel != nil && r.Timestamp != nil {
+			refs = append(refs, slackMessageRef{Channel: *r.Channel, Timestamp: *r.Timestamp})
+		}
+	}
+	return refs, nil
+}
+
+func (a *SlackApp) updateMessages(ctx context.Context, pr *pipelinesv1beta1.PipelineRun, refs []slackMessageRef) *ProviderError {
+	log := logr.FromContext(ctx).WithName("providers.slackapp")
+	for _, ref := range refs {
+		payload, perr := a.newMessage(pr, ref.Channel, ref.Timestamp, "")
+		if perr != nil {
+			return perr
 		}
-		b, err := ioutil.ReadAll(resp.Body)
 		var r slackPostMessageResponse
-		if err = json.Unmarshal(b, &r); err != nil {
-			return NewRuntimeError(fmt.Sprintf("failed to unmarshal Slack response message: %v", err))
+		if perr := a.call(ctx, "chat.update", payload, &r); perr != nil {
+			return perr
+		}
+		log.V(2).Info("update message", "response", r)
+	}
+	return nil
+}
+
+// reply posts the text in the threads of the messages.
+func (a *SlackApp) reply(ctx context.Context, refs []slackMessageRef, text string) *ProviderError {
+	log := logr.FromContext(ctx).WithName("providers.slackapp")
+	for _, ref := range refs {
+		payload := &slackPostMessageRequest{
+			Channel:  ref.Channel,
+			ThreadTS: ref.Timestamp,
+			Text:     text,
 		}
-		if !r.OK {
-			errm := ""
-			if r.Error != nil {
-				errm = *r.Error
+		var r slackPostMessageResponse
+		if perr := a.call(ctx, "chat.postMessage", payload, &r); perr != nil {
+			return perr
+		}
+		log.V(2).Info("reply", "response", r)
+	}
+	return nil
+}
+
+// newMessage renders the message of the run, adding the buttons to the default message if the interactivity is enabled.
+// The templates add the buttons by themselves.
+func (a *SlackApp) newMessage(pr *pipelinesv1beta1.PipelineRun, channel, ts, mentions string) (interface{}, *ProviderError) {
+	m, perr := newSlackMessage(a.Template, pr, channel, ts, mentions)
+	if perr != nil {
+		return nil, perr
+	}
+	if req, ok := m.(*slackPostMessageRequest); ok && a.Interactivity != nil && len(req.Attachments) > 0 {
+		req.Attachments[0].Blocks = append(req.Attachments[0].Blocks, newSlackActionsBlock(a.ProviderKey, pr))
+	}
+	return m, nil
+}
+
+// newSlackMessage renders the message of the run by the template, or the default format.
+// The mentions are prepended to the text of the message.
+// It is shared by the Slack App and the Incoming Webhook.
+func newSlackMessage(t *template.Template, pr *pipelinesv1beta1.PipelineRun, channel, ts, mentions string) (interface{}, *ProviderError) {
+	if t == nil {
+		m := newSlackMessageFromPipelineRun(pr)
+		m.Channel = channel
+		m.Timestamp = ts
+		m.Text = mentions
+		return m, nil
+	}
+	m, perr := renderSlackTemplate(t, pr, channel, ts)
+	if perr != nil {
+		return nil, perr
+	}
+	if len(mentions) > 0 {
+		var text string
+		if raw, ok := m["text"]; ok {
+			if err := json.Unmarshal(raw, &text); err != nil {
+				return nil, NewFailedValidationError(fmt.Sprintf("the text of the template must be a string: %v", err))
 			}
-			return NewRuntimeError(fmt.Sprintf("get an error from Slack: %s", errm))
+			text = " " + text
 		}
-		log.V(2).Info("post message", "response", r)
+		m["text"], _ = json.Marshal(mentions + text)
+	}
+	return m, nil
+}
+
+// renderSlackTemplate renders the template into the message payload, which is
+// a JSON object with the blocks, the attachments and the text as Block Kit Builder outputs.
+func renderSlackTemplate(t *template.Template, pr *pipelinesv1beta1.PipelineRun, channel, ts string) (map[string]json.RawMessage, *ProviderError) {
+	b, perr := executeTemplate(t, pr)
+	if perr != nil {
+		return nil, perr
 	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, NewFailedValidationError(fmt.Sprintf("the template must render a JSON object: %v", err))
+	}
+	if m["blocks"] == nil && m["attachments"] == nil && m["text"] == nil {
+		return nil, NewFailedValidationError("the template must render blocks, attachments or text")
+	}
+	if len(channel) > 0 {
+		m["channel"], _ = json.Marshal(channel)
+	}
+	if len(ts) > 0 {
+		m["ts"], _ = json.Marshal(ts)
+	}
+	return m, nil
+}
+
+// Validate checks the template and the channels specified by name.
+func (a *SlackApp) Validate(ctx context.Context) *ProviderError {
+	if a.Template != nil {
+		if _, perr := renderSlackTemplate(a.Template, newTemplateSamplePipelineRun(), "", ""); perr != nil {
+			return perr
+		}
+	}
+	return a.validateChannels(ctx)
+}
+
+// call calls the Slack Web API method and checks the response.
+// The response is unmarshaled into out if it is not nil.
+func (a *SlackApp) call(ctx context.Context, method string, payload interface{}, out interface{}) *ProviderError {
+	bearer := fmt.Sprintf("Bearer %s", a.AccessToken.GetNoRedactedString())
+	b, err := postHTTP(ctx, a.APIURL+"/"+method, bearer, payload)
+	if err != nil {
+		return newHTTPError("Slack "+method, err)
+	}
+	var r slackResponse
+	if err = json.Unmarshal(b, &r); err != nil {
+		return NewRuntimeError(fmt.Sprintf("failed to unmarshal Slack response message: %v", err))
+	}
+	if !r.OK {
+		return newSlackError(r.Error)
+	}
+	if out != nil {
+		if err = json.Unmarshal(b, out); err != nil {
+			return NewRuntimeError(fmt.Sprintf("failed to unmarshal Slack response message: %v", err))
+		}
+	}
+	return nil
+}
+
+// getSlackMessageRefs returns the messages posted by the provider from the annotation of the run.
+func getSlackMessageRefs(pr *pipelinesv1beta1.PipelineRun, providerKey string) []slackMessageRef {
+	v := pr.Annotations[annotationSlackMessages]
+	if len(v) < 1 {
+		return nil
+	}
+	var messages map[string][]slackMessageRef
+	if err := json.Unmarshal([]byte(v), &messages); err != nil {
+		return nil
+	}
+	return messages[providerKey]
+}
+
+// saveMessageRefs persists the messages posted by the provider in the annotation of the run.
+func (a *SlackApp) saveMessageRefs(ctx context.Context, pr *pipelinesv1beta1.PipelineRun, refs []slackMessageRef) *ProviderError {
+	messages := map[string][]slackMessageRef{}
+	if v := pr.Annotations[annotationSlackMessages]; len(v) > 0 {
+		// overwrite the broken value
+		_ = json.Unmarshal([]byte(v), &messages)
+	}
+	messages[a.ProviderKey] = refs
+	b, err := json.Marshal(messages)
+	if err != nil {
+		return NewRuntimeError(fmt.Sprintf("failed to marshal Slack messages: %v", err))
+	}
+	patched := pr.DeepCopy()
+	if patched.Annotations == nil {
+		patched.Annotations = map[string]string{}
+	}
+	patched.Annotations[annotationSlackMessages] = string(b)
+	if err := a.Client.Patch(ctx, patched, client.MergeFrom(pr)); err != nil {
+		return NewRuntimeError(fmt.Sprintf("failed to save Slack messages: %v", err))
+	}
+	pr.Annotations = patched.Annotations
 	return nil
 }
 
@@ -150,9 +390,26 @@ type slackBlockElement struct {
 }
 
 type slackBlock struct {
+	Type string          `json:"type"`
+	Text *slackBlockText `json:"text,omitempty"`
+	// Elements is []slackBlockElement for the context blocks, or []slackButton for the actions blocks.
+	Elements interface{} `json:"elements,omitempty"`
+}
+
+type slackButton struct {
 	Type     string              `json:"type"`
-	Text     *slackBlockText     `json:"text,omitempty"`
-	Elements []slackBlockElement `json:"elements,omitempty"`
+	Text     slackBlockText      `json:"text"`
+	ActionID string              `json:"action_id"`
+	Value    string              `json:"value"`
+	Style    string              `json:"style,omitempty"`
+	Confirm  *slackConfirmDialog `json:"confirm,omitempty"`
+}
+
+type slackConfirmDialog struct {
+	Title   slackBlockText `json:"title"`
+	Text    slackBlockText `json:"text"`
+	Confirm slackBlockText `json:"confirm"`
+	Deny    slackBlockText `json:"deny"`
 }
 
 type slackAttachment struct {
@@ -161,9 +418,17 @@ type slackAttachment struct {
 }
 
 type slackPostMessageRequest struct {
-	Channel     string            `json:"channel"`
-	Fallback    string            `json:"fallback"`
-	Attachments []slackAttachment `json:"attachments"`
+	Channel     string            `json:"channel,omitempty"`
+	Timestamp   string            `json:"ts,omitempty"`
+	ThreadTS    string            `json:"thread_ts,omitempty"`
+	Text        string            `json:"text,omitempty"`
+	Fallback    string            `json:"fallback,omitempty"`
+	Attachments []slackAttachment `json:"attachments,omitempty"`
+}
+
+type slackResponse struct {
+	OK    bool    `json:"ok"`
+	Error *string `json:"error,omitempty"`
 }
 
 type slackPostMessageResponse struct {
@@ -174,37 +439,29 @@ type slackPostMessageResponse struct {
 }
 
 func newSlackMessageFromPipelineRun(pr *pipelinesv1beta1.PipelineRun) *slackPostMessageRequest {
-	cond := pr.Status.GetCondition(apis.ConditionSucceeded)
-	nn := fmt.Sprintf("%s.%s", pr.Name, pr.Namespace)
-	reason := cond.Reason
-	message := cond.Message
-	color := getSlackColor(cond)
-	duration := pr.Status.CompletionTime.Time.Sub(pr.Status.StartTime.Time)
-	context := strings.Builder{}
-	fmt.Fprint(&context, duration)
-	dashboardBaseURL := pr.Annotations[annotationTektonDashboardBaseURL]
-	if len(dashboardBaseURL) > 0 {
-		url := getDashboardPipelineRunURL(dashboardBaseURL, pr.Namespace, pr.Name)
-		fmt.Fprintf(&context, " | <%s|open dashboard>", url)
+	m := newChatMessage(pr)
+	context := m.Duration
+	if len(m.DashboardURL) > 0 {
+		context += fmt.Sprintf(" | <%s|open dashboard>", m.DashboardURL)
 	}
 	return &slackPostMessageRequest{
-		Fallback: fmt.Sprintf("%s: %s", reason, nn),
+		Fallback: m.Summary(),
 		Attachments: []slackAttachment{
 			{
-				Color: color,
+				Color: m.Color,
 				Blocks: []slackBlock{
 					{
 						Type: "section",
 						Text: &slackBlockText{
 							Type: "mrkdwn",
-							Text: fmt.Sprintf("*%s*", nn),
+							Text: fmt.Sprintf("*%s*", m.Title),
 						},
 					},
 					{
 						Type: "section",
 						Text: &slackBlockText{
 							Type: "plain_text",
-							Text: fmt.Sprintf("%s: %s", reason, message),
+							Text: fmt.Sprintf("%s: %s", m.Reason, m.Message),
 						},
 					},
 					{
@@ -212,7 +469,7 @@ func newSlackMessageFromPipelineRun(pr *pipelinesv1beta1.PipelineRun) *slackPost
 						Elements: []slackBlockElement{
 							{
 								Type: "mrkdwn",
-								Text: context.String(),
+								Text: context,
 							},
 						},
 					},
@@ -222,30 +479,7 @@ func newSlackMessageFromPipelineRun(pr *pipelinesv1beta1.PipelineRun) *slackPost
 	}
 }
 
-const (
-	slackColorGood    = "#2EB886"
-	slackColorWarning = "#DAA038"
-	slackColorDanger  = "#A30100"
-)
-
-func getSlackColor(c *apis.Condition) string {
-	switch c.Status {
-	case corev1.ConditionUnknown:
-		return slackColorWarning
-	case corev1.ConditionTrue:
-		return slackColorGood
-	case corev1.ConditionFalse:
-		return slackColorDanger
-	}
-	return slackColorDanger
-}
-
-func resolveSlackChannel(c v1alpha1.SlackChannel) (string, *ProviderError) {
-	if c.ID != nil {
-		return *c.ID, nil
-	}
-	if c.Name != nil {
-		return *c.Name, nil
-	}
-	return "", NewInvalidProviderSpecError("Slack channel id or name is required")
+// newSlackFailureDetails returns the reasons of the failed tasks of the run in mrkdwn.
+func newSlackFailureDetails(pr *pipelinesv1beta1.PipelineRun) string {
+	return formatFailureDetails(getChatFailedTasks(pr), "*%s*")
 }
@@ -1,6 +1,7 @@
 package providers
 
 import (
+	"encoding/json"
 	"testing"
 	"time"
 
@@ -8,10 +9,71 @@ import (
 	pipelinesv1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
 	knativeapis "knative.dev/pkg/apis"
 	knativeapisduckv1beta1 "knative.dev/pkg/apis/duck/v1beta1"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
 )
 
+func TestNewSlackApp(t *testing.T) {
+	k := fakeclient.NewClientBuilder().
+		WithObjects(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "secret",
+				Namespace: "default",
+			},
+			Data: map[string][]byte{
+				"access-token": []byte("xoxb-xxxx"),
+			},
+		}).
+		Build()
+	for _, c := range []struct {
+		name       string
+		baseURL    *string
+		wantAPIURL string
+	}{
+		{
+			name:       "Default",
+			wantAPIURL: "https://slack.com/api",
+		},
+		{
+			name:       "BaseURL",
+			baseURL:    pointer.String("https://slack-proxy.example.com/api/"),
+			wantAPIURL: "https://slack-proxy.example.com/api",
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			p := &v1alpha1.Provider{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "provider",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.ProviderSpec{
+					Type: "SlackApp",
+					SlackApp: &v1alpha1.SlackAppSpec{
+						AccessToken: v1alpha1.AccessTokenSource{
+							SecretRef: &v1alpha1.LocalSecretKeyReference{
+								LocalObjectReference: corev1.LocalObjectReference{Name: "secret"},
+							},
+						},
+						BaseURL:  c.baseURL,
+						Channels: []v1alpha1.SlackChannel{{ID: pointer.String("C1")}},
+					},
+				},
+			}
+			a, err := NewSlackApp(ctx, p, k)
+			assert.Nil(t, err)
+			assert.Equal(t, c.wantAPIURL, a.APIURL)
+			assert.Equal(t, "xoxb-xxxx", a.AccessToken.GetNoRedactedString())
+		})
+	}
+}
+
 func TestNewSlackMessageFromPipelineRun(t *testing.T) {
 	for _, c := range []struct {
 		name string
@@ -57,7 +119,7 @@ func TestNewSlackMessageFromPipelineRun(t *testing.T) {
 				Fallback: "Reason: foo.bar",
 				Attachments: []slackAttachment{
 					{
-						Color: slackColorGood,
+						Color: chatColorGood,
 						Blocks: []slackBlock{
 							{
 								Type: "section",
@@ -128,7 +190,7 @@ func TestNewSlackMessageFromPipelineRun(t *testing.T) {
 				Fallback: "Reason: foo.bar",
 				Attachments: []slackAttachment{
 					{
-						Color: slackColorDanger,
+						Color: chatColorDanger,
 						Blocks: []slackBlock{
 							{
 								Type: "section",
@@ -166,3 +228,182 @@ func TestNewSlackMessageFromPipelineRun(t *testing.T) {
 		})
 	}
 }
+
+func TestNewSlackMessageFromRunningPipelineRun(t *testing.T) {
+	pr := &pipelinesv1beta1.PipelineRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "bar",
+		},
+		Status: pipelinesv1beta1.PipelineRunStatus{
+			Status: knativeapisduckv1beta1.Status{
+				Conditions: []knativeapis.Condition{
+					{
+						Type:   knativeapis.ConditionSucceeded,
+						Status: corev1.ConditionUnknown,
+						Reason: "Running",
+					},
+				},
+			},
+		},
+	}
+	act := newSlackMessageFromPipelineRun(pr)
+	if assert.Len(t, act.Attachments, 1) && assert.Len(t, act.Attachments[0].Blocks, 3) {
+		assert.Equal(t, "running", act.Attachments[0].Blocks[2].Elements.([]slackBlockElement)[0].Text)
+	}
+}
+
+func TestSlackMessageRefs(t *testing.T) {
+	s := runtime.NewScheme()
+	assert.Nil(t, pipelinesv1beta1.AddToScheme(s))
+	pr := &pipelinesv1beta1.PipelineRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "bar",
+			Annotations: map[string]string{
+				"integrations.tekton.ornew.io/slack-messages": `{"default/other":[{"channel":"C0","ts":"0.1"}]}`,
+			},
+		},
+	}
+	k := fakeclient.NewClientBuilder().WithScheme(s).WithObjects(pr.DeepCopy()).Build()
+	a := &SlackApp{
+		ProviderKey: "default/slack",
+		Client:      k,
+	}
+	assert.Empty(t, getSlackMessageRefs(pr, a.ProviderKey))
+
+	refs := []slackMessageRef{{Channel: "C1", Timestamp: "1.2"}}
+	assert.Nil(t, a.saveMessageRefs(ctx, pr, refs))
+	assert.Equal(t, refs, getSlackMessageRefs(pr, "default/slack"))
+
+	var saved pipelinesv1beta1.PipelineRun
+	assert.Nil(t, k.Get(ctx, types.NamespacedName{Namespace: "bar", Name: "foo"}, &saved))
+	assert.Equal(t, refs, getSlackMessageRefs(&saved, "default/slack"))
+	assert.Equal(t, []slackMessageRef{{Channel: "C0", Timestamp: "0.1"}}, getSlackMessageRefs(&saved, "default/other"))
+}
+
+func TestSlackAppNotifyProgressSkipped(t *testing.T) {
+	pr := newTemplateSamplePipelineRun()
+	a := &SlackApp{ProviderKey: "default/slack"}
+	assert.True(t, a.NotifyProgress(ctx, pr).IsSkipped())
+
+	// no message is posted for the run yet.
+	a.UpdateMessage = true
+	assert.True(t, a.NotifyProgress(ctx, pr).IsSkipped())
+}
+
+func TestNewSlackFailureDetails(t *testing.T) {
+	newTaskRun := func(task string, status corev1.ConditionStatus, reason, message string) *pipelinesv1beta1.PipelineRunTaskRunStatus {
+		return &pipelinesv1beta1.PipelineRunTaskRunStatus{
+			PipelineTaskName: task,
+			Status: &pipelinesv1beta1.TaskRunStatus{
+				Status: knativeapisduckv1beta1.Status{
+					Conditions: []knativeapis.Condition{
+						{
+							Type:    knativeapis.ConditionSucceeded,
+							Status:  status,
+							Reason:  reason,
+							Message: message,
+						},
+					},
+				},
+			},
+		}
+	}
+	pr := &pipelinesv1beta1.PipelineRun{
+		Status: pipelinesv1beta1.PipelineRunStatus{
+			PipelineRunStatusFields: pipelinesv1beta1.PipelineRunStatusFields{
+				TaskRuns: map[string]*pipelinesv1beta1.PipelineRunTaskRunStatus{
+					"foo-test-abc":  newTaskRun("test", corev1.ConditionFalse, "Failed", "step unit exited with code 1"),
+					"foo-build-abc": newTaskRun("build", corev1.ConditionTrue, "Succeeded", ""),
+					"foo-lint-abc":  newTaskRun("lint", corev1.ConditionFalse, "TaskRunTimeout", "timed out"),
+					"foo-push-abc":  {PipelineTaskName: "push"},
+				},
+			},
+		},
+	}
+	assert.Equal(t, "*lint* (foo-lint-abc) TaskRunTimeout: timed out\n*test* (foo-test-abc) Failed: step unit exited with code 1",
+		newSlackFailureDetails(pr))
+}
+
+func TestRenderSlackTemplate(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		template string
+		ts       string
+		want     string
+		wantErr  *ProviderError
+	}{
+		{
+			name:     "Blocks",
+			template: `{"blocks":[{"type":"section","text":{"type":"mrkdwn","text":{{ json (printf "*%s* %s" .Name .Reason) }}}}]}`,
+			want:     `{"blocks":[{"type":"section","text":{"type":"mrkdwn","text":"*sample-run* Failed"}}],"channel":"C1"}`,
+		},
+		{
+			name:     "Update",
+			template: `{"text":{{ json .Message }}}`,
+			ts:       "1.2",
+			want:     `{"channel":"C1","text":"Tasks Completed: 1 (Failed: 1, Cancelled 0), Skipped: 0","ts":"1.2"}`,
+		},
+		{
+			name: "Documented",
+			template: `{
+  "text": {{ json (printf "%s: %s" .Reason .Name) }},
+  "attachments": [{
+    "color": "{{ if .Succeeded }}#2EB886{{ else if .Failed }}#A30100{{ else }}#DAA038{{ end }}",
+    "blocks": [
+      {"type": "section", "text": {"type": "mrkdwn", "text": {{ json (printf "*%s* %s" .Name .Reason) }}}},
+      {"type": "section", "fields": [
+        {"type": "mrkdwn", "text": {{ json (printf "*Revision*\n%s" (index .Params "revision")) }}},
+        {"type": "mrkdwn", "text": {{ json (printf "*Duration*\n%s" .Duration) }}}
+      ]}
+      {{- range .TaskRuns }}{{ if .Failed }},
+      {"type": "context", "elements": [{"type": "mrkdwn", "text": {{ json (printf ":x: %s: %s" .PipelineTaskName (truncate 200 .Message)) }}}]}
+      {{- end }}{{ end }}
+      {{- if .DashboardURL }},
+      {"type": "actions", "elements": [{"type": "button", "text": {"type": "plain_text", "text": "Open Dashboard"}, "url": {{ json .DashboardURL }}}]}
+      {{- end }}
+    ]
+  }]
+}`,
+		},
+		{
+			name:     "InvalidJSON",
+			template: `{"text":{{ .Message }}}`,
+			wantErr:  NewFailedValidationError(""),
+		},
+		{
+			name:     "Empty",
+			template: `{"unfurl_links":false}`,
+			wantErr:  NewFailedValidationError(""),
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			tmpl, perr := parseTemplate(c.name, c.template)
+			assert.Nil(t, perr)
+			m, err := renderSlackTemplate(tmpl, newTemplateSamplePipelineRun(), "C1", c.ts)
+			if c.wantErr != nil {
+				if assert.NotNil(t, err) {
+					assert.Equal(t, c.wantErr.Code, err.Code)
+				}
+				return
+			}
+			assert.Nil(t, err)
+			if len(c.want) > 0 {
+				b, _ := json.Marshal(m)
+				assert.JSONEq(t, c.want, string(b))
+			}
+		})
+	}
+}
+
+func TestSlackAppValidateTemplate(t *testing.T) {
+	tmpl, perr := parseTemplate("test", `{{ .Name }}`)
+	assert.Nil(t, perr)
+	a := &SlackApp{Template: tmpl}
+	err := a.Validate(ctx)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, ErrorCodeFailedValidation, err.Code)
+	}
+}
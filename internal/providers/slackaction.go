@@ -0,0 +1,425 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/pkg/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/go-logr/logr"
+	pipelinesv1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+
+	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
+)
+
+const (
+	// SlackActionPath is the path of the Request URL of the interactivity of the Slack App.
+	SlackActionPath = "/slack/actions"
+
+	slackActionRerun  = "tekton-integration-rerun"
+	slackActionCancel = "tekton-integration-cancel"
+
+	annotationRerunOf = "integrations.tekton.ornew.io/rerun-of"
+
+	// slackRequestMaxAge is the tolerance of the timestamp of the requests to prevent the replay attacks.
+	slackRequestMaxAge = 5 * time.Minute
+	slackActionMaxBody = 1 << 20
+)
+
+// rerunExcludedAnnotations are not copied to the re-run, since they record the notifications of the original run.
+var rerunExcludedAnnotations = []string{
+	"integrations.tekton.ornew.io/last-status",
+	"integrations.tekton.ornew.io/last-progress",
+	annotationSlackMessages,
+	corev1.LastAppliedConfigAnnotation,
+}
+
+// slackInteractivity holds the settings of the buttons of the messages.
+type slackInteractivity struct {
+	SigningSecret     SecretBytes
+	AllowedUsers      []string
+	AllowedUserGroups []string
+}
+
+func newSlackInteractivity(ctx context.Context, k client.Client, namespace string, s *v1alpha1.SlackInteractivity) (*slackInteractivity, *ProviderError) {
+	if s.SigningSecret.SecretRef == nil {
+		return nil, NewInvalidProviderSpecError("missing valid values in .interactivity.signingSecret")
+	}
+	secret, perr := getSecretValue(ctx, k, namespace, s.SigningSecret.SecretRef, "signing-secret")
+	if perr != nil {
+		return nil, perr
+	}
+	return &slackInteractivity{
+		SigningSecret:     NewSecretBytes(secret),
+		AllowedUsers:      s.AllowedUsers,
+		AllowedUserGroups: s.AllowedUserGroups,
+	}, nil
+}
+
+// verify checks the signature of the request from Slack.
+// See https://api.slack.com/authentication/verifying-requests-from-slack
+func (s *slackInteractivity) verify(h http.Header, body []byte, now time.Time) error {
+	ts := h.Get("X-Slack-Request-Timestamp")
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp: %q", ts)
+	}
+	if d := now.Sub(time.Unix(sec, 0)); d > slackRequestMaxAge || d < -slackRequestMaxAge {
+		return fmt.Errorf("the timestamp is too old or too new: %s", ts)
+	}
+	mac := hmac.New(sha256.New, s.SigningSecret.GetNoRedacted())
+	fmt.Fprintf(mac, "v0:%s:", ts)
+	mac.Write(body)
+	want := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(want), []byte(h.Get("X-Slack-Signature"))) {
+		return errors.New("the signature does not match")
+	}
+	return nil
+}
+
+// slackActionValue is the value of the buttons, which identifies the provider and the run.
+type slackActionValue struct {
+	Provider    string `json:"provider"`
+	PipelineRun string `json:"pipelineRun"`
+}
+
+// newSlackActionsBlock returns the buttons of the run, Cancel while it is running, and Re-run after finished.
+func newSlackActionsBlock(providerKey string, pr *pipelinesv1beta1.PipelineRun) slackBlock {
+	value, _ := json.Marshal(&slackActionValue{
+		Provider:    providerKey,
+		PipelineRun: fmt.Sprintf("%s/%s", pr.Namespace, pr.Name),
+	})
+	button := slackButton{
+		Type:     "button",
+		Text:     slackBlockText{Type: "plain_text", Text: "Re-run"},
+		ActionID: slackActionRerun,
+		Value:    string(value),
+	}
+	cond := pr.Status.GetCondition(apis.ConditionSucceeded)
+	if cond != nil && cond.Status == corev1.ConditionUnknown {
+		button = slackButton{
+			Type:     "button",
+			Text:     slackBlockText{Type: "plain_text", Text: "Cancel"},
+			ActionID: slackActionCancel,
+			Value:    string(value),
+			Style:    "danger",
+			Confirm: &slackConfirmDialog{
+				Title:   slackBlockText{Type: "plain_text", Text: "Cancel the run?"},
+				Text:    slackBlockText{Type: "mrkdwn", Text: fmt.Sprintf("*%s/%s* will be cancelled.", pr.Namespace, pr.Name)},
+				Confirm: slackBlockText{Type: "plain_text", Text: "Cancel"},
+				Deny:    slackBlockText{Type: "plain_text", Text: "Keep running"},
+			},
+		}
+	}
+	return slackBlock{
+		Type:     "actions",
+		Elements: []slackButton{button},
+	}
+}
+
+type slackInteractionPayload struct {
+	Type string `json:"type"`
+	User struct {
+		ID string `json:"id"`
+	} `json:"user"`
+	Actions []struct {
+		ActionID string `json:"action_id"`
+		Value    string `json:"value"`
+	} `json:"actions"`
+	ResponseURL string `json:"response_url"`
+}
+
+// SlackActionHandler handles the requests from Slack when the buttons of the messages are pushed.
+type SlackActionHandler struct {
+	Client client.Client
+	now    func() time.Time
+}
+
+var _ http.Handler = (*SlackActionHandler)(nil)
+
+func NewSlackActionHandler(k client.Client) *SlackActionHandler {
+	return &SlackActionHandler{
+		Client: k,
+		now:    time.Now,
+	}
+}
+
+func (h *SlackActionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logr.FromContext(ctx).WithName("providers.slackaction")
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, slackActionMaxBody))
+	if err != nil {
+		http.Error(w, "failed to read the request", http.StatusBadRequest)
+		return
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+	var p slackInteractionPayload
+	if err := json.Unmarshal([]byte(form.Get("payload")), &p); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	action, value, ok := findSlackAction(&p)
+	if !ok {
+		// the other interactions of the app are not for this controller.
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	// only the signing secret is read for the unverified request, and the unknown providers
+	// are rejected like the invalid signatures, so that the request can't probe the providers.
+	interactivity, perr := h.getSlackInteractivity(ctx, value.Provider)
+	if perr != nil {
+		log.Info("rejected the request of the unknown provider", "provider", value.Provider, "reason", perr.Error())
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if err := interactivity.verify(r.Header, body, h.now()); err != nil {
+		log.Info("rejected the unverified request", "provider", value.Provider, "reason", err.Error())
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	a, perr := h.getSlackApp(ctx, value.Provider)
+	if perr != nil {
+		log.Error(perr, "failed to get the provider of the action", "provider", value.Provider)
+		http.Error(w, "failed to get the provider", http.StatusInternalServerError)
+		return
+	}
+	text := a.handleAction(ctx, p.User.ID, action, value.PipelineRun)
+	if len(p.ResponseURL) > 0 {
+		res := map[string]interface{}{
+			"response_type":    "ephemeral",
+			"replace_original": false,
+			"text":             text,
+		}
+		if _, err := postHTTP(ctx, p.ResponseURL, "", res); err != nil {
+			log.Error(err, "failed to respond to the action")
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// findSlackAction returns the first action of the buttons added by this controller.
+func findSlackAction(p *slackInteractionPayload) (string, *slackActionValue, bool) {
+	if p.Type != "block_actions" {
+		return "", nil, false
+	}
+	for _, action := range p.Actions {
+		if action.ActionID != slackActionRerun && action.ActionID != slackActionCancel {
+			continue
+		}
+		var v slackActionValue
+		if err := json.Unmarshal([]byte(action.Value), &v); err != nil {
+			continue
+		}
+		return action.ActionID, &v, true
+	}
+	return "", nil, false
+}
+
+// getProvider returns the Provider of the key, e.g. default/slack.
+func (h *SlackActionHandler) getProvider(ctx context.Context, key string) (*v1alpha1.Provider, *ProviderError) {
+	nn, ok := parseNamespacedName(key)
+	if !ok {
+		return nil, NewFailedValidationError(fmt.Sprintf("invalid provider: %q", key))
+	}
+	var p v1alpha1.Provider
+	if err := h.Client.Get(ctx, nn, &p); err != nil {
+		return nil, NewRuntimeError(fmt.Sprintf("failed to get Provider %s: %v", key, err))
+	}
+	return &p, nil
+}
+
+// getSlackInteractivity returns the interactivity of the SlackApp Provider of the key,
+// reading only the signing secret to verify the request.
+func (h *SlackActionHandler) getSlackInteractivity(ctx context.Context, key string) (*slackInteractivity, *ProviderError) {
+	p, perr := h.getProvider(ctx, key)
+	if perr != nil {
+		return nil, perr
+	}
+	if p.Spec.Type != "SlackApp" || p.Spec.SlackApp == nil || p.Spec.SlackApp.Interactivity == nil {
+		return nil, NewInvalidProviderSpecError(fmt.Sprintf("the interactivity of Provider %s is disabled", key))
+	}
+	return newSlackInteractivity(ctx, h.Client, p.Namespace, p.Spec.SlackApp.Interactivity)
+}
+
+// getSlackApp returns the SlackApp Provider of the key. It must be called after the request is verified,
+// since it reads all the secrets of the Provider.
+func (h *SlackActionHandler) getSlackApp(ctx context.Context, key string) (*SlackApp, *ProviderError) {
+	p, perr := h.getProvider(ctx, key)
+	if perr != nil {
+		return nil, perr
+	}
+	a, perr := NewSlackApp(ctx, p, h.Client)
+	if perr != nil {
+		return nil, perr
+	}
+	if a.Interactivity == nil {
+		return nil, NewInvalidProviderSpecError(fmt.Sprintf("the interactivity of Provider %s is disabled", key))
+	}
+	return a, nil
+}
+
+// handleAction runs the action if the user is allowed, and returns the result to the user.
+func (a *SlackApp) handleAction(ctx context.Context, user, action, run string) string {
+	log := logr.FromContext(ctx).WithName("providers.slackaction").
+		WithValues("user", user, "action", action, "pipelineRun", run)
+	allowed, perr := a.isAllowedUser(ctx, user)
+	if perr != nil {
+		log.Error(perr, "failed to authorize the user")
+		return "Failed to authorize you. Please try again later."
+	}
+	if !allowed {
+		log.Info("the user is not allowed to run the action")
+		return fmt.Sprintf("You are not allowed to run the action on %s.", run)
+	}
+	nn, ok := parseNamespacedName(run)
+	if !ok {
+		return fmt.Sprintf("Invalid PipelineRun: %s", run)
+	}
+	// the Provider can act only on the runs in its namespace,
+	// otherwise anyone who can create a Provider acts on the runs of the other namespaces.
+	if providerNN, ok := parseNamespacedName(a.ProviderKey); !ok || nn.Namespace != providerNN.Namespace {
+		log.Info("the PipelineRun is not in the namespace of the provider", "provider", a.ProviderKey)
+		return fmt.Sprintf("You are not allowed to run the action on %s.", run)
+	}
+	var pr pipelinesv1beta1.PipelineRun
+	if err := a.Client.Get(ctx, nn, &pr); err != nil {
+		if apierrors.IsNotFound(err) {
+			return fmt.Sprintf("PipelineRun %s is not found.", run)
+		}
+		log.Error(err, "failed to get PipelineRun")
+		return fmt.Sprintf("Failed to get PipelineRun %s.", run)
+	}
+	switch action {
+	case slackActionRerun:
+		rerun := newRerunPipelineRun(&pr)
+		if err := a.Client.Create(ctx, rerun); err != nil {
+			log.Error(err, "failed to re-run PipelineRun")
+			return fmt.Sprintf("Failed to re-run %s: %v", run, err)
+		}
+		log.Info("re-run PipelineRun from Slack", "rerun", rerun.Name)
+		return fmt.Sprintf("<@%s> re-ran %s as %s.", user, run, rerun.Name)
+	case slackActionCancel:
+		if pr.IsDone() {
+			return fmt.Sprintf("%s has already finished.", run)
+		}
+		patched := pr.DeepCopy()
+		patched.Spec.Status = pipelinesv1beta1.PipelineRunSpecStatusCancelled
+		if err := a.Client.Patch(ctx, patched, client.MergeFrom(&pr)); err != nil {
+			log.Error(err, "failed to cancel PipelineRun")
+			return fmt.Sprintf("Failed to cancel %s: %v", run, err)
+		}
+		log.Info("cancelled PipelineRun from Slack")
+		return fmt.Sprintf("<@%s> cancelled %s.", user, run)
+	}
+	return fmt.Sprintf("Unknown action: %s", action)
+}
+
+// isAllowedUser returns true if the user is listed in the allowed users or is a member of the allowed user groups.
+func (a *SlackApp) isAllowedUser(ctx context.Context, user string) (bool, *ProviderError) {
+	for _, u := range a.Interactivity.AllowedUsers {
+		if u == user {
+			return true, nil
+		}
+	}
+	for _, g := range a.Interactivity.AllowedUserGroups {
+		q := url.Values{}
+		q.Set("usergroup", g)
+		var r struct {
+			OK    bool     `json:"ok"`
+			Error *string  `json:"error,omitempty"`
+			Users []string `json:"users"`
+		}
+		if perr := a.get(ctx, "usergroups.users.list", q, &r); perr != nil {
+			return false, perr
+		}
+		if !r.OK {
+			return false, newSlackError(r.Error)
+		}
+		for _, u := range r.Users {
+			if u == user {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// newRerunPipelineRun returns a copy of the run to be created.
+func newRerunPipelineRun(pr *pipelinesv1beta1.PipelineRun) *pipelinesv1beta1.PipelineRun {
+	generateName := pr.GenerateName
+	if len(generateName) < 1 {
+		generateName = pr.Name + "-"
+	}
+	labels := make(map[string]string, len(pr.Labels))
+	for k, v := range pr.Labels {
+		labels[k] = v
+	}
+	annotations := make(map[string]string, len(pr.Annotations)+1)
+	for k, v := range pr.Annotations {
+		annotations[k] = v
+	}
+	for _, k := range rerunExcludedAnnotations {
+		delete(annotations, k)
+	}
+	annotations[annotationRerunOf] = pr.Name
+	spec := pr.Spec.DeepCopy()
+	spec.Status = ""
+	return &pipelinesv1beta1.PipelineRun{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: generateName,
+			Namespace:    pr.Namespace,
+			Labels:       labels,
+			Annotations:  annotations,
+		},
+		Spec: *spec,
+	}
+}
+
+func parseNamespacedName(s string) (types.NamespacedName, bool) {
+	parts := strings.SplitN(s, "/", 2)
+	if len(parts) != 2 || len(parts[0]) < 1 || len(parts[1]) < 1 {
+		return types.NamespacedName{}, false
+	}
+	return types.NamespacedName{Namespace: parts[0], Name: parts[1]}, true
+}
@@ -0,0 +1,353 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	pipelinesv1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+
+	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
+)
+
+func signSlackRequest(secret, ts string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%s:", ts)
+	mac.Write(body)
+	return "v0=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestSlackInteractivityVerify(t *testing.T) {
+	now := time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)
+	ts := strconv.FormatInt(now.Unix(), 10)
+	old := strconv.FormatInt(now.Add(-10*time.Minute).Unix(), 10)
+	body := []byte("payload=%7B%7D")
+	s := &slackInteractivity{SigningSecret: NewSecretBytes([]byte("secret"))}
+	for _, c := range []struct {
+		name      string
+		timestamp string
+		signature string
+		wantErr   bool
+	}{
+		{
+			name:      "Valid",
+			timestamp: ts,
+			signature: signSlackRequest("secret", ts, body),
+		},
+		{
+			name:      "WrongSecret",
+			timestamp: ts,
+			signature: signSlackRequest("wrong", ts, body),
+			wantErr:   true,
+		},
+		{
+			name:      "Expired",
+			timestamp: old,
+			signature: signSlackRequest("secret", old, body),
+			wantErr:   true,
+		},
+		{
+			name:      "MissingTimestamp",
+			signature: signSlackRequest("secret", "", body),
+			wantErr:   true,
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			h := http.Header{}
+			h.Set("X-Slack-Request-Timestamp", c.timestamp)
+			h.Set("X-Slack-Signature", c.signature)
+			err := s.verify(h, body, now)
+			if c.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+		})
+	}
+}
+
+func TestNewSlackActionsBlock(t *testing.T) {
+	pr := newTemplateSamplePipelineRun()
+	b, err := json.Marshal(newSlackActionsBlock("default/slack", pr))
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"type":"actions","elements":[{"type":"button","text":{"type":"plain_text","text":"Re-run"},"action_id":"tekton-integration-rerun","value":"{\"provider\":\"default/slack\",\"pipelineRun\":\"default/sample-run\"}"}]}`, string(b))
+
+	pr.Status.Conditions[0].Status = corev1.ConditionUnknown
+	block := newSlackActionsBlock("default/slack", pr)
+	if buttons, ok := block.Elements.([]slackButton); assert.True(t, ok) && assert.Len(t, buttons, 1) {
+		assert.Equal(t, slackActionCancel, buttons[0].ActionID)
+		assert.Equal(t, "danger", buttons[0].Style)
+		assert.NotNil(t, buttons[0].Confirm)
+	}
+}
+
+func TestNewRerunPipelineRun(t *testing.T) {
+	pr := newTemplateSamplePipelineRun()
+	pr.Labels = map[string]string{"app": "sample"}
+	pr.Annotations[annotationSlackMessages] = `{}`
+	pr.Annotations["integrations.tekton.ornew.io/last-status"] = "False"
+	pr.Spec.Status = pipelinesv1beta1.PipelineRunSpecStatusCancelled
+	r := newRerunPipelineRun(pr)
+	assert.Equal(t, "sample-run-", r.GenerateName)
+	assert.Empty(t, r.Name)
+	assert.Equal(t, "default", r.Namespace)
+	assert.Equal(t, map[string]string{"app": "sample"}, r.Labels)
+	assert.Equal(t, "sample-run", r.Annotations[annotationRerunOf])
+	assert.Equal(t, "https://dashboard.example.com", r.Annotations[annotationTektonDashboardBaseURL])
+	assert.NotContains(t, r.Annotations, annotationSlackMessages)
+	assert.NotContains(t, r.Annotations, "integrations.tekton.ornew.io/last-status")
+	assert.Equal(t, pr.Spec.Params, r.Spec.Params)
+	assert.Empty(t, r.Spec.Status)
+	assert.Empty(t, r.Status.Conditions)
+
+	pr.GenerateName = "sample-"
+	assert.Equal(t, "sample-", newRerunPipelineRun(pr).GenerateName)
+}
+
+func TestSlackActionHandler(t *testing.T) {
+	now := time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)
+	for _, c := range []struct {
+		name          string
+		user          string
+		action        string
+		provider      string
+		run           string
+		running       bool
+		secret        string
+		wantCode      int
+		wantText      string
+		wantRerun     bool
+		wantCancelled bool
+	}{
+		{
+			name:      "Rerun",
+			user:      "UALLOWED",
+			action:    slackActionRerun,
+			secret:    "signing",
+			wantCode:  http.StatusOK,
+			wantText:  "<@UALLOWED> re-ran default/sample-run as sample-run-",
+			wantRerun: true,
+		},
+		{
+			name:          "CancelByGroupMember",
+			user:          "UGROUP",
+			action:        slackActionCancel,
+			running:       true,
+			secret:        "signing",
+			wantCode:      http.StatusOK,
+			wantText:      "<@UGROUP> cancelled default/sample-run.",
+			wantCancelled: true,
+		},
+		{
+			name:     "CancelFinished",
+			user:     "UALLOWED",
+			action:   slackActionCancel,
+			secret:   "signing",
+			wantCode: http.StatusOK,
+			wantText: "default/sample-run has already finished.",
+		},
+		{
+			name:     "NotAllowed",
+			user:     "UOTHER",
+			action:   slackActionRerun,
+			secret:   "signing",
+			wantCode: http.StatusOK,
+			wantText: "You are not allowed to run the action on default/sample-run.",
+		},
+		{
+			name:     "OtherNamespace",
+			user:     "UALLOWED",
+			action:   slackActionCancel,
+			run:      "other/sample-run",
+			running:  true,
+			secret:   "signing",
+			wantCode: http.StatusOK,
+			wantText: "You are not allowed to run the action on other/sample-run.",
+		},
+		{
+			name:     "InvalidSignature",
+			user:     "UALLOWED",
+			action:   slackActionRerun,
+			secret:   "wrong",
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			// the unknown provider is rejected like the invalid signature.
+			name:     "UnknownProvider",
+			user:     "UALLOWED",
+			action:   slackActionRerun,
+			provider: "default/missing",
+			secret:   "signing",
+			wantCode: http.StatusUnauthorized,
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			f := newFakeSlack(t)
+			var responses []map[string]interface{}
+			responder := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body map[string]interface{}
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("failed to decode request: %v", err)
+				}
+				responses = append(responses, body)
+			}))
+			defer responder.Close()
+
+			s := runtime.NewScheme()
+			assert.Nil(t, clientgoscheme.AddToScheme(s))
+			assert.Nil(t, pipelinesv1beta1.AddToScheme(s))
+			assert.Nil(t, v1alpha1.AddToScheme(s))
+			pr := newTemplateSamplePipelineRun()
+			if c.running {
+				pr.Status.Conditions[0].Status = corev1.ConditionUnknown
+			}
+			other := pr.DeepCopy()
+			other.Namespace = "other"
+			run := c.run
+			if len(run) == 0 {
+				run = "default/sample-run"
+			}
+			provider := c.provider
+			if len(provider) == 0 {
+				provider = "default/slack"
+			}
+			k := fakeclient.NewClientBuilder().
+				WithScheme(s).
+				WithObjects(
+					pr,
+					other,
+					&corev1.Secret{
+						ObjectMeta: metav1.ObjectMeta{Name: "slack", Namespace: "default"},
+						Data: map[string][]byte{
+							"access-token":   []byte("token"),
+							"signing-secret": []byte("signing"),
+						},
+					},
+					&v1alpha1.Provider{
+						ObjectMeta: metav1.ObjectMeta{Name: "slack", Namespace: "default"},
+						Spec: v1alpha1.ProviderSpec{
+							Type: "SlackApp",
+							SlackApp: &v1alpha1.SlackAppSpec{
+								AccessToken: v1alpha1.AccessTokenSource{
+									SecretRef: &v1alpha1.LocalSecretKeyReference{
+										LocalObjectReference: corev1.LocalObjectReference{Name: "slack"},
+									},
+								},
+								BaseURL:  pointer.String(f.URL),
+								Channels: []v1alpha1.SlackChannel{{ID: pointer.String("C1")}},
+								Interactivity: &v1alpha1.SlackInteractivity{
+									SigningSecret: v1alpha1.SigningSecretSource{
+										SecretRef: &v1alpha1.LocalSecretKeyReference{
+											LocalObjectReference: corev1.LocalObjectReference{Name: "slack"},
+										},
+									},
+									AllowedUsers:      []string{"UALLOWED"},
+									AllowedUserGroups: []string{"S1"},
+								},
+							},
+						},
+					},
+				).
+				Build()
+
+			payload, _ := json.Marshal(map[string]interface{}{
+				"type":         "block_actions",
+				"user":         map[string]string{"id": c.user},
+				"response_url": responder.URL,
+				"actions": []map[string]string{
+					{"action_id": c.action, "value": fmt.Sprintf(`{"provider":%q,"pipelineRun":%q}`, provider, run)},
+				},
+			})
+			body := url.Values{"payload": {string(payload)}}.Encode()
+			ts := strconv.FormatInt(now.Unix(), 10)
+			req := httptest.NewRequest(http.MethodPost, SlackActionPath, strings.NewReader(body)).WithContext(ctx)
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.Header.Set("X-Slack-Request-Timestamp", ts)
+			req.Header.Set("X-Slack-Signature", signSlackRequest(c.secret, ts, []byte(body)))
+			w := httptest.NewRecorder()
+			h := NewSlackActionHandler(k)
+			h.now = func() time.Time { return now }
+			h.ServeHTTP(w, req)
+			assert.Equal(t, c.wantCode, w.Code)
+			if c.wantCode == http.StatusUnauthorized {
+				assert.Equal(t, "unauthorized\n", w.Body.String())
+			}
+			if len(c.wantText) > 0 {
+				if assert.Len(t, responses, 1) {
+					assert.Equal(t, "ephemeral", responses[0]["response_type"])
+					assert.Contains(t, responses[0]["text"], c.wantText)
+				}
+			} else {
+				assert.Empty(t, responses)
+			}
+
+			var runs pipelinesv1beta1.PipelineRunList
+			assert.Nil(t, k.List(context.TODO(), &runs))
+			if c.wantRerun {
+				assert.Len(t, runs.Items, 3)
+			} else {
+				assert.Len(t, runs.Items, 2)
+			}
+			assert.Nil(t, k.Get(context.TODO(), types.NamespacedName{Namespace: "other", Name: "sample-run"}, other))
+			assert.Empty(t, other.Spec.Status)
+			var got pipelinesv1beta1.PipelineRun
+			assert.Nil(t, k.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: "sample-run"}, &got))
+			if c.wantCancelled {
+				assert.Equal(t, pipelinesv1beta1.PipelineRunSpecStatus(pipelinesv1beta1.PipelineRunSpecStatusCancelled), got.Spec.Status)
+			} else {
+				assert.Empty(t, got.Spec.Status)
+			}
+		})
+	}
+}
+
+func TestSlackAppNewMessageWithButtons(t *testing.T) {
+	a := &SlackApp{
+		Interactivity: &slackInteractivity{},
+		ProviderKey:   "default/slack",
+	}
+	m, perr := a.newMessage(newTemplateSamplePipelineRun(), "C1", "", "")
+	assert.Nil(t, perr)
+	req := m.(*slackPostMessageRequest)
+	if assert.Len(t, req.Attachments, 1) && assert.Len(t, req.Attachments[0].Blocks, 4) {
+		assert.Equal(t, "actions", req.Attachments[0].Blocks[3].Type)
+	}
+}
@@ -0,0 +1,286 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
+)
+
+// slackChannelCacheTTL is the duration to reuse the channels listed by conversations.list.
+const slackChannelCacheTTL = 10 * time.Minute
+
+type slackChannel struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	IsPrivate bool   `json:"is_private"`
+	IsMember  bool   `json:"is_member"`
+}
+
+type slackConversationsListResponse struct {
+	OK               bool           `json:"ok"`
+	Error            *string        `json:"error,omitempty"`
+	Channels         []slackChannel `json:"channels"`
+	ResponseMetadata struct {
+		NextCursor string `json:"next_cursor"`
+	} `json:"response_metadata"`
+}
+
+type slackChannelCacheEntry struct {
+	channels  map[string]slackChannel
+	expiresAt time.Time
+}
+
+// slackChannelCache caches the channels of the workspaces by the access token,
+// since the providers are resolved for each reconciliation.
+type slackChannelCache struct {
+	mu      sync.Mutex
+	entries map[string]*slackChannelCacheEntry
+	// misses has the expirations of the names not found, by the key and the name.
+	misses map[string]time.Time
+	now    func() time.Time
+}
+
+var slackChannels = &slackChannelCache{
+	entries: map[string]*slackChannelCacheEntry{},
+	misses:  map[string]time.Time{},
+	now:     time.Now,
+}
+
+func (c *slackChannelCache) get(key, name string) (slackChannel, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok || c.now().After(e.expiresAt) {
+		return slackChannel{}, false
+	}
+	ch, ok := e.channels[name]
+	return ch, ok
+}
+
+func (c *slackChannelCache) set(key string, channels map[string]slackChannel) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sweep()
+	c.entries[key] = &slackChannelCacheEntry{
+		channels:  channels,
+		expiresAt: c.now().Add(slackChannelCacheTTL),
+	}
+}
+
+// isMiss returns true if the name was not found in the channels listed recently.
+func (c *slackChannelCache) isMiss(key, name string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expiresAt, ok := c.misses[key+"#"+name]
+	if ok && c.now().After(expiresAt) {
+		delete(c.misses, key+"#"+name)
+		return false
+	}
+	return ok
+}
+
+func (c *slackChannelCache) setMiss(key, name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sweep()
+	c.misses[key+"#"+name] = c.now().Add(slackChannelCacheTTL)
+}
+
+// sweep removes the expired entries and misses, since the names of the misses are
+// not bounded by the channels of the workspaces. It must be called with the lock held.
+func (c *slackChannelCache) sweep() {
+	now := c.now()
+	for key, e := range c.entries {
+		if now.After(e.expiresAt) {
+			delete(c.entries, key)
+		}
+	}
+	for key, expiresAt := range c.misses {
+		if now.After(expiresAt) {
+			delete(c.misses, key)
+		}
+	}
+}
+
+func (c *slackChannelCache) setMember(key, name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		if ch, ok := e.channels[name]; ok {
+			ch.IsMember = true
+			e.channels[name] = ch
+		}
+	}
+}
+
+func (a *SlackApp) cacheKey() string {
+	h := sha256.Sum256(a.AccessToken.GetNoRedacted())
+	return a.APIURL + "#" + hex.EncodeToString(h[:])
+}
+
+// resolveChannel returns the id of the channel.
+// The name of the channel is resolved by conversations.list, and the bot joins
+// the public channel if AutoJoin is enabled.
+func (a *SlackApp) resolveChannel(ctx context.Context, c v1alpha1.SlackChannel) (string, *ProviderError) {
+	if c.ID != nil {
+		return *c.ID, nil
+	}
+	if c.Name == nil {
+		return "", NewInvalidProviderSpecError("Slack channel id or name is required")
+	}
+	ch, perr := a.lookupChannel(ctx, *c.Name)
+	if perr != nil {
+		return "", perr
+	}
+	if !ch.IsMember && a.AutoJoin && !ch.IsPrivate {
+		if perr := a.joinChannel(ctx, &ch); perr != nil {
+			return "", perr
+		}
+	}
+	return ch.ID, nil
+}
+
+// lookupChannel finds the channel by the name. The channels are listed again
+// if the name is not found in the cache, since the channel may be created or renamed.
+// The name not found is also cached, not to list the channels for every notification.
+func (a *SlackApp) lookupChannel(ctx context.Context, name string) (slackChannel, *ProviderError) {
+	name = strings.TrimPrefix(name, "#")
+	key := a.cacheKey()
+	if ch, ok := slackChannels.get(key, name); ok {
+		return ch, nil
+	}
+	if slackChannels.isMiss(key, name) {
+		return slackChannel{}, newSlackChannelNotFoundError(name)
+	}
+	channels, perr := a.listChannels(ctx)
+	if perr != nil {
+		return slackChannel{}, perr
+	}
+	slackChannels.set(key, channels)
+	ch, ok := channels[name]
+	if !ok {
+		slackChannels.setMiss(key, name)
+		return slackChannel{}, newSlackChannelNotFoundError(name)
+	}
+	return ch, nil
+}
+
+func newSlackChannelNotFoundError(name string) *ProviderError {
+	return NewChannelNotFoundError(fmt.Sprintf("Slack channel #%s is not found or is a private channel that the bot is not in", name))
+}
+
+// listChannels lists the public and private channels visible to the bot by conversations.list.
+func (a *SlackApp) listChannels(ctx context.Context) (map[string]slackChannel, *ProviderError) {
+	log := logr.FromContext(ctx).WithName("providers.slackapp")
+	channels := map[string]slackChannel{}
+	cursor := ""
+	for {
+		q := url.Values{}
+		q.Set("types", "public_channel,private_channel")
+		q.Set("exclude_archived", "true")
+		q.Set("limit", "200")
+		if len(cursor) > 0 {
+			q.Set("cursor", cursor)
+		}
+		var r slackConversationsListResponse
+		if perr := a.get(ctx, "conversations.list", q, &r); perr != nil {
+			return nil, perr
+		}
+		if !r.OK {
+			return nil, newSlackError(r.Error)
+		}
+		for _, ch := range r.Channels {
+			channels[ch.Name] = ch
+		}
+		cursor = r.ResponseMetadata.NextCursor
+		if len(cursor) < 1 {
+			break
+		}
+	}
+	log.V(2).Info("list channels", "count", len(channels))
+	return channels, nil
+}
+
+func (a *SlackApp) joinChannel(ctx context.Context, ch *slackChannel) *ProviderError {
+	log := logr.FromContext(ctx).WithName("providers.slackapp")
+	if perr := a.call(ctx, "conversations.join", map[string]string{"channel": ch.ID}, nil); perr != nil {
+		return perr
+	}
+	log.Info("joined Slack channel", "channel", ch.Name)
+	ch.IsMember = true
+	slackChannels.setMember(a.cacheKey(), ch.Name)
+	return nil
+}
+
+func (a *SlackApp) get(ctx context.Context, method string, q url.Values, out interface{}) *ProviderError {
+	bearer := fmt.Sprintf("Bearer %s", a.AccessToken.GetNoRedactedString())
+	b, err := doHTTP(ctx, http.MethodGet, a.APIURL+"/"+method+"?"+q.Encode(), bearer, nil)
+	if err != nil {
+		return newHTTPError("Slack "+method, err)
+	}
+	if err := json.Unmarshal(b, out); err != nil {
+		return NewRuntimeError(fmt.Sprintf("failed to unmarshal Slack response message: %v", err))
+	}
+	return nil
+}
+
+// validateChannels checks that the named channels exist and the bot can post to them.
+func (a *SlackApp) validateChannels(ctx context.Context) *ProviderError {
+	for _, c := range a.Channels {
+		if c.ID != nil || c.Name == nil {
+			continue
+		}
+		ch, perr := a.lookupChannel(ctx, *c.Name)
+		if perr != nil {
+			return perr
+		}
+		if !ch.IsMember && !(a.AutoJoin && !ch.IsPrivate) {
+			return NewNotInChannelError(fmt.Sprintf("the bot is not in Slack channel #%s, invite it or enable autoJoin", ch.Name))
+		}
+	}
+	return nil
+}
+
+// newSlackError converts the error of the Slack Web API.
+func newSlackError(e *string) *ProviderError {
+	errm := ""
+	if e != nil {
+		errm = *e
+	}
+	switch errm {
+	case "channel_not_found":
+		return NewChannelNotFoundError("Slack channel is not found")
+	case "not_in_channel":
+		return NewNotInChannelError("the bot is not in Slack channel")
+	case "ratelimited", "rate_limited":
+		return NewRateLimitedError("rate limited by Slack")
+	}
+	return NewRuntimeError(fmt.Sprintf("get an error from Slack: %s", errm))
+}
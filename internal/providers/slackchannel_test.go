@@ -0,0 +1,280 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/utils/pointer"
+
+	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
+)
+
+type fakeSlack struct {
+	*httptest.Server
+	ListCalls   int
+	LookupCalls int
+	Joined      []string
+	Posted      []map[string]interface{}
+	Updated     []map[string]interface{}
+}
+
+func newFakeSlack(t *testing.T) *fakeSlack {
+	f := &fakeSlack{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/conversations.list", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		f.ListCalls++
+		switch r.URL.Query().Get("cursor") {
+		case "":
+			w.Write([]byte(`{"ok":true,"channels":[{"id":"C1","name":"general","is_member":true}],"response_metadata":{"next_cursor":"next"}}`))
+		case "next":
+			w.Write([]byte(`{"ok":true,"channels":[{"id":"C2","name":"random"},{"id":"G3","name":"secret","is_private":true}],"response_metadata":{"next_cursor":""}}`))
+		}
+	})
+	mux.HandleFunc("/conversations.join", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		f.Joined = append(f.Joined, body["channel"])
+		w.Write([]byte(`{"ok":true,"channel":{"id":"C2"}}`))
+	})
+	mux.HandleFunc("/users.lookupByEmail", func(w http.ResponseWriter, r *http.Request) {
+		f.LookupCalls++
+		switch r.URL.Query().Get("email") {
+		case "alice@example.com":
+			w.Write([]byte(`{"ok":true,"user":{"id":"UALICE"}}`))
+		default:
+			w.Write([]byte(`{"ok":false,"error":"users_not_found"}`))
+		}
+	})
+	mux.HandleFunc("/usergroups.users.list", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("usergroup") {
+		case "S1":
+			w.Write([]byte(`{"ok":true,"users":["UGROUP"]}`))
+		default:
+			w.Write([]byte(`{"ok":false,"error":"no_such_subteam"}`))
+		}
+	})
+	record := func(messages *[]map[string]interface{}) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			var body map[string]interface{}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("failed to decode request: %v", err)
+			}
+			*messages = append(*messages, body)
+			w.Write([]byte(fmt.Sprintf(`{"ok":true,"channel":%q,"ts":"%d.0"}`, body["channel"], len(*messages))))
+		}
+	}
+	mux.HandleFunc("/chat.postMessage", record(&f.Posted))
+	mux.HandleFunc("/chat.update", record(&f.Updated))
+	f.Server = httptest.NewServer(mux)
+	t.Cleanup(f.Server.Close)
+	return f
+}
+
+func TestSlackAppResolveChannel(t *testing.T) {
+	for _, c := range []struct {
+		name       string
+		channel    v1alpha1.SlackChannel
+		autoJoin   bool
+		want       string
+		wantJoined []string
+		wantErr    *ProviderError
+	}{
+		{
+			name:    "ID",
+			channel: v1alpha1.SlackChannel{ID: pointer.String("C9"), Name: pointer.String("ignored")},
+			want:    "C9",
+		},
+		{
+			name:    "Name",
+			channel: v1alpha1.SlackChannel{Name: pointer.String("#general")},
+			want:    "C1",
+		},
+		{
+			name:    "NameInNextPage",
+			channel: v1alpha1.SlackChannel{Name: pointer.String("random")},
+			want:    "C2",
+		},
+		{
+			name:       "AutoJoin",
+			channel:    v1alpha1.SlackChannel{Name: pointer.String("random")},
+			autoJoin:   true,
+			want:       "C2",
+			wantJoined: []string{"C2"},
+		},
+		{
+			name:     "AutoJoinPrivate",
+			channel:  v1alpha1.SlackChannel{Name: pointer.String("secret")},
+			autoJoin: true,
+			want:     "G3",
+		},
+		{
+			name:    "NotFound",
+			channel: v1alpha1.SlackChannel{Name: pointer.String("unknown")},
+			wantErr: NewChannelNotFoundError(""),
+		},
+		{
+			name:    "Empty",
+			wantErr: NewInvalidProviderSpecError(""),
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			f := newFakeSlack(t)
+			a := &SlackApp{
+				APIURL:      f.URL,
+				AccessToken: NewSecretBytes([]byte("token")),
+				AutoJoin:    c.autoJoin,
+			}
+			id, err := a.resolveChannel(ctx, c.channel)
+			if c.wantErr != nil {
+				if assert.NotNil(t, err) {
+					assert.Equal(t, c.wantErr.Code, err.Code)
+				}
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, c.want, id)
+			assert.Equal(t, c.wantJoined, f.Joined)
+		})
+	}
+}
+
+func TestSlackAppResolveChannelCache(t *testing.T) {
+	now := time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)
+	slackChannels.now = func() time.Time { return now }
+	defer func() { slackChannels.now = time.Now }()
+
+	f := newFakeSlack(t)
+	a := &SlackApp{
+		APIURL:      f.URL,
+		AccessToken: NewSecretBytes([]byte("token")),
+		AutoJoin:    true,
+	}
+	for i := 0; i < 2; i++ {
+		id, err := a.resolveChannel(ctx, v1alpha1.SlackChannel{Name: pointer.String("random")})
+		assert.Nil(t, err)
+		assert.Equal(t, "C2", id)
+	}
+	// the second resolution reuses both the channel list and the membership.
+	assert.Equal(t, 2, f.ListCalls)
+	assert.Equal(t, []string{"C2"}, f.Joined)
+
+	// an unknown name lists the channels again, and the miss is cached.
+	for i := 0; i < 2; i++ {
+		_, err := a.resolveChannel(ctx, v1alpha1.SlackChannel{Name: pointer.String("unknown")})
+		if assert.NotNil(t, err) {
+			assert.Equal(t, ErrorCodeChannelNotFound, err.Code)
+		}
+		assert.Equal(t, 4, f.ListCalls)
+	}
+
+	now = now.Add(slackChannelCacheTTL + time.Second)
+	_, err := a.resolveChannel(ctx, v1alpha1.SlackChannel{Name: pointer.String("general")})
+	assert.Nil(t, err)
+	assert.Equal(t, 6, f.ListCalls)
+
+	// the miss expires with the same TTL.
+	_, err = a.resolveChannel(ctx, v1alpha1.SlackChannel{Name: pointer.String("unknown")})
+	if assert.NotNil(t, err) {
+		assert.Equal(t, ErrorCodeChannelNotFound, err.Code)
+	}
+	assert.Equal(t, 8, f.ListCalls)
+}
+
+func TestSlackChannelCacheSweep(t *testing.T) {
+	now := time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)
+	c := &slackChannelCache{
+		entries: map[string]*slackChannelCacheEntry{},
+		misses:  map[string]time.Time{},
+		now:     func() time.Time { return now },
+	}
+	c.set("old", map[string]slackChannel{})
+	c.setMiss("old", "unknown-1")
+	c.setMiss("old", "unknown-2")
+
+	now = now.Add(slackChannelCacheTTL + time.Second)
+	c.setMiss("new", "unknown")
+	assert.Len(t, c.misses, 1)
+	assert.Contains(t, c.misses, "new#unknown")
+	assert.Empty(t, c.entries)
+
+	c.set("new", map[string]slackChannel{})
+	assert.Len(t, c.entries, 1)
+	assert.True(t, c.isMiss("new", "unknown"))
+}
+
+func TestSlackAppValidateChannels(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		channels []v1alpha1.SlackChannel
+		autoJoin bool
+		wantErr  *ProviderError
+	}{
+		{
+			name: "Member",
+			channels: []v1alpha1.SlackChannel{
+				{ID: pointer.String("C9")},
+				{Name: pointer.String("general")},
+			},
+		},
+		{
+			name:     "NotInChannel",
+			channels: []v1alpha1.SlackChannel{{Name: pointer.String("random")}},
+			wantErr:  NewNotInChannelError(""),
+		},
+		{
+			name:     "AutoJoin",
+			channels: []v1alpha1.SlackChannel{{Name: pointer.String("random")}},
+			autoJoin: true,
+		},
+		{
+			name:     "ChannelNotFound",
+			channels: []v1alpha1.SlackChannel{{Name: pointer.String("unknown")}},
+			wantErr:  NewChannelNotFoundError(""),
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			f := newFakeSlack(t)
+			a := &SlackApp{
+				APIURL:      f.URL,
+				AccessToken: NewSecretBytes([]byte("token")),
+				Channels:    c.channels,
+				AutoJoin:    c.autoJoin,
+			}
+			err := a.validateChannels(ctx)
+			if c.wantErr != nil {
+				if assert.NotNil(t, err) {
+					assert.Equal(t, c.wantErr.Code, err.Code)
+				}
+				return
+			}
+			assert.Nil(t, err)
+			assert.Empty(t, f.Joined)
+		})
+	}
+}
@@ -0,0 +1,211 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"knative.dev/pkg/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/go-logr/logr"
+	pipelinesv1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+
+	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
+)
+
+const (
+	annotationAuthorEmail = "integrations.tekton.ornew.io/author-email"
+	annotationTriggeredBy = "integrations.tekton.ornew.io/triggered-by"
+
+	slackUserCacheTTL = time.Hour
+)
+
+// slackUserMapping resolves the authors of the runs to Slack user IDs.
+type slackUserMapping struct {
+	LookupByEmail bool
+	// Users maps the emails or the names to Slack user IDs.
+	Users map[string]string
+}
+
+// newSlackUserMapping reads the mapping from the ConfigMap in the namespace of the provider.
+func newSlackUserMapping(ctx context.Context, k client.Client, namespace string, s *v1alpha1.SlackUserMapping) (*slackUserMapping, *ProviderError) {
+	m := &slackUserMapping{
+		LookupByEmail: s.LookupByEmail,
+		Users:         map[string]string{},
+	}
+	ref := s.ConfigMapRef
+	if ref == nil {
+		return m, nil
+	}
+	data, perr := getConfigMapValue(ctx, k, namespace, ref, "users.yaml")
+	if perr != nil {
+		return nil, perr
+	}
+	if err := yaml.Unmarshal([]byte(data), &m.Users); err != nil {
+		return nil, NewFailedValidationError(fmt.Sprintf("failed to parse ConfigMap %s: %v", ref.Name, err))
+	}
+	return m, nil
+}
+
+// getRunAuthors returns the email and the name of the author of the run, read from
+// the annotations, the labels or the params author-email and triggered-by.
+func getRunAuthors(pr *pipelinesv1beta1.PipelineRun) []string {
+	var authors []string
+	for _, key := range []struct{ annotation, param string }{
+		{annotationAuthorEmail, "author-email"},
+		{annotationTriggeredBy, "triggered-by"},
+	} {
+		v := pr.Annotations[key.annotation]
+		if len(v) < 1 {
+			v = pr.Labels[key.annotation]
+		}
+		if len(v) < 1 {
+			v = getParam(pr, key.param)
+		}
+		if len(v) > 0 {
+			authors = append(authors, v)
+		}
+	}
+	return authors
+}
+
+// isMentionTarget returns true if the mentions are added for the condition,
+// which is only when the run failed. Cancelled runs are not mentioned.
+func isMentionTarget(c *apis.Condition) bool {
+	return c != nil && c.Status == corev1.ConditionFalse && !isCancelled(c)
+}
+
+// getNotificationMentions returns the mentions of the Notification being dispatched in mrkdwn.
+func getNotificationMentions(ctx context.Context) []string {
+	n := notificationFromContext(ctx)
+	if n == nil || n.Spec.Mentions == nil {
+		return nil
+	}
+	var mentions []string
+	if n.Spec.Mentions.Channel {
+		mentions = append(mentions, "<!channel>")
+	}
+	if n.Spec.Mentions.Here {
+		mentions = append(mentions, "<!here>")
+	}
+	for _, g := range n.Spec.Mentions.UserGroups {
+		mentions = append(mentions, fmt.Sprintf("<!subteam^%s>", g))
+	}
+	return mentions
+}
+
+// getMentions returns the mentions of the authors and the Notification in mrkdwn.
+// The authors that can't be resolved are not mentioned, since the mentions must not block the notification.
+func (a *SlackApp) getMentions(ctx context.Context, pr *pipelinesv1beta1.PipelineRun) string {
+	log := logr.FromContext(ctx).WithName("providers.slackapp")
+	var mentions []string
+	if a.MentionAuthors != nil {
+		seen := map[string]bool{}
+		for _, author := range getRunAuthors(pr) {
+			id, perr := a.lookupUser(ctx, author)
+			if perr != nil {
+				log.Error(perr, "failed to look up the Slack user of the author")
+				continue
+			}
+			if len(id) < 1 || seen[id] {
+				continue
+			}
+			seen[id] = true
+			mentions = append(mentions, fmt.Sprintf("<@%s>", id))
+		}
+	}
+	mentions = append(mentions, getNotificationMentions(ctx)...)
+	return strings.Join(mentions, " ")
+}
+
+// lookupUser returns the Slack user ID of the author, or empty if not found.
+func (a *SlackApp) lookupUser(ctx context.Context, author string) (string, *ProviderError) {
+	if id, ok := a.MentionAuthors.Users[author]; ok {
+		return id, nil
+	}
+	if !a.MentionAuthors.LookupByEmail || !strings.Contains(author, "@") {
+		return "", nil
+	}
+	key := a.cacheKey() + "#" + strings.ToLower(author)
+	if id, ok := slackUsers.get(key); ok {
+		return id, nil
+	}
+	q := url.Values{}
+	q.Set("email", author)
+	var r struct {
+		OK    bool    `json:"ok"`
+		Error *string `json:"error,omitempty"`
+		User  struct {
+			ID string `json:"id"`
+		} `json:"user"`
+	}
+	if perr := a.get(ctx, "users.lookupByEmail", q, &r); perr != nil {
+		return "", perr
+	}
+	if !r.OK {
+		if r.Error == nil || *r.Error != "users_not_found" {
+			return "", newSlackError(r.Error)
+		}
+	}
+	// the users not found are also cached to avoid looking up them on each notification.
+	slackUsers.set(key, r.User.ID)
+	return r.User.ID, nil
+}
+
+type slackUserCacheEntry struct {
+	id        string
+	expiresAt time.Time
+}
+
+// slackUserCache caches the users looked up by email.
+type slackUserCache struct {
+	mu      sync.Mutex
+	entries map[string]slackUserCacheEntry
+	now     func() time.Time
+}
+
+var slackUsers = &slackUserCache{
+	entries: map[string]slackUserCacheEntry{},
+	now:     time.Now,
+}
+
+func (c *slackUserCache) get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok || c.now().After(e.expiresAt) {
+		return "", false
+	}
+	return e.id, true
+}
+
+func (c *slackUserCache) set(key, id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = slackUserCacheEntry{
+		id:        id,
+		expiresAt: c.now().Add(slackUserCacheTTL),
+	}
+}
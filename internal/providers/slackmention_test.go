@@ -0,0 +1,247 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	pipelinesv1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+
+	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
+)
+
+func TestNewSlackUserMapping(t *testing.T) {
+	k := fakeclient.NewClientBuilder().
+		WithObjects(&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "slack-users",
+				Namespace: "default",
+			},
+			Data: map[string]string{
+				"users.yaml": "alice@example.com: UALICE\nbob: UBOB\n",
+				"broken":     "- alice",
+			},
+		}).
+		Build()
+	for _, c := range []struct {
+		name    string
+		spec    *v1alpha1.SlackUserMapping
+		want    *slackUserMapping
+		wantErr *ProviderError
+	}{
+		{
+			name: "LookupByEmail",
+			spec: &v1alpha1.SlackUserMapping{LookupByEmail: true},
+			want: &slackUserMapping{LookupByEmail: true, Users: map[string]string{}},
+		},
+		{
+			name: "ConfigMap",
+			spec: &v1alpha1.SlackUserMapping{
+				ConfigMapRef: &v1alpha1.LocalConfigMapKeyReference{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "slack-users"},
+				},
+			},
+			want: &slackUserMapping{Users: map[string]string{
+				"alice@example.com": "UALICE",
+				"bob":               "UBOB",
+			}},
+		},
+		{
+			name: "BrokenConfigMap",
+			spec: &v1alpha1.SlackUserMapping{
+				ConfigMapRef: &v1alpha1.LocalConfigMapKeyReference{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "slack-users"},
+					Key:                  pointer.String("broken"),
+				},
+			},
+			wantErr: NewFailedValidationError(""),
+		},
+		{
+			name: "ConfigMapNotFound",
+			spec: &v1alpha1.SlackUserMapping{
+				ConfigMapRef: &v1alpha1.LocalConfigMapKeyReference{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "not-exists"},
+				},
+			},
+			wantErr: NewFailedValidationError(""),
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			m, err := newSlackUserMapping(ctx, k, "default", c.spec)
+			if c.wantErr != nil {
+				if assert.NotNil(t, err) {
+					assert.Equal(t, c.wantErr.Code, err.Code)
+				}
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, c.want, m)
+		})
+	}
+}
+
+func TestGetRunAuthors(t *testing.T) {
+	pr := &pipelinesv1beta1.PipelineRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: map[string]string{
+				"integrations.tekton.ornew.io/triggered-by": "bob",
+			},
+		},
+		Spec: pipelinesv1beta1.PipelineRunSpec{
+			Params: []pipelinesv1beta1.Param{
+				{Name: "author-email", Value: *pipelinesv1beta1.NewArrayOrString("alice@example.com")},
+				{Name: "triggered-by", Value: *pipelinesv1beta1.NewArrayOrString("ignored")},
+			},
+		},
+	}
+	assert.Equal(t, []string{"alice@example.com", "bob"}, getRunAuthors(pr))
+}
+
+func TestGetNotificationMentions(t *testing.T) {
+	assert.Empty(t, getNotificationMentions(ctx))
+	n := &v1alpha1.Notification{
+		Spec: v1alpha1.NotificationSpec{
+			Mentions: &v1alpha1.Mentions{
+				Here:       true,
+				UserGroups: []string{"S0123"},
+			},
+		},
+	}
+	assert.Equal(t, []string{"<!here>", "<!subteam^S0123>"}, getNotificationMentions(WithNotification(ctx, n)))
+}
+
+func TestSlackAppNotifyMentions(t *testing.T) {
+	s := runtime.NewScheme()
+	assert.Nil(t, clientgoscheme.AddToScheme(s))
+	assert.Nil(t, pipelinesv1beta1.AddToScheme(s))
+	n := &v1alpha1.Notification{
+		Spec: v1alpha1.NotificationSpec{
+			Mentions: &v1alpha1.Mentions{Here: true},
+		},
+	}
+	for _, c := range []struct {
+		name          string
+		reason        string
+		updateMessage bool
+		posted        []string
+		wantText      string
+		wantReply     string
+	}{
+		{
+			name:     "Failed",
+			reason:   "Failed",
+			wantText: "<@UALICE> <@UBOB> <!here>",
+		},
+		{
+			name:   "Cancelled",
+			reason: "Cancelled",
+		},
+		{
+			name:          "FailedUpdateMessage",
+			reason:        "Failed",
+			updateMessage: true,
+			posted:        []string{"C1"},
+			wantReply:     "<@UALICE> <@UBOB> <!here>",
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			f := newFakeSlack(t)
+			pr := newTemplateSamplePipelineRun()
+			pr.Status.Conditions[0].Reason = c.reason
+			pr.Annotations[annotationAuthorEmail] = "alice@example.com"
+			pr.Annotations[annotationTriggeredBy] = "bob"
+			if len(c.posted) > 0 {
+				pr.Annotations[annotationSlackMessages] = `{"default/slack":[{"channel":"C1","ts":"0.1"}]}`
+			}
+			a := &SlackApp{
+				APIURL:        f.URL,
+				AccessToken:   NewSecretBytes([]byte("token")),
+				Channels:      []v1alpha1.SlackChannel{{ID: pointer.String("C1")}},
+				UpdateMessage: c.updateMessage,
+				MentionAuthors: &slackUserMapping{
+					LookupByEmail: true,
+					Users:         map[string]string{"bob": "UBOB"},
+				},
+				ProviderKey: "default/slack",
+				Client:      fakeclient.NewClientBuilder().WithScheme(s).WithObjects(pr.DeepCopy()).Build(),
+			}
+			err := a.Notify(WithNotification(ctx, n), pr)
+			assert.Nil(t, err)
+			if c.updateMessage {
+				if assert.Len(t, f.Updated, 1) {
+					assert.Nil(t, f.Updated[0]["text"])
+				}
+				if assert.Len(t, f.Posted, 1) {
+					assert.Equal(t, "0.1", f.Posted[0]["thread_ts"])
+					assert.Equal(t, c.wantReply, f.Posted[0]["text"])
+				}
+				return
+			}
+			if assert.Len(t, f.Posted, 1) {
+				if len(c.wantText) > 0 {
+					assert.Equal(t, c.wantText, f.Posted[0]["text"])
+				} else {
+					assert.Nil(t, f.Posted[0]["text"])
+				}
+			}
+		})
+	}
+}
+
+func TestSlackAppLookupUserCache(t *testing.T) {
+	f := newFakeSlack(t)
+	a := &SlackApp{
+		APIURL:         f.URL,
+		AccessToken:    NewSecretBytes([]byte("token")),
+		MentionAuthors: &slackUserMapping{LookupByEmail: true},
+	}
+	for i := 0; i < 2; i++ {
+		id, err := a.lookupUser(ctx, "alice@example.com")
+		assert.Nil(t, err)
+		assert.Equal(t, "UALICE", id)
+		id, err = a.lookupUser(ctx, "nobody@example.com")
+		assert.Nil(t, err)
+		assert.Equal(t, "", id)
+	}
+	assert.Equal(t, 2, f.LookupCalls)
+}
+
+func TestNewSlackMessageMentions(t *testing.T) {
+	tmpl, perr := parseTemplate("test", `{"text":{{ json .Reason }},"blocks":[]}`)
+	assert.Nil(t, perr)
+	m, perr := newSlackMessage(tmpl, newTemplateSamplePipelineRun(), "C1", "", "<!here>")
+	assert.Nil(t, perr)
+	assert.JSONEq(t, `"<!here> Failed"`, string(m.(map[string]json.RawMessage)["text"]))
+
+	tmpl, perr = parseTemplate("test", `{"blocks":[]}`)
+	assert.Nil(t, perr)
+	m, perr = newSlackMessage(tmpl, newTemplateSamplePipelineRun(), "C1", "", "<!here>")
+	assert.Nil(t, perr)
+	assert.JSONEq(t, `"<!here>"`, string(m.(map[string]json.RawMessage)["text"]))
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"text/template"
+
+	corev1 "k8s.io/api/core/v1"
+	"knative.dev/pkg/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/go-logr/logr"
+	pipelinesv1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+
+	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
+)
+
+// SlackWebhook posts the messages to a Slack Incoming Webhook.
+// The channel is fixed by the webhook, and the messages can't be updated.
+type SlackWebhook struct {
+	URL      SecretString
+	Template *template.Template
+}
+
+var _ Provider = (*SlackWebhook)(nil)
+
+func NewSlackWebhook(ctx context.Context, p *v1alpha1.Provider, k client.Client) (*SlackWebhook, *ProviderError) {
+	s := p.Spec.SlackWebhook
+	if s == nil {
+		return nil, NewInvalidProviderSpecError("missing value .slackWebhook")
+	}
+	u, perr := getWebhookURL(ctx, k, p.Namespace, s.URL)
+	if perr != nil {
+		return nil, perr
+	}
+	a := &SlackWebhook{
+		URL: u,
+	}
+	if len(s.MessageTemplate) > 0 {
+		a.Template, perr = parseTemplate("messageTemplate", s.MessageTemplate)
+		if perr != nil {
+			return nil, perr
+		}
+	}
+	return a, nil
+}
+
+// getWebhookURL reads the webhook URL from the secret and checks it.
+func getWebhookURL(ctx context.Context, k client.Client, namespace string, s v1alpha1.WebhookURLSource) (SecretString, *ProviderError) {
+	if s.SecretRef == nil {
+		return SecretString{}, NewInvalidProviderSpecError("missing valid values in .url")
+	}
+	b, perr := getSecretValue(ctx, k, namespace, s.SecretRef, "url")
+	if perr != nil {
+		return SecretString{}, perr
+	}
+	raw := strings.TrimSpace(string(b))
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || len(u.Host) < 1 {
+		// the URL is not included in the message, since it contains the credentials.
+		return SecretString{}, NewFailedValidationError(fmt.Sprintf("the webhook URL in the secret %s is not a valid HTTP URL", s.SecretRef.Name))
+	}
+	return NewSecretString(raw), nil
+}
+
+func (a *SlackWebhook) Notify(ctx context.Context, pr *pipelinesv1beta1.PipelineRun) *ProviderError {
+	log := logr.FromContext(ctx).WithName("providers.slackwebhook").
+		WithValues("providerType", "SlackWebhook", "pipelineRun", pr.Name)
+	cond := pr.Status.GetCondition(apis.ConditionSucceeded)
+	if cond == nil {
+		log.V(1).Info("PipelineRun has not condition, ignored")
+		return NewSkippedError("PipelineRun has not condition")
+	}
+	if cond.Status == corev1.ConditionUnknown {
+		log.V(2).Info("this run is not finished yet, skipped")
+		return NewSkippedError("this run is not finished yet")
+	}
+	mentions := ""
+	if isMentionTarget(cond) {
+		mentions = strings.Join(getNotificationMentions(ctx), " ")
+	}
+	payload, perr := newSlackMessage(a.Template, pr, "", "", mentions)
+	if perr != nil {
+		return perr
+	}
+	if _, err := postHTTP(ctx, a.URL.GetNoRedactedString(), "", payload); err != nil {
+		return newSlackWebhookError(err)
+	}
+	log.V(2).Info("post message")
+	return nil
+}
+
+// Validate checks the template.
+func (a *SlackWebhook) Validate(ctx context.Context) *ProviderError {
+	if a.Template == nil {
+		return nil
+	}
+	_, perr := renderSlackTemplate(a.Template, newTemplateSamplePipelineRun(), "", "")
+	return perr
+}
+
+// newSlackWebhookError converts the error of the Incoming Webhook, which responds with the error in the plain text.
+func newSlackWebhookError(err error) *ProviderError {
+	var serr *httpStatusError
+	if errors.As(err, &serr) {
+		switch strings.TrimSpace(serr.Body) {
+		case "channel_not_found", "channel_is_archived":
+			return NewChannelNotFoundError(fmt.Sprintf("Slack Incoming Webhook: %s", serr.Body))
+		}
+	}
+	return newWebhookError("Slack Incoming Webhook", err)
+}
@@ -0,0 +1,220 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
+)
+
+func TestNewSlackWebhook(t *testing.T) {
+	k := fakeclient.NewClientBuilder().
+		WithObjects(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "secret",
+				Namespace: "default",
+			},
+			Data: map[string][]byte{
+				"url":     []byte("https://hooks.slack.com/services/T0/B0/xxxx\n"),
+				"invalid": []byte("hooks.slack.com/services/T0/B0/xxxx"),
+			},
+		}).
+		Build()
+	for _, c := range []struct {
+		name    string
+		spec    *v1alpha1.SlackWebhookSpec
+		wantErr *ProviderError
+	}{
+		{
+			name: "Basic",
+			spec: &v1alpha1.SlackWebhookSpec{
+				URL: v1alpha1.WebhookURLSource{
+					SecretRef: &v1alpha1.LocalSecretKeyReference{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "secret"},
+					},
+				},
+			},
+		},
+		{
+			name: "InvalidURL",
+			spec: &v1alpha1.SlackWebhookSpec{
+				URL: v1alpha1.WebhookURLSource{
+					SecretRef: &v1alpha1.LocalSecretKeyReference{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "secret"},
+						Key:                  pointer.String("invalid"),
+					},
+				},
+			},
+			wantErr: NewFailedValidationError(""),
+		},
+		{
+			name: "InvalidTemplate",
+			spec: &v1alpha1.SlackWebhookSpec{
+				URL: v1alpha1.WebhookURLSource{
+					SecretRef: &v1alpha1.LocalSecretKeyReference{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "secret"},
+					},
+				},
+				MessageTemplate: "{{ .Name",
+			},
+			wantErr: NewInvalidProviderSpecError(""),
+		},
+		{
+			name: "SecretNotFound",
+			spec: &v1alpha1.SlackWebhookSpec{
+				URL: v1alpha1.WebhookURLSource{
+					SecretRef: &v1alpha1.LocalSecretKeyReference{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "not-exists-secret"},
+					},
+				},
+			},
+			wantErr: NewNotFoundPrivateKeyError(""),
+		},
+		{
+			name:    "SlackWebhookSpecNotFound",
+			wantErr: NewInvalidProviderSpecError(""),
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			p := &v1alpha1.Provider{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "provider",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.ProviderSpec{
+					Type:         "SlackWebhook",
+					SlackWebhook: c.spec,
+				},
+			}
+			a, err := NewSlackWebhook(ctx, p, k)
+			if c.wantErr != nil {
+				if assert.NotNil(t, err) {
+					assert.Equal(t, c.wantErr.Code, err.Code)
+				}
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, "https://hooks.slack.com/services/T0/B0/xxxx", a.URL.GetNoRedactedString())
+		})
+	}
+}
+
+func TestSlackWebhookNotify(t *testing.T) {
+	for _, c := range []struct {
+		name        string
+		status      corev1.ConditionStatus
+		template    string
+		respCode    int
+		respBody    string
+		want        string
+		wantErr     *ProviderError
+		wantSkipped bool
+	}{
+		{
+			name:     "Default",
+			status:   corev1.ConditionFalse,
+			respCode: http.StatusOK,
+			respBody: "ok",
+			want:     `{"fallback":"Failed: sample-run.default","attachments":[{"color":"#A30100","blocks":[{"type":"section","text":{"type":"mrkdwn","text":"*sample-run.default*"}},{"type":"section","text":{"type":"plain_text","text":"Failed: Tasks Completed: 1 (Failed: 1, Cancelled 0), Skipped: 0"}},{"type":"context","elements":[{"type":"mrkdwn","text":"1m0s | <https://dashboard.example.com/#/namespaces/default/pipelineruns/sample-run|open dashboard>"}]}]}]}`,
+		},
+		{
+			name:     "Template",
+			status:   corev1.ConditionFalse,
+			template: `{"text":{{ json .Reason }}}`,
+			respCode: http.StatusOK,
+			respBody: "ok",
+			want:     `{"text":"Failed"}`,
+		},
+		{
+			name:        "Running",
+			wantSkipped: true,
+			status:      corev1.ConditionUnknown,
+		},
+		{
+			name:     "ChannelNotFound",
+			status:   corev1.ConditionTrue,
+			respCode: http.StatusNotFound,
+			respBody: "channel_not_found",
+			wantErr:  NewChannelNotFoundError(""),
+		},
+		{
+			name:     "InvalidToken",
+			status:   corev1.ConditionTrue,
+			respCode: http.StatusForbidden,
+			respBody: "invalid_token",
+			wantErr:  NewRuntimeError(""),
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			var bodies []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/services/T0/B0/xxxx", r.URL.Path)
+				assert.Empty(t, r.Header.Get("Authorization"))
+				var body json.RawMessage
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("failed to decode request: %v", err)
+				}
+				bodies = append(bodies, string(body))
+				w.WriteHeader(c.respCode)
+				w.Write([]byte(c.respBody))
+			}))
+			defer srv.Close()
+			pr := newTemplateSamplePipelineRun()
+			pr.Status.Conditions[0].Status = c.status
+			a := &SlackWebhook{
+				URL: NewSecretString(srv.URL + "/services/T0/B0/xxxx"),
+			}
+			if len(c.template) > 0 {
+				tmpl, perr := parseTemplate("test", c.template)
+				assert.Nil(t, perr)
+				a.Template = tmpl
+			}
+			err := a.Notify(ctx, pr)
+			if c.wantErr != nil {
+				if assert.NotNil(t, err) {
+					assert.Equal(t, c.wantErr.Code, err.Code)
+				}
+				return
+			}
+			if c.wantSkipped {
+				assert.True(t, err.IsSkipped())
+			} else {
+				assert.Nil(t, err)
+			}
+			if len(c.want) < 1 {
+				assert.Empty(t, bodies)
+				return
+			}
+			if assert.Len(t, bodies, 1) {
+				assert.JSONEq(t, c.want, bodies[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,263 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+	duckv1beta1 "knative.dev/pkg/apis/duck/v1beta1"
+
+	pipelinesv1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+)
+
+// TemplateData is the data model passed to the message templates.
+type TemplateData struct {
+	// The PipelineRun object.
+	PipelineRun *pipelinesv1beta1.PipelineRun
+
+	Name      string
+	Namespace string
+	// Pipeline is the name of the referenced Pipeline, if any.
+	Pipeline string
+	// Status is the status of the Succeeded condition: "True", "False" or "Unknown".
+	Status  string
+	Reason  string
+	Message string
+	// Succeeded, Failed and Running are the shorthands of Status.
+	Succeeded bool
+	Failed    bool
+	Running   bool
+	// Transition is the transition of the run: "running", "succeeded", "failed" or "cancelled".
+	Transition string
+
+	Labels      map[string]string
+	Annotations map[string]string
+	// Params are the parameters of the run. The array values are joined with ",".
+	Params map[string]string
+	// Results are the pipeline results of the run.
+	Results map[string]string
+	// TaskRuns are the child TaskRuns of the run, sorted by the start time.
+	TaskRuns []TaskRunTemplateData
+
+	StartTime      *time.Time
+	CompletionTime *time.Time
+	// Duration is the duration from the start to the completion, or to now if the run is not completed.
+	Duration time.Duration
+	// DashboardURL is the URL of the run on the Tekton Dashboard, or empty if not configured.
+	DashboardURL string
+}
+
+// TaskRunTemplateData is the data model of the child TaskRuns.
+type TaskRunTemplateData struct {
+	Name             string
+	PipelineTaskName string
+	Status           string
+	Reason           string
+	Message          string
+	Succeeded        bool
+	Failed           bool
+	Running          bool
+	Results          map[string]string
+	StartTime        *time.Time
+	CompletionTime   *time.Time
+	Duration         time.Duration
+}
+
+var templateNow = time.Now
+
+func newTemplateData(pr *pipelinesv1beta1.PipelineRun) *TemplateData {
+	d := &TemplateData{
+		PipelineRun:  pr,
+		Name:         pr.Name,
+		Namespace:    pr.Namespace,
+		Labels:       pr.Labels,
+		Annotations:  pr.Annotations,
+		Params:       map[string]string{},
+		Results:      map[string]string{},
+		DashboardURL: getDashboardTargetURL(pr),
+	}
+	if pr.Spec.PipelineRef != nil {
+		d.Pipeline = pr.Spec.PipelineRef.Name
+	}
+	cond := pr.Status.GetCondition(apis.ConditionSucceeded)
+	d.Status, d.Reason, d.Message, d.Succeeded, d.Failed, d.Running = getTemplateCondition(cond)
+	d.Transition = getRunTransition(cond)
+	for _, p := range pr.Spec.Params {
+		if p.Value.Type == pipelinesv1beta1.ParamTypeArray {
+			d.Params[p.Name] = strings.Join(p.Value.ArrayVal, ",")
+		} else {
+			d.Params[p.Name] = p.Value.StringVal
+		}
+	}
+	for _, r := range pr.Status.PipelineResults {
+		d.Results[r.Name] = r.Value
+	}
+	d.StartTime, d.CompletionTime, d.Duration = getTemplateTimes(pr.Status.StartTime, pr.Status.CompletionTime)
+	for name, tr := range pr.Status.TaskRuns {
+		t := TaskRunTemplateData{
+			Name:             name,
+			PipelineTaskName: tr.PipelineTaskName,
+			Results:          map[string]string{},
+		}
+		if tr.Status != nil {
+			t.Status, t.Reason, t.Message, t.Succeeded, t.Failed, t.Running = getTemplateCondition(tr.Status.GetCondition(apis.ConditionSucceeded))
+			for _, r := range tr.Status.TaskRunResults {
+				t.Results[r.Name] = r.Value
+			}
+			t.StartTime, t.CompletionTime, t.Duration = getTemplateTimes(tr.Status.StartTime, tr.Status.CompletionTime)
+		}
+		d.TaskRuns = append(d.TaskRuns, t)
+	}
+	// the TaskRuns not started yet follow the started ones, and the ties are sorted by the name.
+	sort.SliceStable(d.TaskRuns, func(i, j int) bool {
+		a, b := d.TaskRuns[i], d.TaskRuns[j]
+		if (a.StartTime == nil) != (b.StartTime == nil) {
+			return b.StartTime == nil
+		}
+		if a.StartTime == nil || a.StartTime.Equal(*b.StartTime) {
+			return a.Name < b.Name
+		}
+		return a.StartTime.Before(*b.StartTime)
+	})
+	return d
+}
+
+func getTemplateCondition(c *apis.Condition) (status, reason, message string, succeeded, failed, running bool) {
+	if c == nil {
+		return string(corev1.ConditionUnknown), "", "", false, false, true
+	}
+	return string(c.Status), c.Reason, c.Message,
+		c.Status == corev1.ConditionTrue, c.Status == corev1.ConditionFalse, c.Status == corev1.ConditionUnknown
+}
+
+func getTemplateTimes(start, completion *metav1.Time) (*time.Time, *time.Time, time.Duration) {
+	if start == nil {
+		return nil, nil, 0
+	}
+	s := start.Time
+	if completion == nil {
+		return &s, nil, templateNow().Sub(s).Round(time.Second)
+	}
+	c := completion.Time
+	return &s, &c, c.Sub(s)
+}
+
+var templateFuncs = template.FuncMap{
+	// json encodes the value as JSON, e.g. to embed a string in a JSON template.
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	// truncate shortens the string to at most n runes.
+	"truncate": func(n int, s string) string {
+		r := []rune(s)
+		if len(r) <= n {
+			return s
+		}
+		return string(r[:n])
+	},
+	"join":  strings.Join,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	// replace replaces all the old in the string with the new, e.g. {{ replace "." "_" .Name }}.
+	"replace": func(old, new, s string) string {
+		return strings.ReplaceAll(s, old, new)
+	},
+}
+
+// parseTemplate parses the message template of the provider.
+func parseTemplate(name, text string) (*template.Template, *ProviderError) {
+	t, err := template.New(name).Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, NewInvalidProviderSpecError(fmt.Sprintf("failed to parse the template: %v", err))
+	}
+	return t, nil
+}
+
+// executeTemplate renders the template with the data model of the run.
+func executeTemplate(t *template.Template, pr *pipelinesv1beta1.PipelineRun) ([]byte, *ProviderError) {
+	var b bytes.Buffer
+	if err := t.Execute(&b, newTemplateData(pr)); err != nil {
+		return nil, NewFailedValidationError(fmt.Sprintf("failed to render the template: %v", err))
+	}
+	return b.Bytes(), nil
+}
+
+// newTemplateSamplePipelineRun returns a failed run used to check the templates in the reconciler.
+func newTemplateSamplePipelineRun() *pipelinesv1beta1.PipelineRun {
+	start := metav1.NewTime(time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC))
+	completion := metav1.NewTime(start.Add(time.Minute))
+	failed := duckv1beta1.Status{
+		Conditions: []apis.Condition{
+			{
+				Type:    apis.ConditionSucceeded,
+				Status:  corev1.ConditionFalse,
+				Reason:  "Failed",
+				Message: "Tasks Completed: 1 (Failed: 1, Cancelled 0), Skipped: 0",
+			},
+		},
+	}
+	return &pipelinesv1beta1.PipelineRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sample-run",
+			Namespace: "default",
+			Labels: map[string]string{
+				"tekton.dev/pipeline": "sample",
+			},
+			Annotations: map[string]string{
+				annotationTektonDashboardBaseURL: "https://dashboard.example.com",
+			},
+		},
+		Spec: pipelinesv1beta1.PipelineRunSpec{
+			PipelineRef: &pipelinesv1beta1.PipelineRef{Name: "sample"},
+			Params: []pipelinesv1beta1.Param{
+				{Name: "revision", Value: *pipelinesv1beta1.NewArrayOrString("main")},
+			},
+		},
+		Status: pipelinesv1beta1.PipelineRunStatus{
+			Status: failed,
+			PipelineRunStatusFields: pipelinesv1beta1.PipelineRunStatusFields{
+				StartTime:      &start,
+				CompletionTime: &completion,
+				PipelineResults: []pipelinesv1beta1.PipelineRunResult{
+					{Name: "digest", Value: "sha256:0"},
+				},
+				TaskRuns: map[string]*pipelinesv1beta1.PipelineRunTaskRunStatus{
+					"sample-run-build": {
+						PipelineTaskName: "build",
+						Status: &pipelinesv1beta1.TaskRunStatus{
+							Status: failed,
+							TaskRunStatusFields: pipelinesv1beta1.TaskRunStatusFields{
+								StartTime:      &start,
+								CompletionTime: &completion,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	knativeapis "knative.dev/pkg/apis"
+	knativeapisduckv1beta1 "knative.dev/pkg/apis/duck/v1beta1"
+
+	pipelinesv1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+)
+
+func TestNewTemplateData(t *testing.T) {
+	d := newTemplateData(newTemplateSamplePipelineRun())
+	assert.Equal(t, "sample-run", d.Name)
+	assert.Equal(t, "default", d.Namespace)
+	assert.Equal(t, "sample", d.Pipeline)
+	assert.Equal(t, "False", d.Status)
+	assert.Equal(t, "Failed", d.Reason)
+	assert.True(t, d.Failed)
+	assert.False(t, d.Succeeded || d.Running)
+	assert.Equal(t, map[string]string{"revision": "main"}, d.Params)
+	assert.Equal(t, map[string]string{"digest": "sha256:0"}, d.Results)
+	assert.Equal(t, "sample", d.Labels["tekton.dev/pipeline"])
+	assert.Equal(t, time.Minute, d.Duration)
+	assert.Equal(t, "https://dashboard.example.com/#/namespaces/default/pipelineruns/sample-run", d.DashboardURL)
+	if assert.Len(t, d.TaskRuns, 1) {
+		assert.Equal(t, "sample-run-build", d.TaskRuns[0].Name)
+		assert.Equal(t, "build", d.TaskRuns[0].PipelineTaskName)
+		assert.True(t, d.TaskRuns[0].Failed)
+		assert.Equal(t, time.Minute, d.TaskRuns[0].Duration)
+	}
+}
+
+func TestNewTemplateDataRunning(t *testing.T) {
+	start := time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)
+	templateNow = func() time.Time { return start.Add(90 * time.Second) }
+	defer func() { templateNow = time.Now }()
+
+	newTaskRun := func(task string, offset time.Duration) *pipelinesv1beta1.PipelineRunTaskRunStatus {
+		t := metav1.NewTime(start.Add(offset))
+		return &pipelinesv1beta1.PipelineRunTaskRunStatus{
+			PipelineTaskName: task,
+			Status: &pipelinesv1beta1.TaskRunStatus{
+				TaskRunStatusFields: pipelinesv1beta1.TaskRunStatusFields{StartTime: &t},
+			},
+		}
+	}
+	pr := &pipelinesv1beta1.PipelineRun{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+		Spec: pipelinesv1beta1.PipelineRunSpec{
+			Params: []pipelinesv1beta1.Param{
+				{Name: "tags", Value: *pipelinesv1beta1.NewArrayOrString("a", "b")},
+			},
+		},
+		Status: pipelinesv1beta1.PipelineRunStatus{
+			Status: knativeapisduckv1beta1.Status{
+				Conditions: []knativeapis.Condition{
+					{Type: knativeapis.ConditionSucceeded, Status: corev1.ConditionUnknown, Reason: "Running"},
+				},
+			},
+			PipelineRunStatusFields: pipelinesv1beta1.PipelineRunStatusFields{
+				StartTime: &metav1.Time{Time: start},
+				TaskRuns: map[string]*pipelinesv1beta1.PipelineRunTaskRunStatus{
+					"foo-b": newTaskRun("test", time.Minute),
+					"foo-a": newTaskRun("build", 0),
+					"foo-c": {PipelineTaskName: "push"},
+					"foo-0": {PipelineTaskName: "lint"},
+				},
+			},
+		},
+	}
+	d := newTemplateData(pr)
+	assert.True(t, d.Running)
+	assert.Nil(t, d.CompletionTime)
+	assert.Equal(t, 90*time.Second, d.Duration)
+	assert.Equal(t, "a,b", d.Params["tags"])
+	var tasks []string
+	for _, tr := range d.TaskRuns {
+		tasks = append(tasks, tr.PipelineTaskName)
+	}
+	assert.Equal(t, []string{"build", "test", "lint", "push"}, tasks)
+}
+
+func TestExecuteTemplate(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		template string
+		want     string
+		wantErr  *ProviderError
+	}{
+		{
+			name:     "Fields",
+			template: `{{ .Name }} {{ .Reason }} {{ index .Params "revision" }} {{ index .Params "missing" }}{{ .Duration }}`,
+			want:     "sample-run Failed main 1m0s",
+		},
+		{
+			name:     "Functions",
+			template: `{{ json .Message }} {{ truncate 5 .Name }} {{ upper .Pipeline }}`,
+			want:     `"Tasks Completed: 1 (Failed: 1, Cancelled 0), Skipped: 0" sampl SAMPLE`,
+		},
+		{
+			name:     "TaskRuns",
+			template: `{{ range .TaskRuns }}{{ .PipelineTaskName }}={{ .Reason }}{{ end }}`,
+			want:     "build=Failed",
+		},
+		{
+			name:     "RenderError",
+			template: `{{ .Unknown }}`,
+			wantErr:  NewFailedValidationError(""),
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			tmpl, perr := parseTemplate(c.name, c.template)
+			assert.Nil(t, perr)
+			b, err := executeTemplate(tmpl, newTemplateSamplePipelineRun())
+			if c.wantErr != nil {
+				if assert.NotNil(t, err) {
+					assert.Equal(t, c.wantErr.Code, err.Code)
+				}
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, c.want, string(b))
+		})
+	}
+}
+
+func TestParseTemplateError(t *testing.T) {
+	_, err := parseTemplate("test", `{{ .Name `)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, ErrorCodeInvalidProviderSpec, err.Code)
+	}
+}
@@ -0,0 +1,203 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"text/template"
+
+	corev1 "k8s.io/api/core/v1"
+	"knative.dev/pkg/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/go-logr/logr"
+	pipelinesv1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+
+	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
+)
+
+const webhookDefaultSignatureHeader = "X-Tekton-Signature-256"
+
+// Webhook sends the runs to an HTTP endpoint.
+type Webhook struct {
+	// URL is a secret, since the URLs of the hooks often contain the tokens.
+	URL                 SecretString
+	Method              string
+	Headers             []webhookHeader
+	Template            *template.Template
+	ExpectedStatusCodes []int32
+	SignatureKey        SecretBytes
+	SignatureHeader     string
+	NotifyRunning       bool
+}
+
+// webhookHeader is a header whose value may be read from a secret.
+type webhookHeader struct {
+	Name  string
+	Value SecretString
+}
+
+var _ Provider = (*Webhook)(nil)
+var _ Validator = (*Webhook)(nil)
+
+func NewWebhook(ctx context.Context, p *v1alpha1.Provider, k client.Client) (*Webhook, *ProviderError) {
+	s := p.Spec.Webhook
+	if s == nil {
+		return nil, NewInvalidProviderSpecError("missing value .webhook")
+	}
+	if u, err := url.Parse(s.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) < 1 {
+		return nil, NewInvalidProviderSpecError("invalid URL in .webhook.url")
+	}
+	a := &Webhook{
+		URL:                 NewSecretString(s.URL),
+		Method:              http.MethodPost,
+		ExpectedStatusCodes: s.ExpectedStatusCodes,
+		NotifyRunning:       s.NotifyRunning,
+	}
+	if s.Method != nil && len(*s.Method) > 0 {
+		a.Method = *s.Method
+	}
+	if len(s.BodyTemplate) > 0 {
+		if a.Method == http.MethodGet {
+			return nil, NewInvalidProviderSpecError("bodyTemplate is not allowed with GET")
+		}
+		t, perr := parseTemplate("bodyTemplate", s.BodyTemplate)
+		if perr != nil {
+			return nil, perr
+		}
+		a.Template = t
+	}
+	for _, h := range s.Headers {
+		if len(h.Name) < 1 {
+			return nil, NewInvalidProviderSpecError("missing name in .webhook.headers")
+		}
+		value := h.Value
+		if h.ValueFrom != nil && h.ValueFrom.SecretRef != nil {
+			b, perr := getSecretValue(ctx, k, p.Namespace, h.ValueFrom.SecretRef, h.Name)
+			if perr != nil {
+				return nil, perr
+			}
+			value = string(b)
+		}
+		a.Headers = append(a.Headers, webhookHeader{Name: h.Name, Value: NewSecretString(value)})
+	}
+	if s.Signature != nil {
+		key, perr := getSecretValue(ctx, k, p.Namespace, &s.Signature.SecretRef, "secret")
+		if perr != nil {
+			return nil, perr
+		}
+		a.SignatureKey = NewSecretBytes(key)
+		a.SignatureHeader = webhookDefaultSignatureHeader
+		if s.Signature.Header != nil && len(*s.Signature.Header) > 0 {
+			a.SignatureHeader = *s.Signature.Header
+		}
+	}
+	return a, nil
+}
+
+func (a *Webhook) Notify(ctx context.Context, pr *pipelinesv1beta1.PipelineRun) *ProviderError {
+	log := logr.FromContext(ctx).WithName("providers.webhook").
+		WithValues("providerType", "Webhook", "pipelineRun", pr.Name)
+	cond := pr.Status.GetCondition(apis.ConditionSucceeded)
+	if cond == nil {
+		log.V(1).Info("PipelineRun has not condition, ignored")
+		return NewSkippedError("PipelineRun has not condition")
+	}
+	if cond.Status == corev1.ConditionUnknown && !a.NotifyRunning {
+		log.V(2).Info("this run is not finished yet, skipped")
+		return NewSkippedError("this run is not finished yet")
+	}
+	req, perr := a.newRequest(ctx, pr)
+	if perr != nil {
+		return perr
+	}
+	if _, err := sendHTTPExpecting(req, a.isExpected); err != nil {
+		return newWebhookError("Webhook", err)
+	}
+	log.V(2).Info("send webhook", "method", a.Method)
+	return nil
+}
+
+// Validate renders the body template with the sample run.
+func (a *Webhook) Validate(ctx context.Context) *ProviderError {
+	_, perr := a.newBody(newTemplateSamplePipelineRun())
+	return perr
+}
+
+func (a *Webhook) newRequest(ctx context.Context, pr *pipelinesv1beta1.PipelineRun) (*http.Request, *ProviderError) {
+	body, perr := a.newBody(pr)
+	if perr != nil {
+		return nil, perr
+	}
+	req, err := http.NewRequestWithContext(ctx, a.Method, a.URL.GetNoRedactedString(), bytes.NewReader(body))
+	if err != nil {
+		return nil, NewRuntimeError("failed to create the request of Webhook")
+	}
+	if len(body) > 0 {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for _, h := range a.Headers {
+		req.Header.Set(h.Name, h.Value.GetNoRedactedString())
+	}
+	if len(a.SignatureHeader) > 0 {
+		req.Header.Set(a.SignatureHeader, signWebhookBody(a.SignatureKey.GetNoRedacted(), body))
+	}
+	return req, nil
+}
+
+// newBody renders the body template, or encodes the built-in JSON of the run.
+// The requests of GET have no body.
+func (a *Webhook) newBody(pr *pipelinesv1beta1.PipelineRun) ([]byte, *ProviderError) {
+	if a.Template != nil {
+		return executeTemplate(a.Template, pr)
+	}
+	if a.Method == http.MethodGet {
+		return nil, nil
+	}
+	b, err := json.Marshal(newRunEvent(pr))
+	if err != nil {
+		return nil, NewRuntimeError(fmt.Sprintf("failed to marshal Webhook payload: %v", err))
+	}
+	return b, nil
+}
+
+func (a *Webhook) isExpected(code int) bool {
+	if len(a.ExpectedStatusCodes) < 1 {
+		return isSuccessStatusCode(code)
+	}
+	for _, c := range a.ExpectedStatusCodes {
+		if int(c) == code {
+			return true
+		}
+	}
+	return false
+}
+
+// signWebhookBody returns the HMAC-SHA256 signature of the body as sha256=<hex digest>.
+func signWebhookBody(key, body []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
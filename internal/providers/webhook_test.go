@@ -0,0 +1,375 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
+)
+
+func TestNewWebhook(t *testing.T) {
+	k := fakeclient.NewClientBuilder().
+		WithObjects(
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "webhook", Namespace: "default"},
+				Data: map[string][]byte{
+					"Authorization": []byte("Bearer t0k3n"),
+					"token":         []byte("t0k3n"),
+					"secret":        []byte("s3cr3t"),
+				},
+			},
+		).
+		Build()
+	secretRef := func(key *string) *v1alpha1.LocalSecretKeyReference {
+		return &v1alpha1.LocalSecretKeyReference{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "webhook"},
+			Key:                  key,
+		}
+	}
+	for _, c := range []struct {
+		name          string
+		spec          *v1alpha1.WebhookSpec
+		wantMethod    string
+		wantHeaders   map[string]string
+		wantSignature string
+		wantErr       *ProviderError
+	}{
+		{
+			name:       "Basic",
+			spec:       &v1alpha1.WebhookSpec{URL: "https://example.com/hook"},
+			wantMethod: http.MethodPost,
+		},
+		{
+			name: "Headers",
+			spec: &v1alpha1.WebhookSpec{
+				URL:    "https://example.com/hook",
+				Method: pointer.String(http.MethodPut),
+				Headers: []v1alpha1.WebhookHeader{
+					{Name: "X-Static", Value: "static"},
+					{Name: "Authorization", ValueFrom: &v1alpha1.WebhookHeaderSource{SecretRef: secretRef(nil)}},
+					{Name: "X-Token", ValueFrom: &v1alpha1.WebhookHeaderSource{SecretRef: secretRef(pointer.String("token"))}},
+				},
+				Signature: &v1alpha1.WebhookSignature{
+					SecretRef: *secretRef(nil),
+				},
+			},
+			wantMethod: http.MethodPut,
+			wantHeaders: map[string]string{
+				"X-Static":      "static",
+				"Authorization": "Bearer t0k3n",
+				"X-Token":       "t0k3n",
+			},
+			wantSignature: "X-Tekton-Signature-256",
+		},
+		{
+			name: "SignatureHeader",
+			spec: &v1alpha1.WebhookSpec{
+				URL: "https://example.com/hook",
+				Signature: &v1alpha1.WebhookSignature{
+					SecretRef: *secretRef(nil),
+					Header:    pointer.String("X-Hub-Signature-256"),
+				},
+			},
+			wantMethod:    http.MethodPost,
+			wantSignature: "X-Hub-Signature-256",
+		},
+		{
+			name: "MissingHeaderKey",
+			spec: &v1alpha1.WebhookSpec{
+				URL: "https://example.com/hook",
+				Headers: []v1alpha1.WebhookHeader{
+					{Name: "X-Unknown", ValueFrom: &v1alpha1.WebhookHeaderSource{SecretRef: secretRef(nil)}},
+				},
+			},
+			wantErr: NewNotFoundPrivateKeyError(""),
+		},
+		{
+			name: "MissingHeaderName",
+			spec: &v1alpha1.WebhookSpec{
+				URL:     "https://example.com/hook",
+				Headers: []v1alpha1.WebhookHeader{{Value: "value"}},
+			},
+			wantErr: NewInvalidProviderSpecError(""),
+		},
+		{
+			name:    "InvalidURL",
+			spec:    &v1alpha1.WebhookSpec{URL: "example.com/hook"},
+			wantErr: NewInvalidProviderSpecError(""),
+		},
+		{
+			name: "InvalidTemplate",
+			spec: &v1alpha1.WebhookSpec{
+				URL:          "https://example.com/hook",
+				BodyTemplate: "{{ .Name",
+			},
+			wantErr: NewInvalidProviderSpecError(""),
+		},
+		{
+			name: "BodyWithGET",
+			spec: &v1alpha1.WebhookSpec{
+				URL:          "https://example.com/hook",
+				Method:       pointer.String(http.MethodGet),
+				BodyTemplate: "{{ .Name }}",
+			},
+			wantErr: NewInvalidProviderSpecError(""),
+		},
+		{
+			name:    "WebhookSpecNotFound",
+			wantErr: NewInvalidProviderSpecError(""),
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			p := &v1alpha1.Provider{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "provider",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.ProviderSpec{
+					Type:    "Webhook",
+					Webhook: c.spec,
+				},
+			}
+			a, err := NewWebhook(ctx, p, k)
+			if c.wantErr != nil {
+				if assert.NotNil(t, err) {
+					assert.Equal(t, c.wantErr.Code, err.Code)
+				}
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, "https://example.com/hook", a.URL.GetNoRedactedString())
+			assert.Equal(t, c.wantMethod, a.Method)
+			headers := map[string]string{}
+			for _, h := range a.Headers {
+				headers[h.Name] = h.Value.GetNoRedactedString()
+			}
+			if len(c.wantHeaders) > 0 {
+				assert.Equal(t, c.wantHeaders, headers)
+			} else {
+				assert.Empty(t, headers)
+			}
+			assert.Equal(t, c.wantSignature, a.SignatureHeader)
+			if len(c.wantSignature) > 0 {
+				assert.Equal(t, "s3cr3t", a.SignatureKey.GetNoRedactedString())
+			}
+		})
+	}
+}
+
+func TestWebhookNotify(t *testing.T) {
+	sign := func(body string) string {
+		mac := hmac.New(sha256.New, []byte("s3cr3t"))
+		mac.Write([]byte(body))
+		return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+	for _, c := range []struct {
+		name        string
+		method      string
+		template    string
+		expected    []int32
+		status      corev1.ConditionStatus
+		running     bool
+		respCode    int
+		want        string
+		wantJSON    bool
+		wantNotSent bool
+		wantErr     *ProviderError
+		wantSkipped bool
+	}{
+		{
+			name:     "Default",
+			method:   http.MethodPost,
+			status:   corev1.ConditionFalse,
+			respCode: http.StatusOK,
+			want: `{
+				"name": "sample-run",
+				"namespace": "default",
+				"uid": "",
+				"pipeline": "sample",
+				"transition": "failed",
+				"status": "False",
+				"reason": "Failed",
+				"message": "Tasks Completed: 1 (Failed: 1, Cancelled 0), Skipped: 0",
+				"startTime": "2021-07-01T00:00:00Z",
+				"completionTime": "2021-07-01T00:01:00Z",
+				"duration": "1m0s",
+				"dashboardURL": "https://dashboard.example.com/#/namespaces/default/pipelineruns/sample-run",
+				"labels": {"tekton.dev/pipeline": "sample"},
+				"params": {"revision": "main"},
+				"results": {"digest": "sha256:0"},
+				"taskRuns": [{"name": "sample-run-build", "pipelineTask": "build", "status": "False", "reason": "Failed"}]
+			}`,
+			wantJSON: true,
+		},
+		{
+			name:     "Template",
+			method:   http.MethodPut,
+			template: `{"text": "{{ .Name }} is {{ .Reason }}"}`,
+			status:   corev1.ConditionTrue,
+			respCode: http.StatusAccepted,
+			want:     `{"text": "sample-run is Failed"}`,
+		},
+		{
+			name:     "ExpectedStatusCode",
+			method:   http.MethodPost,
+			template: "{{ .Name }}",
+			expected: []int32{http.StatusFound},
+			status:   corev1.ConditionTrue,
+			respCode: http.StatusFound,
+			want:     "sample-run",
+		},
+		{
+			name:     "UnexpectedStatusCode",
+			method:   http.MethodPost,
+			template: "{{ .Name }}",
+			expected: []int32{http.StatusCreated},
+			status:   corev1.ConditionTrue,
+			respCode: http.StatusOK,
+			want:     "sample-run",
+			wantErr:  NewRuntimeError(""),
+		},
+		{
+			name:     "GET",
+			method:   http.MethodGet,
+			status:   corev1.ConditionTrue,
+			respCode: http.StatusOK,
+		},
+		{
+			name:     "NotifyRunning",
+			method:   http.MethodPost,
+			template: "{{ .Status }}",
+			status:   corev1.ConditionUnknown,
+			running:  true,
+			respCode: http.StatusOK,
+			want:     "Unknown",
+		},
+		{
+			name:        "Running",
+			wantSkipped: true,
+			method:      http.MethodPost,
+			status:      corev1.ConditionUnknown,
+			wantNotSent: true,
+		},
+		{
+			name:     "ServerError",
+			method:   http.MethodPost,
+			template: "{{ .Name }}",
+			status:   corev1.ConditionFalse,
+			want:     "sample-run",
+			respCode: http.StatusBadRequest,
+			wantErr:  NewRuntimeError(""),
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			sent := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				sent++
+				assert.Equal(t, c.method, r.Method)
+				assert.Equal(t, "/hook/t0k3n", r.URL.Path)
+				assert.Equal(t, "static", r.Header.Get("X-Static"))
+				b, err := ioutil.ReadAll(r.Body)
+				if err != nil {
+					t.Errorf("failed to read request: %v", err)
+				}
+				if c.wantJSON {
+					assert.JSONEq(t, c.want, string(b))
+				} else {
+					assert.Equal(t, c.want, string(b))
+				}
+				switch {
+				case c.wantJSON:
+					assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+				case len(b) > 0:
+					assert.Equal(t, "text/plain", r.Header.Get("Content-Type"))
+				}
+				assert.Equal(t, sign(string(b)), r.Header.Get("X-Tekton-Signature-256"))
+				w.WriteHeader(c.respCode)
+			}))
+			defer srv.Close()
+
+			a := &Webhook{
+				URL:    NewSecretString(srv.URL + "/hook/t0k3n"),
+				Method: c.method,
+				Headers: []webhookHeader{
+					{Name: "X-Static", Value: NewSecretString("static")},
+				},
+				ExpectedStatusCodes: c.expected,
+				SignatureKey:        NewSecretBytes([]byte("s3cr3t")),
+				SignatureHeader:     "X-Tekton-Signature-256",
+				NotifyRunning:       c.running,
+			}
+			if !c.wantJSON {
+				a.Headers = append(a.Headers, webhookHeader{Name: "Content-Type", Value: NewSecretString("text/plain")})
+			}
+			if len(c.template) > 0 {
+				tmpl, perr := parseTemplate("bodyTemplate", c.template)
+				if !assert.Nil(t, perr) {
+					return
+				}
+				a.Template = tmpl
+			}
+			pr := newTemplateSamplePipelineRun()
+			pr.Status.Conditions[0].Status = c.status
+			err := a.Notify(ctx, pr)
+			if c.wantErr != nil {
+				if assert.NotNil(t, err) {
+					assert.Equal(t, c.wantErr.Code, err.Code)
+					assert.NotContains(t, err.Message, "t0k3n")
+				}
+				return
+			}
+			if c.wantSkipped {
+				assert.True(t, err.IsSkipped())
+			} else {
+				assert.Nil(t, err)
+			}
+			if c.wantNotSent {
+				assert.Equal(t, 0, sent)
+			} else {
+				assert.Equal(t, 1, sent)
+			}
+		})
+	}
+}
+
+func TestWebhookValidate(t *testing.T) {
+	tmpl, perr := parseTemplate("bodyTemplate", "{{ .Unknown }}")
+	if !assert.Nil(t, perr) {
+		return
+	}
+	a := &Webhook{Method: http.MethodPost, Template: tmpl}
+	assert.NotNil(t, a.Validate(ctx))
+
+	a.Template = nil
+	assert.Nil(t, a.Validate(ctx))
+}
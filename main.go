@@ -53,10 +53,18 @@ func init() {
 
 func main() {
 	var configFile string
+	var slackActionsBindAddress string
+	var slackActionsCertDir string
 	flag.StringVar(&configFile, "config", "",
 		"The controller will load its initial configuration from this file. "+
 			"Omit this flag to use the default configuration values. "+
 			"Command-line flags override configuration from this file.")
+	flag.StringVar(&slackActionsBindAddress, "slack-actions-bind-address", "",
+		"The address the endpoint of the Slack buttons binds to. e.g. :9443 "+
+			"Omit this flag to disable the endpoint.")
+	flag.StringVar(&slackActionsCertDir, "slack-actions-cert-dir", "",
+		"The directory containing tls.crt and tls.key for the endpoint of the Slack buttons. "+
+			"Omit this flag to serve plain HTTP behind a TLS terminating ingress.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -98,12 +106,23 @@ func main() {
 	//+kubebuilder:scaffold:builder
 
 	if err = (&controllers.PipelineRunReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("tekton-integration"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "PipelineRun")
 		os.Exit(1)
 	}
+	if slackActionsBindAddress != "" {
+		if err = (&controllers.SlackActionServer{
+			Client:      mgr.GetClient(),
+			BindAddress: slackActionsBindAddress,
+			CertDir:     slackActionsCertDir,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create server", "server", "SlackActions")
+			os.Exit(1)
+		}
+	}
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
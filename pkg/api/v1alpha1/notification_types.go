@@ -43,6 +43,21 @@ type RunFilter struct {
 	LabelSelector *metav1.LabelSelector `json:"labelSelector,omitempty"`
 }
 
+// Mentions defines the mentions added to the notifications of failed runs.
+type Mentions struct {
+	// Mention the active members of the channel with @here.
+	// +optional
+	Here bool `json:"here,omitempty"`
+
+	// Mention all the members of the channel with @channel.
+	// +optional
+	Channel bool `json:"channel,omitempty"`
+
+	// The IDs of the user groups to mention, e.g. S0123456789.
+	// +optional
+	UserGroups []string `json:"userGroups,omitempty"`
+}
+
 // NotificationSpec defines the desired state of Notification
 type NotificationSpec struct {
 	// Handle events using this provider.
@@ -53,6 +68,11 @@ type NotificationSpec struct {
 	// Defaults to false.
 	// +optional
 	Suspend bool `json:"suspend,omitempty"`
+
+	// Mentions added to the notifications when the run fails.
+	// The providers that don't support mentions ignore it.
+	// +optional
+	Mentions *Mentions `json:"mentions,omitempty"`
 }
 
 // NotificationStatus defines the observed state of Notification
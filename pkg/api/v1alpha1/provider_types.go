@@ -29,6 +29,14 @@ type LocalSecretKeyReference struct {
 	Key *string `json:"key,omitempty"`
 }
 
+// +structType=atomic
+type LocalConfigMapKeyReference struct {
+	corev1.LocalObjectReference `json:",inline"`
+
+	// +optional
+	Key *string `json:"key,omitempty"`
+}
+
 type AccessTokenSource struct {
 	// +optional
 	SecretRef *LocalSecretKeyReference `json:"secretRef,omitempty"`
@@ -40,18 +48,478 @@ type SlackChannel struct {
 	// +optional
 	ID *string `json:"id,omitempty"`
 	// The name of the channel. If an ID is specified, it will be ignored.
+	// The name is resolved to the ID by conversations.list, which requires
+	// the channels:read and groups:read scopes.
 	// +optional
 	Name *string `json:"name,omitempty"`
 }
 
+// SlackUserMapping maps the authors of the runs to Slack users.
+type SlackUserMapping struct {
+	// Look up the users by email with users.lookupByEmail.
+	// It requires the users:read.email scope.
+	// +optional
+	LookupByEmail bool `json:"lookupByEmail,omitempty"`
+
+	// The ConfigMap which has a YAML map from the emails or the names of the authors
+	// to Slack user IDs. The key defaults to users.yaml.
+	// +optional
+	ConfigMapRef *LocalConfigMapKeyReference `json:"configMapRef,omitempty"`
+}
+
 // SlackAppSpec represents information about an Slack App.
 type SlackAppSpec struct {
 	// +required
 	AccessToken AccessTokenSource `json:"accessToken"`
 
+	// The base URL of the Slack Web API. Defaults to https://slack.com/api
+	// +optional
+	BaseURL *string `json:"baseURL,omitempty"`
+
 	// +required
 	// +kubebuilder:validation:MinItems=1
 	Channels []SlackChannel `json:"channels"`
+
+	// Join the public channels specified by name if the bot is not in them.
+	// It requires the channels:join scope.
+	// +optional
+	AutoJoin bool `json:"autoJoin,omitempty"`
+
+	// The Go text/template to render the message, which outputs a JSON object
+	// with blocks, attachments and text like Block Kit Builder.
+	// Defaults to the built-in message.
+	// +optional
+	MessageTemplate string `json:"messageTemplate,omitempty"`
+
+	// Post a message when the run starts, and update the message with the progress and the result
+	// instead of posting a new message.
+	// +optional
+	UpdateMessage bool `json:"updateMessage,omitempty"`
+
+	// Reply the details of the failed tasks in the thread of the message.
+	// +optional
+	ReplyFailureDetails bool `json:"replyFailureDetails,omitempty"`
+
+	// Mention the authors of the failed runs. The authors are read from
+	// the annotations, the labels or the params author-email and triggered-by.
+	// +optional
+	MentionAuthors *SlackUserMapping `json:"mentionAuthors,omitempty"`
+
+	// Add the buttons to re-run or cancel the PipelineRun to the messages.
+	// The controller must be started with the Slack actions endpoint enabled.
+	// +optional
+	Interactivity *SlackInteractivity `json:"interactivity,omitempty"`
+}
+
+// SlackInteractivity represents the settings of the buttons to re-run or cancel the runs.
+// The users not listed in allowedUsers nor allowedUserGroups can't push the buttons.
+type SlackInteractivity struct {
+	// The signing secret of the Slack App to verify the requests from Slack.
+	// +required
+	SigningSecret SigningSecretSource `json:"signingSecret"`
+
+	// The IDs of the Slack users allowed to push the buttons. e.g. U1234567890
+	// +optional
+	AllowedUsers []string `json:"allowedUsers,omitempty"`
+
+	// The IDs of the Slack user groups whose members are allowed to push the buttons.
+	// It requires the usergroups:read scope. e.g. S1234567890
+	// +optional
+	AllowedUserGroups []string `json:"allowedUserGroups,omitempty"`
+}
+
+type SigningSecretSource struct {
+	// The key defaults to signing-secret.
+	// +optional
+	SecretRef *LocalSecretKeyReference `json:"secretRef,omitempty"`
+}
+
+// SlackWebhookSpec represents information about a Slack Incoming Webhook.
+type SlackWebhookSpec struct {
+	// The URL of the Incoming Webhook.
+	// +required
+	URL WebhookURLSource `json:"url"`
+
+	// The Go text/template to render the message, which outputs a JSON object
+	// with blocks, attachments and text like Block Kit Builder.
+	// Defaults to the built-in message.
+	// +optional
+	MessageTemplate string `json:"messageTemplate,omitempty"`
+}
+
+// MicrosoftTeamsSpec represents information about a Microsoft Teams incoming webhook or a Workflows URL.
+type MicrosoftTeamsSpec struct {
+	// The URL of the incoming webhook, or the HTTP trigger of the Workflows.
+	// +required
+	URL WebhookURLSource `json:"url"`
+}
+
+// DiscordSpec represents information about a Discord webhook.
+type DiscordSpec struct {
+	// The URL of the webhook.
+	// +required
+	URL WebhookURLSource `json:"url"`
+
+	// Override the default username of the webhook.
+	// +optional
+	Username *string `json:"username,omitempty"`
+}
+
+// MattermostSpec represents information about a Mattermost bot account.
+type MattermostSpec struct {
+	// The base URL of Mattermost. e.g. https://mattermost.example.com
+	// +required
+	BaseURL string `json:"baseURL"`
+
+	// The access token of the bot account, which must be a member of the channels.
+	// +required
+	AccessToken AccessTokenSource `json:"accessToken"`
+
+	// The IDs of the channels to post the messages.
+	// +required
+	// +kubebuilder:validation:MinItems=1
+	ChannelIDs []string `json:"channelIDs"`
+
+	// Reply the details of the failed tasks in the thread of the message.
+	// +optional
+	ReplyFailureDetails bool `json:"replyFailureDetails,omitempty"`
+}
+
+// RocketChatSpec represents information about a Rocket.Chat user or bot.
+type RocketChatSpec struct {
+	// The base URL of Rocket.Chat. e.g. https://rocketchat.example.com
+	// +required
+	BaseURL string `json:"baseURL"`
+
+	// The secret which has the keys user-id and auth-token of the personal access token.
+	// +required
+	SecretRef corev1.LocalObjectReference `json:"secretRef"`
+
+	// The channels to post the messages, e.g. #general, @user or a room ID.
+	// +required
+	// +kubebuilder:validation:MinItems=1
+	Channels []string `json:"channels"`
+
+	// Override the display name of the messages. It requires the message-impersonate permission.
+	// +optional
+	Alias *string `json:"alias,omitempty"`
+}
+
+// EmailSpec represents information about an SMTP relay to send the emails.
+type EmailSpec struct {
+	// The host of the SMTP server.
+	// +required
+	Host string `json:"host"`
+
+	// The port of the SMTP server. Defaults to 465 with TLS, or 587 otherwise.
+	// +optional
+	Port *int32 `json:"port,omitempty"`
+
+	// The TLS mode of the connection. StartTLS upgrades the connection and fails if the server does not support it,
+	// TLS connects with the implicit TLS, and None sends the emails in plaintext. Defaults to StartTLS.
+	// +kubebuilder:validation:Enum=StartTLS;TLS;None
+	// +optional
+	TLS *string `json:"tls,omitempty"`
+
+	// The secret which has the keys username and password for the PLAIN authentication.
+	// If not specified, the emails are sent without the authentication.
+	// +optional
+	SecretRef *corev1.LocalObjectReference `json:"secretRef,omitempty"`
+
+	// The address of the sender.
+	// +required
+	From string `json:"from"`
+
+	// The static recipients.
+	// +optional
+	To []string `json:"to,omitempty"`
+
+	// The name of the param of the run which has the comma-separated recipients.
+	// The recipients are also read from the annotation integrations.tekton.ornew.io/email-to.
+	// +optional
+	ToParam *string `json:"toParam,omitempty"`
+
+	// The domains allowed for the recipients read from the runs.
+	// If not specified, the recipients read from the runs are ignored.
+	// +optional
+	AllowedDomains []string `json:"allowedDomains,omitempty"`
+
+	// The Go text/template to render the subject. Defaults to the reason and the name of the run.
+	// +optional
+	SubjectTemplate string `json:"subjectTemplate,omitempty"`
+
+	// The Go text/template to render the plaintext body. Defaults to the built-in body.
+	// +optional
+	TextTemplate string `json:"textTemplate,omitempty"`
+
+	// The Go html/template to render the HTML body. Defaults to the built-in body.
+	// +optional
+	HTMLTemplate string `json:"htmlTemplate,omitempty"`
+}
+
+// PagerDutySpec represents information about a PagerDuty service integrated with the Events API v2.
+type PagerDutySpec struct {
+	// The integration key of the Events API v2 integration on the service. The key defaults to routing-key.
+	// +required
+	RoutingKey RoutingKeySource `json:"routingKey"`
+
+	// The severity of the incidents. Defaults to error.
+	// +kubebuilder:validation:Enum=critical;error;warning;info
+	// +optional
+	Severity *string `json:"severity,omitempty"`
+
+	// The base URL of the Events API. Defaults to https://events.pagerduty.com
+	// For the EU service region, https://events.eu.pagerduty.com
+	// +optional
+	BaseURL *string `json:"baseURL,omitempty"`
+
+	// The additional custom details of the incidents. The values are Go text/templates
+	// rendered with the run, and override the built-in details of the same keys.
+	// +optional
+	CustomDetails map[string]string `json:"customDetails,omitempty"`
+}
+
+// OpsgenieSpec represents information about an Opsgenie API integration.
+type OpsgenieSpec struct {
+	// The API key of the API integration, which requires the Create and Update access. The key defaults to api-key.
+	// +required
+	APIKey APIKeySource `json:"apiKey"`
+
+	// The region of the Opsgenie account: US or EU. Defaults to US.
+	// +kubebuilder:validation:Enum=US;EU
+	// +optional
+	Region *string `json:"region,omitempty"`
+
+	// The base URL of the API, which overrides the region. e.g. https://api.opsgenie.com
+	// +optional
+	BaseURL *string `json:"baseURL,omitempty"`
+
+	// The priority of the alerts. Defaults to P3.
+	// +kubebuilder:validation:Enum=P1;P2;P3;P4;P5
+	// +optional
+	Priority *string `json:"priority,omitempty"`
+
+	// The priorities by the reasons of the failed runs, which override the priority.
+	// e.g. PipelineRunTimeout: P2
+	// +optional
+	ReasonPriorities map[string]string `json:"reasonPriorities,omitempty"`
+
+	// The static tags of the alerts.
+	// +optional
+	Tags []string `json:"tags,omitempty"`
+
+	// The keys of the labels of the run copied to the tags of the alerts as key:value.
+	// +optional
+	TagLabels []string `json:"tagLabels,omitempty"`
+}
+
+// WebhookSpec represents information about an HTTP endpoint receiving the runs.
+type WebhookSpec struct {
+	// The URL of the endpoint.
+	// +required
+	URL string `json:"url"`
+
+	// The method of the requests. Defaults to POST.
+	// +kubebuilder:validation:Enum=GET;POST;PUT;PATCH;DELETE
+	// +optional
+	Method *string `json:"method,omitempty"`
+
+	// The headers of the requests.
+	// +optional
+	Headers []WebhookHeader `json:"headers,omitempty"`
+
+	// The Go text/template to render the body. Defaults to the built-in JSON of the run.
+	// +optional
+	BodyTemplate string `json:"bodyTemplate,omitempty"`
+
+	// The status codes of the successful responses. Defaults to any 2xx.
+	// +optional
+	ExpectedStatusCodes []int32 `json:"expectedStatusCodes,omitempty"`
+
+	// Sign the body with HMAC-SHA256.
+	// +optional
+	Signature *WebhookSignature `json:"signature,omitempty"`
+
+	// Notify the started runs in addition to the finished runs.
+	// +optional
+	NotifyRunning bool `json:"notifyRunning,omitempty"`
+}
+
+// WebhookHeader represents a header of the requests.
+type WebhookHeader struct {
+	// +required
+	Name string `json:"name"`
+
+	// +optional
+	Value string `json:"value,omitempty"`
+
+	// The source of the value, which takes precedence over the value.
+	// +optional
+	ValueFrom *WebhookHeaderSource `json:"valueFrom,omitempty"`
+}
+
+// WebhookHeaderSource represents the source of the value of a header.
+type WebhookHeaderSource struct {
+	// The key defaults to the name of the header.
+	// +optional
+	SecretRef *LocalSecretKeyReference `json:"secretRef,omitempty"`
+}
+
+// WebhookSignature represents the HMAC-SHA256 signature of the body,
+// which is sent as sha256=<hex digest> in the header.
+type WebhookSignature struct {
+	// The shared secret. The key defaults to secret.
+	// +required
+	SecretRef LocalSecretKeyReference `json:"secretRef"`
+
+	// The name of the header. Defaults to X-Tekton-Signature-256.
+	// +optional
+	Header *string `json:"header,omitempty"`
+}
+
+// KafkaSpec represents information about a Kafka topic receiving the events of the runs.
+type KafkaSpec struct {
+	// The addresses of the bootstrap brokers. e.g. kafka-0.kafka:9092
+	// +kubebuilder:validation:MinItems=1
+	// +required
+	Brokers []string `json:"brokers"`
+
+	// +required
+	Topic string `json:"topic"`
+
+	// The encoding of the records. Defaults to JSON.
+	// CloudEvents is the structured mode of the CloudEvents Kafka protocol binding.
+	// +kubebuilder:validation:Enum=JSON;CloudEvents
+	// +optional
+	Encoding *string `json:"encoding,omitempty"`
+
+	// The acknowledgment of the brokers required for the delivery. Defaults to All.
+	// +kubebuilder:validation:Enum=None;Leader;All
+	// +optional
+	RequiredAcks *string `json:"requiredAcks,omitempty"`
+
+	// The version of Kafka. e.g. 2.8.0
+	// +optional
+	Version *string `json:"version,omitempty"`
+
+	// Connect to the brokers with TLS.
+	// +optional
+	TLS *KafkaTLS `json:"tls,omitempty"`
+
+	// +optional
+	SASL *KafkaSASL `json:"sasl,omitempty"`
+}
+
+// KafkaTLS represents the TLS connections to the brokers.
+type KafkaTLS struct {
+	// The secret which has the PEM-encoded CA certificates in ca.crt, and the client certificate
+	// in tls.crt and tls.key. All the keys are optional, and the brokers are verified
+	// with the system roots if ca.crt is missing.
+	// +optional
+	SecretRef *corev1.LocalObjectReference `json:"secretRef,omitempty"`
+}
+
+// KafkaSASL represents the SASL authentication to the brokers.
+type KafkaSASL struct {
+	// Defaults to PLAIN.
+	// +kubebuilder:validation:Enum=PLAIN;SCRAM-SHA-256;SCRAM-SHA-512
+	// +optional
+	Mechanism *string `json:"mechanism,omitempty"`
+
+	// The secret which has the keys username and password.
+	// +required
+	SecretRef corev1.LocalObjectReference `json:"secretRef"`
+}
+
+// NATSSpec represents information about NATS receiving the events of the runs.
+type NATSSpec struct {
+	// The URLs of the servers. e.g. nats://nats:4222
+	// +kubebuilder:validation:MinItems=1
+	// +required
+	Servers []string `json:"servers"`
+
+	// The Go text/template to render the subject.
+	// Defaults to tekton.{{ .Namespace }}.{{ or .Pipeline .Name | replace "." "_" }}.{{ .Transition }}
+	// +optional
+	Subject *string `json:"subject,omitempty"`
+
+	// The encoding of the messages. Defaults to JSON.
+	// +kubebuilder:validation:Enum=JSON;CloudEvents
+	// +optional
+	Encoding *string `json:"encoding,omitempty"`
+
+	// Publish the messages to JetStream, and wait for the acknowledgment of the stream.
+	// +optional
+	JetStream bool `json:"jetStream,omitempty"`
+
+	// The secret which has the credentials file of the user, which has the JWT and the NKEY seed.
+	// The key defaults to user.creds.
+	// +optional
+	CredentialsSecretRef *LocalSecretKeyReference `json:"credentialsSecretRef,omitempty"`
+
+	// The secret which has the NKEY seed of the user. The key defaults to seed.
+	// +optional
+	NKeySecretRef *LocalSecretKeyReference `json:"nkeySecretRef,omitempty"`
+}
+
+// AlertmanagerSpec represents information about a Prometheus Alertmanager.
+type AlertmanagerSpec struct {
+	// The base URL of Alertmanager. e.g. http://alertmanager.monitoring:9093
+	// +required
+	URL string `json:"url"`
+
+	// The type of the credentials in the secret.
+	// Basic requires the keys username and password, and Bearer requires the key token.
+	// If not specified, the alerts are posted without the authentication.
+	// +kubebuilder:validation:Enum=Basic;Bearer
+	// +optional
+	AuthType *string `json:"authType,omitempty"`
+
+	// +optional
+	SecretRef *corev1.LocalObjectReference `json:"secretRef,omitempty"`
+
+	// The ConfigMap which has the PEM-encoded CA certificates to verify Alertmanager
+	// in addition to the system roots. The key defaults to ca.crt.
+	// +optional
+	CA *LocalConfigMapKeyReference `json:"ca,omitempty"`
+
+	// The alertname label of the alerts. Defaults to TektonPipelineRunFailed.
+	// +optional
+	AlertName *string `json:"alertName,omitempty"`
+
+	// The static labels of the alerts.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// The labels of the run copied to the alerts, from the keys of the labels of the run
+	// to the names of the labels of the alerts. e.g. tekton.dev/pipeline: tekton_pipeline
+	// +optional
+	CopyLabels map[string]string `json:"copyLabels,omitempty"`
+
+	// The duration after which the alerts are resolved by Alertmanager
+	// if the pipelines do not recover. Defaults to 24h.
+	// +optional
+	ResolveTimeout *metav1.Duration `json:"resolveTimeout,omitempty"`
+}
+
+// APIKeySource represents the source of the API key.
+type APIKeySource struct {
+	// +optional
+	SecretRef *LocalSecretKeyReference `json:"secretRef,omitempty"`
+}
+
+// RoutingKeySource represents the source of the routing key.
+type RoutingKeySource struct {
+	// +optional
+	SecretRef *LocalSecretKeyReference `json:"secretRef,omitempty"`
+}
+
+// WebhookURLSource represents the source of the webhook URL,
+// which is read from a secret since the URL contains the credentials.
+type WebhookURLSource struct {
+	// The key defaults to url.
+	// +optional
+	SecretRef *LocalSecretKeyReference `json:"secretRef,omitempty"`
 }
 
 type PrivateKeySource struct {
@@ -59,6 +527,16 @@ type PrivateKeySource struct {
 	SecretRef *LocalSecretKeyReference `json:"secretRef,omitempty"`
 }
 
+// GitHubAppInstallation represents an installation of the GitHub App on an account.
+type GitHubAppInstallation struct {
+	// The login of the user or organization that installed the app.
+	// +required
+	Owner string `json:"owner"`
+
+	// +required
+	InstallationID int64 `json:"installationID"`
+}
+
 // GitHubAppSpec represents information about an GitHub App.
 type GitHubAppSpec struct {
 	// +required
@@ -69,6 +547,80 @@ type GitHubAppSpec struct {
 
 	// +optional
 	BaseURL *string `json:"baseURL,omitempty"`
+
+	// The installation used for repositories of owners not listed in installations.
+	// If not specified, the installation is looked up from the repository on each notification.
+	// +optional
+	InstallationID *int64 `json:"installationID,omitempty"`
+
+	// The installations for an app installed on multiple accounts.
+	// +optional
+	Installations []GitHubAppInstallation `json:"installations,omitempty"`
+}
+
+// GitLabSpec represents information about a GitLab project access token.
+type GitLabSpec struct {
+	// The base URL of GitLab. Defaults to https://gitlab.com
+	// +optional
+	BaseURL *string `json:"baseURL,omitempty"`
+
+	// The access token requires the api scope.
+	// +required
+	AccessToken AccessTokenSource `json:"accessToken"`
+
+	// Comment the result on the merge request of the run.
+	// +optional
+	MergeRequestComment bool `json:"mergeRequestComment,omitempty"`
+}
+
+// BitbucketSpec represents information about Bitbucket Cloud or Bitbucket Data Center.
+type BitbucketSpec struct {
+	// The base URL of Bitbucket Data Center. If not specified, Bitbucket Cloud is used.
+	// +optional
+	BaseURL *string `json:"baseURL,omitempty"`
+
+	// The type of the credentials in the secret.
+	// AppPassword requires the keys username and app-password.
+	// OAuthConsumer requires the keys client-id and client-secret, and is supported only on Bitbucket Cloud.
+	// AccessToken requires the key access-token.
+	// +kubebuilder:validation:Enum=AppPassword;OAuthConsumer;AccessToken
+	// +required
+	AuthType string `json:"authType"`
+
+	// +required
+	SecretRef corev1.LocalObjectReference `json:"secretRef"`
+}
+
+// GiteaSpec represents information about a Gitea or Forgejo access token.
+type GiteaSpec struct {
+	// The base URL of Gitea. e.g. https://gitea.example.com
+	// +required
+	BaseURL string `json:"baseURL"`
+
+	// The access token requires the write:repository scope.
+	// +required
+	AccessToken AccessTokenSource `json:"accessToken"`
+}
+
+// AzureDevOpsSpec represents information about Azure DevOps Services or Azure DevOps Server.
+type AzureDevOpsSpec struct {
+	// The base URL of Azure DevOps. Defaults to https://dev.azure.com
+	// For Azure DevOps Server, e.g. https://server/tfs
+	// +optional
+	BaseURL *string `json:"baseURL,omitempty"`
+
+	// The organization, or the collection of Azure DevOps Server.
+	// If not specified, it must be given by the run.
+	// +optional
+	Organization *string `json:"organization,omitempty"`
+
+	// The personal access token requires the Code (status) scope.
+	// +required
+	AccessToken AccessTokenSource `json:"accessToken"`
+
+	// The genre of the statuses. Defaults to tekton.
+	// +optional
+	Genre *string `json:"genre,omitempty"`
 }
 
 // ProviderSpec defines the desired state of Provider
@@ -84,6 +636,58 @@ type ProviderSpec struct {
 	GitHubApp *GitHubAppSpec `json:"githubApp,omitempty"`
 	// +optional
 	SlackApp *SlackAppSpec `json:"slackApp,omitempty"`
+	// +optional
+	GitLab *GitLabSpec `json:"gitlab,omitempty"`
+	// +optional
+	Bitbucket *BitbucketSpec `json:"bitbucket,omitempty"`
+	// +optional
+	Gitea *GiteaSpec `json:"gitea,omitempty"`
+	// +optional
+	AzureDevOps *AzureDevOpsSpec `json:"azureDevOps,omitempty"`
+	// +optional
+	SlackWebhook *SlackWebhookSpec `json:"slackWebhook,omitempty"`
+	// +optional
+	MicrosoftTeams *MicrosoftTeamsSpec `json:"microsoftTeams,omitempty"`
+	// +optional
+	Discord *DiscordSpec `json:"discord,omitempty"`
+	// +optional
+	Mattermost *MattermostSpec `json:"mattermost,omitempty"`
+	// +optional
+	RocketChat *RocketChatSpec `json:"rocketChat,omitempty"`
+	// +optional
+	Email *EmailSpec `json:"email,omitempty"`
+	// +optional
+	PagerDuty *PagerDutySpec `json:"pagerDuty,omitempty"`
+	// +optional
+	Opsgenie *OpsgenieSpec `json:"opsgenie,omitempty"`
+	// +optional
+	Alertmanager *AlertmanagerSpec `json:"alertmanager,omitempty"`
+	// +optional
+	Webhook *WebhookSpec `json:"webhook,omitempty"`
+	// +optional
+	Kafka *KafkaSpec `json:"kafka,omitempty"`
+	// +optional
+	NATS *NATSSpec `json:"nats,omitempty"`
+}
+
+// PipelineOutcome is the last outcome of a pipeline notified by the provider.
+type PipelineOutcome struct {
+	// The identity of the pipeline: the namespace and the context-id, or the name of the pipeline.
+	// e.g. default/sample
+	// +required
+	Pipeline string `json:"pipeline"`
+
+	// The status of the Succeeded condition of the last run: True or False.
+	// +required
+	Status metav1.ConditionStatus `json:"status"`
+
+	// The name of the last run.
+	// +required
+	PipelineRun string `json:"pipelineRun"`
+
+	// The start time of the last run. The outcomes of the older runs are ignored.
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
 }
 
 // ProviderStatus defines the observed state of Provider
@@ -94,6 +698,13 @@ type ProviderStatus struct {
 	// ObservedGeneration is the last observed generation.
 	// +optional
 	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// The last outcomes of the pipelines, which are tracked by the providers
+	// that resolve the alerts when the pipelines recover.
+	// +listType=map
+	// +listMapKey=pipeline
+	// +optional
+	Pipelines []PipelineOutcome `json:"pipelines,omitempty"`
 }
 
 //+kubebuilder:object:root=true
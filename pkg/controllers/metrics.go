@@ -0,0 +1,77 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	pipelinesv1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/ornew/tekton-integration/internal/providers"
+	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
+)
+
+const (
+	notificationOutcomeSuccess = "success"
+	notificationOutcomeFailure = "failure"
+	notificationOutcomeSkipped = "skipped"
+)
+
+var (
+	notificationsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "tekton_integration_notifications_total",
+		Help: "Total number of the notification attempts by provider type, Notification, outcome and error code. The skipped attempts sent nothing.",
+	}, []string{"provider_type", "namespace", "notification", "outcome", "code"})
+	notificationDurationSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "tekton_integration_notification_duration_seconds",
+		Help:    "Time the notification attempts took, including the resolution of the providers.",
+		Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"provider_type", "outcome"})
+	notificationLatencySeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "tekton_integration_notification_latency_seconds",
+		Help:    "Time from the completion of the runs to the delivery of the notifications.",
+		Buckets: []float64{0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600},
+	}, []string{"provider_type"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		notificationsTotal,
+		notificationDurationSeconds,
+		notificationLatencySeconds,
+	)
+}
+
+// observeNotification records the attempt of the Notification started at start.
+// providerType is empty if the Provider can't be got.
+func observeNotification(pr *pipelinesv1beta1.PipelineRun, notif *v1alpha1.Notification, providerType string, start time.Time, perr *providers.ProviderError) {
+	outcome := notificationOutcomeSuccess
+	code := ""
+	if perr.IsSkipped() {
+		outcome = notificationOutcomeSkipped
+	} else if perr != nil {
+		outcome = notificationOutcomeFailure
+		code = string(perr.Code)
+	}
+	notificationsTotal.WithLabelValues(providerType, notif.Namespace, notif.Name, outcome, code).Inc()
+	notificationDurationSeconds.WithLabelValues(providerType, outcome).Observe(time.Since(start).Seconds())
+	if perr == nil && pr.Status.CompletionTime != nil {
+		notificationLatencySeconds.WithLabelValues(providerType).Observe(time.Since(pr.Status.CompletionTime.Time).Seconds())
+	}
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	pipelinesv1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/ornew/tekton-integration/internal/providers"
+	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
+)
+
+// histogramCount returns the number of the observations of the histogram.
+func histogramCount(t *testing.T, o prometheus.Observer) uint64 {
+	var m dto.Metric
+	if err := o.(prometheus.Metric).Write(&m); err != nil {
+		t.Fatalf("failed to write metric: %v", err)
+	}
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestObserveNotification(t *testing.T) {
+	notif := &v1alpha1.Notification{
+		ObjectMeta: metav1.ObjectMeta{Name: "metrics", Namespace: "default"},
+	}
+	for _, c := range []struct {
+		name         string
+		providerType string
+		completed    bool
+		perr         *providers.ProviderError
+		wantOutcome  string
+		wantCode     string
+		wantLatency  bool
+	}{
+		{
+			name:         "Sent",
+			providerType: "MetricsSent",
+			completed:    true,
+			wantOutcome:  "success",
+			wantLatency:  true,
+		},
+		{
+			name:         "SentRunning",
+			providerType: "MetricsRunning",
+			wantOutcome:  "success",
+		},
+		{
+			name:         "Failed",
+			providerType: "MetricsFailed",
+			completed:    true,
+			perr:         providers.NewRateLimitedError("rate limited"),
+			wantOutcome:  "failure",
+			wantCode:     "RateLimited",
+		},
+		{
+			name:         "Skipped",
+			providerType: "MetricsSkipped",
+			completed:    true,
+			perr:         providers.NewSkippedError("this run is cancelled"),
+			wantOutcome:  "skipped",
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			pr := &pipelinesv1beta1.PipelineRun{}
+			if c.completed {
+				pr.Status.CompletionTime = &metav1.Time{Time: time.Now().Add(-time.Minute)}
+			}
+			observeNotification(pr, notif, c.providerType, time.Now(), c.perr)
+			assert.Equal(t, float64(1), testutil.ToFloat64(notificationsTotal.WithLabelValues(c.providerType, "default", "metrics", c.wantOutcome, c.wantCode)))
+			assert.Equal(t, uint64(1), histogramCount(t, notificationDurationSeconds.WithLabelValues(c.providerType, c.wantOutcome)))
+			var latency uint64
+			if c.wantLatency {
+				latency = 1
+			}
+			assert.Equal(t, latency, histogramCount(t, notificationLatencySeconds.WithLabelValues(c.providerType)))
+		})
+	}
+}
@@ -18,11 +18,16 @@ package controllers
 
 import (
 	"context"
+	"fmt"
+	"strconv"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -35,17 +40,28 @@ import (
 )
 
 const (
-	annotationLastStatus = "integrations.tekton.ornew.io/last-status"
+	annotationLastStatus   = "integrations.tekton.ornew.io/last-status"
+	annotationLastProgress = "integrations.tekton.ornew.io/last-progress"
+)
+
+// The reasons of the events of the deliveries.
+const (
+	eventReasonNotificationSent   = "NotificationSent"
+	eventReasonNotificationFailed = "NotificationFailed"
 )
 
 // PipelineRunReconciler reconciles a PipelineRun object
 type PipelineRunReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+	// Recorder records the outcomes of the deliveries on the runs and the Notifications.
+	// The events are aggregated and rate-limited by the correlator of the broadcaster.
+	Recorder record.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=tekton.dev,resources=pipelineruns,verbs=get;list;watch;update;patch
 //+kubebuilder:rbac:groups=tekton.dev,resources=pipelineruns/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 func (r *PipelineRunReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := logr.FromContext(ctx)
@@ -68,69 +84,102 @@ func (r *PipelineRunReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 
 	status := string(cond.Status)
 	last := pr.Annotations[annotationLastStatus]
-	if last == "" || status != last {
+	changed := last == "" || status != last
+	progress := getProgress(&pr)
+	lastProgress := pr.Annotations[annotationLastProgress]
+	progressed := cond.Status == corev1.ConditionUnknown && progress != lastProgress
+	if !changed && !progressed {
+		return ctrl.Result{}, nil
+	}
+	if changed {
 		log.Info("PipelineRun status changed", "status", status, "last", last)
+	} else {
+		log.V(1).Info("PipelineRun progressed", "progress", progress, "last", lastProgress)
+	}
 
-		if pr.Annotations == nil {
-			log.Info("annotation is nil")
-			pr.SetAnnotations(make(map[string]string))
-		}
+	// TODO no blocking reconcile
+	//ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	//defer cancel()
 
-		pr.Annotations[annotationLastStatus] = status
-		if err := r.Update(ctx, &pr); err != nil {
-			if apierrors.IsConflict(err) {
-				return ctrl.Result{Requeue: true}, nil
-			}
-			if apierrors.IsNotFound(err) {
-				return ctrl.Result{Requeue: true}, nil
-			}
-			log.Error(err, "unable to update PipelineRun")
-			return ctrl.Result{}, err
+	var allNotif v1alpha1.NotificationList
+	if err := r.Client.List(ctx, &allNotif); err != nil {
+		log.Error(err, "failed to list notifications")
+		return ctrl.Result{Requeue: true}, nil
+	}
+	notifs := make([]v1alpha1.Notification, 0)
+	for _, notif := range allNotif.Items {
+		isReady := apimeta.IsStatusConditionTrue(notif.Status.Conditions, v1alpha1.ReadyCondition)
+		if notif.Spec.Suspend || !isReady {
+			continue
 		}
+		// TODO filtering
+		notifs = append(notifs, notif)
+	}
+	deliveries := r.resolveDeliveries(ctx, notifs)
+	if !changed {
+		// the progress is recorded only if any provider reports it,
+		// not to update every run per TaskRun.
+		deliveries = filterProgressDeliveries(deliveries)
+		if len(deliveries) == 0 {
+			log.V(1).Info("no provider reports the progress")
+			return ctrl.Result{}, nil
+		}
+	}
 
-		// TODO no blocking reconcile
-		//ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
-		//defer cancel()
+	if pr.Annotations == nil {
+		log.Info("annotation is nil")
+		pr.SetAnnotations(make(map[string]string))
+	}
 
-		var allNotif v1alpha1.NotificationList
-		err := r.Client.List(ctx, &allNotif)
-		if err != nil {
-			log.Error(err, "failed to list notifications")
+	pr.Annotations[annotationLastStatus] = status
+	pr.Annotations[annotationLastProgress] = progress
+	if err := r.Update(ctx, &pr); err != nil {
+		if apierrors.IsConflict(err) {
 			return ctrl.Result{Requeue: true}, nil
 		}
-		notifs := make([]v1alpha1.Notification, 0)
-		for _, notif := range allNotif.Items {
-			isReady := apimeta.IsStatusConditionTrue(notif.Status.Conditions, v1alpha1.ReadyCondition)
-			if notif.Spec.Suspend || !isReady {
-				continue
-			}
-			// TODO filtering
-			notifs = append(notifs, notif)
-		}
-		if len(notifs) == 0 {
-			log.Info("matched notifications are not found")
-			return ctrl.Result{}, nil
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{Requeue: true}, nil
 		}
-		for _, notif := range notifs {
-			var provider v1alpha1.Provider
-			providerRef := types.NamespacedName{
-				Namespace: notif.Namespace,
-				Name:      notif.Spec.ProviderRef.Name,
-			}
-			if err := r.Client.Get(ctx, providerRef, &provider); err != nil {
-				log.Error(err, "failed to get Provider", "provider", providerRef)
-				continue
-			}
-			logp := log.WithValues("provider", providerRef, "type", provider.Spec.Type)
-			app, err := providers.ResolveProvider(ctx, &provider, r.Client)
-			if err != nil {
-				logp.Error(err, "failed to create the provider app")
-				continue
+		log.Error(err, "unable to update PipelineRun")
+		return ctrl.Result{}, err
+	}
+
+	if len(deliveries) == 0 {
+		log.Info("matched notifications are not found")
+		return ctrl.Result{}, nil
+	}
+	for _, d := range deliveries {
+		d := d
+		start := time.Now().Add(-d.elapsed)
+		if d.err != nil {
+			observeNotification(&pr, &d.notif, d.provider.Spec.Type, start, d.err)
+			if d.provider.Name == "" {
+				r.recordFailed(&pr, &d.notif, d.err.Error())
+			} else {
+				r.recordNotified(&pr, &d.notif, &d.provider, d.err)
 			}
-			log.Info("get provider app", "app", app)
-			if err := app.Notify(ctx, &pr); err != nil {
+			continue
+		}
+		logp := log.WithValues("provider", d.notif.Spec.ProviderRef.Name, "type", d.provider.Spec.Type)
+		log.Info("get provider app", "app", d.app)
+		nctx := providers.WithNotification(ctx, &d.notif)
+		if changed {
+			err := d.app.Notify(nctx, &pr)
+			if err.IsSkipped() {
+				logp.V(1).Info("the provider skipped the run", "reason", err.Message)
+			} else if err != nil {
 				logp.Error(err, "failed to notify")
 			}
+			observeNotification(&pr, &d.notif, d.provider.Spec.Type, start, err)
+			r.recordNotified(&pr, &d.notif, &d.provider, err)
+			continue
+		}
+		err := d.app.(providers.ProgressNotifier).NotifyProgress(nctx, &pr)
+		observeNotification(&pr, &d.notif, d.provider.Spec.Type, start, err)
+		// the progress per TaskRun records only the failures, not to flood the events of the run.
+		if err != nil && !err.IsSkipped() {
+			logp.Error(err, "failed to notify progress")
+			r.recordNotified(&pr, &d.notif, &d.provider, err)
 		}
 	}
 
@@ -143,3 +192,93 @@ func (r *PipelineRunReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		For(&pipelinesv1beta1.PipelineRun{}).
 		Complete(r)
 }
+
+// notificationDelivery is the Notification with its resolved provider app, or the error of the resolution.
+type notificationDelivery struct {
+	notif    v1alpha1.Notification
+	provider v1alpha1.Provider
+	app      providers.Provider
+	err      *providers.ProviderError
+	// elapsed is the time taken by the resolution.
+	elapsed time.Duration
+}
+
+// resolveDeliveries gets the Providers of the Notifications and resolves their apps.
+// The Provider is empty if it can't be got.
+func (r *PipelineRunReconciler) resolveDeliveries(ctx context.Context, notifs []v1alpha1.Notification) []notificationDelivery {
+	log := logr.FromContext(ctx)
+	deliveries := make([]notificationDelivery, 0, len(notifs))
+	for _, notif := range notifs {
+		start := time.Now()
+		d := notificationDelivery{notif: notif}
+		providerRef := types.NamespacedName{
+			Namespace: notif.Namespace,
+			Name:      notif.Spec.ProviderRef.Name,
+		}
+		if err := r.Client.Get(ctx, providerRef, &d.provider); err != nil {
+			log.Error(err, "failed to get Provider", "provider", providerRef)
+			d.provider = v1alpha1.Provider{}
+			d.err = providers.NewRuntimeError(fmt.Sprintf("failed to get Provider %s: %v", providerRef.Name, err))
+		} else if d.app, d.err = providers.ResolveProvider(ctx, &d.provider, r.Client); d.err != nil {
+			log.Error(d.err, "failed to create the provider app", "provider", providerRef, "type", d.provider.Spec.Type)
+		}
+		d.elapsed = time.Since(start)
+		deliveries = append(deliveries, d)
+	}
+	return deliveries
+}
+
+// filterProgressDeliveries returns the deliveries whose providers report the progress.
+// The deliveries failed to resolve are excluded, since they are reported at the changes of the status.
+func filterProgressDeliveries(deliveries []notificationDelivery) []notificationDelivery {
+	filtered := make([]notificationDelivery, 0, len(deliveries))
+	for _, d := range deliveries {
+		if p, ok := d.app.(providers.ProgressNotifier); ok && d.err == nil && p.ReportsProgress() {
+			filtered = append(filtered, d)
+		}
+	}
+	return filtered
+}
+
+// recordNotified records the outcome of the delivery by the Provider on both the run and the Notification.
+// The failures have the code of the error, e.g. RuntimeError. Nothing is recorded if the Provider skipped the run.
+func (r *PipelineRunReconciler) recordNotified(pr *pipelinesv1beta1.PipelineRun, notif *v1alpha1.Notification, provider *v1alpha1.Provider, perr *providers.ProviderError) {
+	if r.Recorder == nil || perr.IsSkipped() {
+		return
+	}
+	via := fmt.Sprintf("%s Provider %s", provider.Spec.Type, provider.Name)
+	if perr != nil {
+		r.recordFailed(pr, notif, fmt.Sprintf("failed to send via %s: %v", via, perr))
+		return
+	}
+	r.Recorder.Eventf(pr, corev1.EventTypeNormal, eventReasonNotificationSent,
+		"Notification %s sent via %s", notif.Name, via)
+	r.Recorder.Eventf(notif, corev1.EventTypeNormal, eventReasonNotificationSent,
+		"PipelineRun %s/%s sent via %s", pr.Namespace, pr.Name, via)
+}
+
+// recordFailed records the failure of the delivery on both the run and the Notification.
+func (r *PipelineRunReconciler) recordFailed(pr *pipelinesv1beta1.PipelineRun, notif *v1alpha1.Notification, message string) {
+	if r.Recorder == nil {
+		return
+	}
+	r.Recorder.Eventf(pr, corev1.EventTypeWarning, eventReasonNotificationFailed,
+		"Notification %s %s", notif.Name, message)
+	r.Recorder.Eventf(notif, corev1.EventTypeWarning, eventReasonNotificationFailed,
+		"PipelineRun %s/%s %s", pr.Namespace, pr.Name, message)
+}
+
+// getProgress returns the number of the completed TaskRuns of the run.
+func getProgress(pr *pipelinesv1beta1.PipelineRun) string {
+	completed := 0
+	for _, tr := range pr.Status.TaskRuns {
+		if tr.Status == nil {
+			continue
+		}
+		c := tr.Status.GetCondition(knativeapis.ConditionSucceeded)
+		if c != nil && c.Status != corev1.ConditionUnknown {
+			completed++
+		}
+	}
+	return strconv.Itoa(completed)
+}
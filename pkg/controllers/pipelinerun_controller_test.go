@@ -0,0 +1,97 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	pipelinesv1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/ornew/tekton-integration/internal/providers"
+	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
+)
+
+func TestRecordNotified(t *testing.T) {
+	pr := &pipelinesv1beta1.PipelineRun{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "tekton.dev/v1beta1", Kind: "PipelineRun"},
+		ObjectMeta: metav1.ObjectMeta{Name: "sample-run", Namespace: "default"},
+	}
+	notif := &v1alpha1.Notification{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "integrations.tekton.ornew.io/v1alpha1", Kind: "Notification"},
+		ObjectMeta: metav1.ObjectMeta{Name: "slack-notification", Namespace: "default"},
+	}
+	provider := &v1alpha1.Provider{
+		ObjectMeta: metav1.ObjectMeta{Name: "slack-app", Namespace: "default"},
+		Spec:       v1alpha1.ProviderSpec{Type: "SlackApp"},
+	}
+	for _, c := range []struct {
+		name string
+		perr *providers.ProviderError
+		want []string
+	}{
+		{
+			name: "Sent",
+			want: []string{
+				"Normal NotificationSent Notification slack-notification sent via SlackApp Provider slack-app involvedObject{kind=PipelineRun,apiVersion=tekton.dev/v1beta1}",
+				"Normal NotificationSent PipelineRun default/sample-run sent via SlackApp Provider slack-app involvedObject{kind=Notification,apiVersion=integrations.tekton.ornew.io/v1alpha1}",
+			},
+		},
+		{
+			name: "Failed",
+			perr: providers.NewChannelNotFoundError("channel not found: tekton"),
+			want: []string{
+				"Warning NotificationFailed Notification slack-notification failed to send via SlackApp Provider slack-app: ChannelNotFound: channel not found: tekton involvedObject{kind=PipelineRun,apiVersion=tekton.dev/v1beta1}",
+				"Warning NotificationFailed PipelineRun default/sample-run failed to send via SlackApp Provider slack-app: ChannelNotFound: channel not found: tekton involvedObject{kind=Notification,apiVersion=integrations.tekton.ornew.io/v1alpha1}",
+			},
+		},
+		{
+			name: "Skipped",
+			perr: providers.NewSkippedError("this run is not finished yet"),
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			recorder.IncludeObject = true
+			r := &PipelineRunReconciler{Recorder: recorder}
+			r.recordNotified(pr, notif, provider, c.perr)
+			close(recorder.Events)
+			var got []string
+			for e := range recorder.Events {
+				got = append(got, e)
+			}
+			assert.Equal(t, c.want, got)
+		})
+	}
+}
+
+func TestFilterProgressDeliveries(t *testing.T) {
+	deliveries := []notificationDelivery{
+		{notif: v1alpha1.Notification{ObjectMeta: metav1.ObjectMeta{Name: "slack"}}, app: &providers.SlackApp{UpdateMessage: true}},
+		{notif: v1alpha1.Notification{ObjectMeta: metav1.ObjectMeta{Name: "slack-no-update"}}, app: &providers.SlackApp{}},
+		{notif: v1alpha1.Notification{ObjectMeta: metav1.ObjectMeta{Name: "webhook"}}, app: &providers.Webhook{}},
+		{notif: v1alpha1.Notification{ObjectMeta: metav1.ObjectMeta{Name: "invalid"}}, err: providers.NewInvalidProviderSpecError("missing value .slackApp")},
+	}
+	filtered := filterProgressDeliveries(deliveries)
+	if assert.Len(t, filtered, 1) {
+		assert.Equal(t, "slack", filtered[0].notif.Name)
+	}
+	assert.Empty(t, filterProgressDeliveries(deliveries[1:]))
+}
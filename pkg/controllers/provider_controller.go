@@ -24,9 +24,13 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
 	"github.com/go-logr/logr"
+
+	"github.com/ornew/tekton-integration/internal/providers"
 	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
 )
 
@@ -39,6 +43,8 @@ type ProviderReconciler struct {
 //+kubebuilder:rbac:groups=integrations.tekton.ornew.io,resources=providers,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=integrations.tekton.ornew.io,resources=providers/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=integrations.tekton.ornew.io,resources=providers/finalizers,verbs=update
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
 
 func (r *ProviderReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := logr.FromContext(ctx)
@@ -53,6 +59,13 @@ func (r *ProviderReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		return ctrl.Result{}, err
 	}
 
+	// the validation may connect to the external services, so the ready Provider
+	// is validated again only when its spec is changed.
+	if apimeta.IsStatusConditionTrue(provider.Status.Conditions, v1alpha1.ReadyCondition) && provider.Status.ObservedGeneration == provider.Generation {
+		log.V(2).Info("already validated", "generation", provider.Generation)
+		return ctrl.Result{}, nil
+	}
+
 	if err := r.validate(ctx, provider); err != nil {
 		patch := client.MergeFrom(provider.DeepCopy())
 		r.setStatusCondition(&provider, v1alpha1.ReadyCondition, metav1.ConditionFalse, v1alpha1.ReconcileFailedReason, err.Error())
@@ -62,36 +75,46 @@ func (r *ProviderReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		return ctrl.Result{Requeue: true}, err
 	}
 
-	if !apimeta.IsStatusConditionTrue(provider.Status.Conditions, v1alpha1.ReadyCondition) || provider.Status.ObservedGeneration != provider.Generation {
-		patch := client.MergeFrom(provider.DeepCopy())
-		r.setStatusCondition(&provider, v1alpha1.ReadyCondition, metav1.ConditionTrue, v1alpha1.InitializedReason, v1alpha1.InitializedReason)
-		if err := r.Status().Patch(ctx, &provider, patch); err != nil {
-			return ctrl.Result{Requeue: true}, err
-		}
-		log.Info("initialized")
+	patch := client.MergeFrom(provider.DeepCopy())
+	r.setStatusCondition(&provider, v1alpha1.ReadyCondition, metav1.ConditionTrue, v1alpha1.InitializedReason, v1alpha1.InitializedReason)
+	if err := r.Status().Patch(ctx, &provider, patch); err != nil {
+		return ctrl.Result{Requeue: true}, err
 	}
+	log.Info("initialized")
 
 	return ctrl.Result{}, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
+// The updates of the status only, e.g. the outcomes of the pipelines, are not reconciled.
 func (r *ProviderReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&v1alpha1.Provider{}).
+		For(&v1alpha1.Provider{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
 		Complete(r)
 }
 
 func (r *ProviderReconciler) validate(ctx context.Context, provider v1alpha1.Provider) error {
-	// TODO
+	app, err := providers.ResolveProvider(ctx, &provider, r.Client)
+	if err != nil {
+		return err
+	}
+	if v, ok := app.(providers.Validator); ok {
+		if err := v.Validate(ctx); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// setStatusCondition sets the condition observed at the current generation of the Provider.
 func (r *ProviderReconciler) setStatusCondition(provider *v1alpha1.Provider, condition string, status metav1.ConditionStatus, reason, message string) {
 	newCondition := metav1.Condition{
-		Type:    condition,
-		Status:  status,
-		Reason:  reason,
-		Message: message,
+		Type:               condition,
+		Status:             status,
+		ObservedGeneration: provider.Generation,
+		Reason:             reason,
+		Message:            message,
 	}
 	apimeta.SetStatusCondition(&provider.Status.Conditions, newCondition)
+	provider.Status.ObservedGeneration = provider.Generation
 }
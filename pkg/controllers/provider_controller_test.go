@@ -0,0 +1,111 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/ornew/tekton-integration/pkg/api/v1alpha1"
+)
+
+func TestProviderReconcile(t *testing.T) {
+	ready := []metav1.Condition{{
+		Type:               v1alpha1.ReadyCondition,
+		Status:             metav1.ConditionTrue,
+		Reason:             v1alpha1.InitializedReason,
+		Message:            v1alpha1.InitializedReason,
+		LastTransitionTime: metav1.Now(),
+	}}
+	valid := v1alpha1.ProviderSpec{
+		Type:    "Webhook",
+		Webhook: &v1alpha1.WebhookSpec{URL: "https://example.com/hook"},
+	}
+	// the spec is invalid, since .webhook is missing.
+	invalid := v1alpha1.ProviderSpec{Type: "Webhook"}
+	for _, c := range []struct {
+		name      string
+		spec      v1alpha1.ProviderSpec
+		status    v1alpha1.ProviderStatus
+		wantErr   bool
+		wantReady metav1.ConditionStatus
+	}{
+		{
+			name:      "Valid",
+			spec:      valid,
+			wantReady: metav1.ConditionTrue,
+		},
+		{
+			name:      "Invalid",
+			spec:      invalid,
+			wantErr:   true,
+			wantReady: metav1.ConditionFalse,
+		},
+		{
+			// the validation is skipped, so the Provider stays ready.
+			name:      "GenerationUnchanged",
+			spec:      invalid,
+			status:    v1alpha1.ProviderStatus{Conditions: ready, ObservedGeneration: 2},
+			wantReady: metav1.ConditionTrue,
+		},
+		{
+			name:      "GenerationChanged",
+			spec:      invalid,
+			status:    v1alpha1.ProviderStatus{Conditions: ready, ObservedGeneration: 1},
+			wantErr:   true,
+			wantReady: metav1.ConditionFalse,
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			s := runtime.NewScheme()
+			assert.Nil(t, corev1.AddToScheme(s))
+			assert.Nil(t, v1alpha1.AddToScheme(s))
+			p := &v1alpha1.Provider{
+				ObjectMeta: metav1.ObjectMeta{Name: "provider", Namespace: "default", Generation: 2},
+				Spec:       c.spec,
+				Status:     c.status,
+			}
+			k := fakeclient.NewClientBuilder().WithScheme(s).WithObjects(p).Build()
+			r := &ProviderReconciler{Client: k, Scheme: s}
+			nn := types.NamespacedName{Namespace: "default", Name: "provider"}
+			ctx := logr.NewContext(context.Background(), logr.Discard())
+			_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: nn})
+			if c.wantErr {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+			}
+			var got v1alpha1.Provider
+			assert.Nil(t, k.Get(ctx, nn, &got))
+			assert.Equal(t, int64(2), got.Status.ObservedGeneration)
+			if cond := apimeta.FindStatusCondition(got.Status.Conditions, v1alpha1.ReadyCondition); assert.NotNil(t, cond) {
+				assert.Equal(t, c.wantReady, cond.Status)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2021 Arata Furukawa.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/go-logr/logr"
+
+	"github.com/ornew/tekton-integration/internal/providers"
+)
+
+// SlackActionServer serves the requests from the buttons of the Slack messages.
+type SlackActionServer struct {
+	Client client.Client
+	// BindAddress is the address to listen on. e.g. :9443
+	BindAddress string
+	// CertDir is the directory which contains tls.crt and tls.key.
+	// If empty, the server listens on plain HTTP for TLS terminated by the ingress.
+	CertDir string
+}
+
+//+kubebuilder:rbac:groups=tekton.dev,resources=pipelineruns,verbs=get;create;patch
+
+// SetupWithManager adds the server to the Manager.
+func (s *SlackActionServer) SetupWithManager(mgr ctrl.Manager) error {
+	return mgr.Add(s)
+}
+
+// NeedLeaderElection returns false to serve the requests on all replicas.
+func (s *SlackActionServer) NeedLeaderElection() bool {
+	return false
+}
+
+func (s *SlackActionServer) Start(ctx context.Context) error {
+	log := ctrl.Log.WithName("slack-actions")
+	mux := http.NewServeMux()
+	mux.Handle(providers.SlackActionPath, providers.NewSlackActionHandler(s.Client))
+	srv := &http.Server{
+		Addr:              s.BindAddress,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext: func(net.Listener) context.Context {
+			return logr.NewContext(ctx, log)
+		},
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		log.Info("starting the Slack actions server", "addr", s.BindAddress, "path", providers.SlackActionPath)
+		var err error
+		if len(s.CertDir) > 0 {
+			err = srv.ListenAndServeTLS(filepath.Join(s.CertDir, "tls.crt"), filepath.Join(s.CertDir, "tls.key"))
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != http.ErrServerClosed {
+			errCh <- err
+		}
+		close(errCh)
+	}()
+	select {
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		return srv.Shutdown(shutdownCtx)
+	case err := <-errCh:
+		return err
+	}
+}